@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/provider"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// modelCatalogMaxAge is how old the cached model catalog gets before it
+	// is refreshed at startup.
+	modelCatalogMaxAge = 24 * time.Hour
+	// startupRefreshTimeout bounds the refresh run in the background at
+	// startup.
+	startupRefreshTimeout = 10 * time.Second
+	// smokeTestMaxTokens bounds the answer of a model test.
+	smokeTestMaxTokens = 1024
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List, inspect and test the supported models",
+	Example: `
+  # List the models of the providers with credentials
+  opencode models list --configured
+
+  # List the models of a provider
+  opencode models list --provider anthropic
+
+  # Show the details of a model
+  opencode models show claude-4-sonnet
+
+  # Send a short request to a model
+  opencode models test claude-4-sonnet
+
+  # Fetch the model catalogs of the configured providers
+  opencode models refresh
+  `,
+}
+
+var modelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the supported models",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		providerName, _ := cmd.Flags().GetString("provider")
+		configured, _ := cmd.Flags().GetBool("configured")
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tPROVIDER\tCONTEXT\tINPUT/1M\tOUTPUT/1M\tCONFIGURED")
+		for _, model := range listModels(models.ModelProvider(providerName), configured) {
+			fmt.Fprintf(w, "%s\t%s\t%d\t$%.2f\t$%.2f\t%s\n",
+				model.ID,
+				model.Provider,
+				model.ContextWindow,
+				model.CostPer1MIn,
+				model.CostPer1MOut,
+				yesNo(providerConfigured(model.Provider)),
+			)
+		}
+		return w.Flush()
+	},
+}
+
+var modelsShowCmd = &cobra.Command{
+	Use:   "show <model-id>",
+	Short: "Show the details of a model",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		model, err := findModel(args[0])
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ID\t%s\n", model.ID)
+		fmt.Fprintf(w, "Name\t%s\n", model.Name)
+		fmt.Fprintf(w, "Provider\t%s\n", model.Provider)
+		fmt.Fprintf(w, "API model\t%s\n", model.APIModel)
+		if model.API != "" {
+			fmt.Fprintf(w, "API\t%s\n", model.API)
+		}
+		fmt.Fprintf(w, "Context window\t%d tokens\n", model.ContextWindow)
+		fmt.Fprintf(w, "Default max tokens\t%d\n", model.DefaultMaxTokens)
+		fmt.Fprintf(w, "Input\t$%.2f / 1M tokens\n", model.CostPer1MIn)
+		fmt.Fprintf(w, "Output\t$%.2f / 1M tokens\n", model.CostPer1MOut)
+		fmt.Fprintf(w, "Cache write\t$%.2f / 1M tokens\n", model.CostPer1MInCached)
+		fmt.Fprintf(w, "Cache read\t$%.2f / 1M tokens\n", model.CostPer1MOutCached)
+		fmt.Fprintf(w, "Reasoning\t%s\n", yesNo(model.CanReason))
+		fmt.Fprintf(w, "Attachments\t%s\n", yesNo(model.SupportsAttachments))
+		fmt.Fprintf(w, "Configured\t%s\n", yesNo(providerConfigured(model.Provider)))
+		return w.Flush()
+	},
+}
+
+var modelsTestCmd = &cobra.Command{
+	Use:   "test <model-id>",
+	Short: "Send a short request to a model",
+	Long: `Send a short request to a model that asks it to call a tool, and report
+the latency, the token usage and whether the model called the tool.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		model, err := findModel(args[0])
+		if err != nil {
+			return err
+		}
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		result, err := smokeTest(cmd.Context(), model, timeout)
+		if err != nil {
+			return fmt.Errorf("model %s failed: %w", model.ID, err)
+		}
+		usage := result.response.Usage
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Model\t%s\n", model.ID)
+		fmt.Fprintf(w, "Latency\t%s\n", result.latency.Round(time.Millisecond))
+		fmt.Fprintf(w, "Input tokens\t%d\n", usage.InputTokens)
+		fmt.Fprintf(w, "Output tokens\t%d\n", usage.OutputTokens)
+		if usage.CacheCreationTokens > 0 || usage.CacheReadTokens > 0 {
+			fmt.Fprintf(w, "Cache tokens\t%d written, %d read\n", usage.CacheCreationTokens, usage.CacheReadTokens)
+		}
+		fmt.Fprintf(w, "Cost\t$%.4f\n", usage.Cost(model))
+		fmt.Fprintf(w, "Finish reason\t%s\n", result.response.FinishReason)
+		fmt.Fprintf(w, "Tool calls\t%s\n", result.toolCalls())
+		if text := strings.Join(strings.Fields(result.response.Content), " "); text != "" {
+			fmt.Fprintf(w, "Response\t%s\n", text)
+		}
+		return w.Flush()
+	},
+}
+
+var modelsRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Fetch the model catalogs of the configured providers",
+	Long: `Fetch the model catalogs of the configured providers that publish one
+(OpenRouter and GitHub Copilot) and cache them in the data directory. New
+models become selectable and known models take the pricing and context
+window of the catalog.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		catalog, added, err := provider.RefreshModelCatalog(cmd.Context())
+		if len(catalog.Models) == 0 && err != nil {
+			return fmt.Errorf("failed to refresh the model catalog: %w", err)
+		}
+
+		counts := make(map[models.ModelProvider]int)
+		for _, model := range catalog.Models {
+			counts[model.Provider]++
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROVIDER\tMODELS")
+		for name, count := range counts {
+			fmt.Fprintf(w, "%s\t%d\n", name, count)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("%d new models, cached in %s\n", added, config.ModelCatalogPath())
+		if err != nil {
+			return fmt.Errorf("some catalogs were not refreshed: %w", err)
+		}
+		return nil
+	},
+}
+
+// loadConfig loads the configuration for the current directory.
+func loadConfig() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %v", err)
+	}
+	_, err = config.Load(cwd, false)
+	return err
+}
+
+// listModels returns the supported models of the provider, or of all of them
+// when empty, optionally only those of configured providers. The most
+// popular providers come first, like in the model dialog.
+func listModels(providerName models.ModelProvider, configured bool) []models.Model {
+	var list []models.Model
+	for _, model := range models.SupportedModels {
+		if providerName != "" && model.Provider != providerName {
+			continue
+		}
+		if configured && !providerConfigured(model.Provider) {
+			continue
+		}
+		list = append(list, model)
+	}
+	slices.SortFunc(list, func(a, b models.Model) int {
+		return cmp.Or(
+			cmp.Compare(providerRank(a.Provider), providerRank(b.Provider)),
+			cmp.Compare(a.Provider, b.Provider),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+	return list
+}
+
+// findModel returns the supported model with the given ID.
+func findModel(id string) (models.Model, error) {
+	model, ok := models.SupportedModels[models.ModelID(id)]
+	if !ok {
+		return model, fmt.Errorf("unknown model %q, see `opencode models list`", id)
+	}
+	return model, nil
+}
+
+// providerConfigured reports whether the provider has credentials and is
+// enabled, as for the model dialog.
+func providerConfigured(provider models.ModelProvider) bool {
+	providerCfg, ok := config.Get().Providers[provider]
+	return ok && !providerCfg.Disabled
+}
+
+// providerRank orders providers by popularity, unranked ones last.
+func providerRank(provider models.ModelProvider) int {
+	if rank := models.ProviderPopularity[provider]; rank > 0 {
+		return rank
+	}
+	return 999
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// smokeTestTool is offered to the model during a test. It is never run.
+type smokeTestTool struct{}
+
+func (smokeTestTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{
+		Name:        "echo",
+		Description: "Echo a text back to the user",
+		Parameters: map[string]any{
+			"text": map[string]any{
+				"type":        "string",
+				"description": "The text to echo",
+			},
+		},
+		Required: []string{"text"},
+	}
+}
+
+func (smokeTestTool) Run(context.Context, tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse("ping"), nil
+}
+
+type smokeTestResult struct {
+	response *provider.ProviderResponse
+	latency  time.Duration
+}
+
+func (r smokeTestResult) toolCalls() string {
+	for _, call := range r.response.ToolCalls {
+		if call.Name == "echo" {
+			return "supported"
+		}
+	}
+	if len(r.response.ToolCalls) > 0 {
+		return "called an unknown tool"
+	}
+	return "not called"
+}
+
+// smokeTest asks the model to call a tool through the provider of the model,
+// set up the way the coder agent would use it.
+func smokeTest(ctx context.Context, model models.Model, timeout time.Duration) (smokeTestResult, error) {
+	if !providerConfigured(model.Provider) {
+		return smokeTestResult{}, fmt.Errorf("provider %s is not configured", model.Provider)
+	}
+	agentCfg := config.Get().Agents[config.AgentCoder]
+	agentCfg.Model = model.ID
+	agentCfg.MaxTokens = min(cmp.Or(model.DefaultMaxTokens, smokeTestMaxTokens), smokeTestMaxTokens)
+	llm, err := agent.NewModelProvider(config.AgentCoder, agentCfg, model.ID,
+		provider.WithSystemMessage("You are testing your connection. Follow the instructions of the user."),
+	)
+	if err != nil {
+		return smokeTestResult{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	messages := []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: `Call the echo tool with the text "ping".`}},
+	}}
+	start := time.Now()
+	response, err := llm.SendMessages(ctx, messages, []tools.BaseTool{smokeTestTool{}})
+	if err != nil {
+		return smokeTestResult{}, err
+	}
+	return smokeTestResult{response: response, latency: time.Since(start)}, nil
+}
+
+// refreshStaleModelCatalog refreshes the cached model catalog when enabled
+// and older than a day. The configuration was already validated against the
+// cached catalog, so the refreshed one is only used from the next start.
+// Failures are only logged.
+func refreshStaleModelCatalog(ctx context.Context) {
+	if !config.Get().RefreshModels {
+		return
+	}
+	catalog, err := models.ReadCatalog(config.ModelCatalogPath())
+	if err == nil && !catalog.Stale(modelCatalogMaxAge) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, startupRefreshTimeout)
+	defer cancel()
+	if err := provider.CacheModelCatalog(ctx); err != nil {
+		logging.Warn("failed to refresh the model catalog", "error", err)
+	}
+}
+
+func init() {
+	modelsListCmd.Flags().String("provider", "", "Only list the models of a provider")
+	modelsListCmd.Flags().Bool("configured", false, "Only list the models of providers with credentials")
+	modelsTestCmd.Flags().Duration("timeout", time.Minute, "Time to wait for the answer")
+	modelsCmd.AddCommand(modelsListCmd, modelsShowCmd, modelsTestCmd, modelsRefreshCmd)
+	rootCmd.AddCommand(modelsCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListModels(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg := config.Get()
+	previous := cfg.Providers
+	cfg.Providers = map[models.ModelProvider]config.Provider{
+		models.ProviderAnthropic: {APIKey: "key"},
+		models.ProviderGemini:    {APIKey: "key"},
+		models.ProviderOpenAI:    {APIKey: "key", Disabled: true},
+	}
+	t.Cleanup(func() { cfg.Providers = previous })
+
+	providers := func(list []models.Model) []models.ModelProvider {
+		var result []models.ModelProvider
+		for _, model := range list {
+			if !slices.Contains(result, model.Provider) {
+				result = append(result, model.Provider)
+			}
+		}
+		return result
+	}
+
+	// Only configured and enabled providers, the most popular first
+	configured := listModels("", true)
+	assert.Equal(t, []models.ModelProvider{models.ProviderAnthropic, models.ProviderGemini}, providers(configured))
+
+	// A single provider, sorted by ID
+	anthropic := listModels(models.ProviderAnthropic, false)
+	require.NotEmpty(t, anthropic)
+	assert.Equal(t, []models.ModelProvider{models.ProviderAnthropic}, providers(anthropic))
+	assert.True(t, slices.IsSortedFunc(anthropic, func(a, b models.Model) int {
+		return strings.Compare(string(a.ID), string(b.ID))
+	}))
+	assert.Empty(t, listModels(models.ProviderOpenAI, true))
+
+	// Every model is listed, the ranked providers before the others
+	all := listModels("", false)
+	assert.Len(t, all, len(models.SupportedModels))
+	var ranks []int
+	for _, provider := range providers(all) {
+		ranks = append(ranks, providerRank(provider))
+	}
+	assert.True(t, slices.IsSorted(ranks))
+}
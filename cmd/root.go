@@ -45,6 +45,9 @@ to assist developers in writing, debugging, and understanding code directly from
 
   # Run a single non-interactive prompt with JSON output format
   opencode -p "Explain the use of context in Go" -f json
+
+  # Run several prompts one after another in the same session
+  opencode -p "Add a --verbose flag" -p "Now document it in the README"
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If the help flag is set, show the help message
@@ -60,7 +63,7 @@ to assist developers in writing, debugging, and understanding code directly from
 		// Load the config
 		debug, _ := cmd.Flags().GetBool("debug")
 		cwd, _ := cmd.Flags().GetString("cwd")
-		prompt, _ := cmd.Flags().GetString("prompt")
+		prompts, _ := cmd.Flags().GetStringArray("prompt")
 		outputFormat, _ := cmd.Flags().GetString("output-format")
 		quiet, _ := cmd.Flags().GetBool("quiet")
 
@@ -97,6 +100,8 @@ to assist developers in writing, debugging, and understanding code directly from
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		go refreshStaleModelCatalog(ctx)
+
 		app, err := app.New(ctx, conn)
 		if err != nil {
 			logging.Error("Failed to create app: %v", err)
@@ -109,9 +114,9 @@ to assist developers in writing, debugging, and understanding code directly from
 		initMCPTools(ctx, app)
 
 		// Non-interactive mode
-		if prompt != "" {
+		if len(prompts) > 0 {
 			// Run non-interactive flow using the App method
-			return app.RunNonInteractive(ctx, prompt, outputFormat, quiet)
+			return app.RunNonInteractive(ctx, prompts, outputFormat, quiet)
 		}
 
 		// Interactive mode
@@ -299,7 +304,7 @@ func init() {
 	rootCmd.Flags().BoolP("version", "v", false, "Version")
 	rootCmd.Flags().BoolP("debug", "d", false, "Debug")
 	rootCmd.Flags().StringP("cwd", "c", "", "Current working directory")
-	rootCmd.Flags().StringP("prompt", "p", "", "Prompt to run in non-interactive mode")
+	rootCmd.Flags().StringArrayP("prompt", "p", nil, "Prompt to run in non-interactive mode, repeat to run several prompts in one session")
 
 	// Add format flag with validation logic
 	rootCmd.Flags().StringP("output-format", "f", format.Text.String(),
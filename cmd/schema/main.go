@@ -77,6 +77,180 @@ func generateSchema() map[string]any {
 		"default":     false,
 	}
 
+	schema["properties"].(map[string]any)["refreshModels"] = map[string]any{
+		"type":        "boolean",
+		"description": "Refresh the cached model catalogs of OpenRouter and GitHub Copilot at startup once they are a day old",
+		"default":     false,
+	}
+
+	// Add budget limits
+	schema["properties"].(map[string]any)["budget"] = map[string]any{
+		"type":        "object",
+		"description": "Cost and tool loop limits that stop the agent when reached",
+		"properties": map[string]any{
+			"maxSessionCost": map[string]any{
+				"type":        "number",
+				"description": "Maximum cost in USD for a single session (0 disables)",
+				"minimum":     0,
+			},
+			"maxDailyCost": map[string]any{
+				"type":        "number",
+				"description": "Maximum cost in USD across all sessions updated today (0 disables)",
+				"minimum":     0,
+			},
+			"maxToolIterations": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of tool call rounds per request (0 disables)",
+				"minimum":     0,
+			},
+		},
+	}
+
+	// Add context compaction
+	schema["properties"].(map[string]any)["compaction"] = map[string]any{
+		"type":        "object",
+		"description": "When and how the agent compacts a conversation that approaches the context window",
+		"properties": map[string]any{
+			"threshold": map[string]any{
+				"type":             "number",
+				"description":      "Share of the model's context window the conversation may use before it is compacted",
+				"exclusiveMinimum": 0,
+				"maximum":          1,
+				"default":          0.8,
+			},
+			"strategy": map[string]any{
+				"type":        "string",
+				"description": "Prune old tool results, summarize older turns, or prune and then summarize when needed",
+				"enum":        []string{"auto", "prune", "summarize"},
+				"default":     "auto",
+			},
+		},
+	}
+
+	schema["properties"].(map[string]any)["retry"] = map[string]any{
+		"type":        "object",
+		"description": "How requests that failed on rate limits, server errors or overloads are retried",
+		"properties": map[string]any{
+			"maxRetries": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of retries of a request",
+				"minimum":     0,
+				"default":     8,
+			},
+			"maxWait": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of seconds a request may spend waiting between its retries",
+				"minimum":     0,
+				"default":     300,
+			},
+		},
+	}
+
+	schema["properties"].(map[string]any)["cassette"] = map[string]any{
+		"type":        "object",
+		"description": "Record provider HTTP traffic into a cassette or replay it from one",
+		"properties": map[string]any{
+			"mode": map[string]any{
+				"type":        "string",
+				"description": "Whether provider calls are recorded or replayed",
+				"enum":        []string{string(config.CassetteRecord), string(config.CassetteReplay)},
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "File name of the cassette in the cassettes directory of the data directory",
+			},
+		},
+	}
+
+	// Add hooks
+	toolHookSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"command": map[string]any{
+				"type":        "string",
+				"description": "Shell command that receives the tool call as JSON on stdin",
+			},
+			"tools": map[string]any{
+				"type":        "array",
+				"description": "Tool name patterns the hook runs for (all when empty)",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"paths": map[string]any{
+				"type":        "array",
+				"description": "Glob patterns of the files the hook runs for, relative to the working directory (all calls when empty)",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"timeout": map[string]any{
+				"type":        "integer",
+				"description": "Timeout in seconds",
+				"default":     10,
+				"minimum":     1,
+			},
+		},
+		"required": []string{"command"},
+	}
+	schema["properties"].(map[string]any)["hooks"] = map[string]any{
+		"type":        "object",
+		"description": "Hooks that run around the agent",
+		"properties": map[string]any{
+			"preToolUse": map[string]any{
+				"type":        "array",
+				"description": "Commands that run before matching tool calls and may block or change them",
+				"items":       toolHookSchema,
+			},
+			"postToolUse": map[string]any{
+				"type":        "array",
+				"description": "Commands that run after matching tool calls, their output is added to the tool result",
+				"items":       toolHookSchema,
+			},
+			"postTurn": map[string]any{
+				"type":        "array",
+				"description": "Hooks that run after each completed turn and may suggest follow-up prompts",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"command": map[string]any{
+							"type":        "string",
+							"description": "Shell command that receives the turn as JSON on stdin",
+						},
+						"mcpServer": map[string]any{
+							"type":        "string",
+							"description": "Name of the MCP server to call instead of a command",
+						},
+						"tool": map[string]any{
+							"type":        "string",
+							"description": "Tool of the MCP server to call",
+						},
+						"agents": map[string]any{
+							"type":        "array",
+							"description": "Agents the hook runs for (all when empty)",
+							"items": map[string]any{
+								"type": "string",
+							},
+						},
+						"models": map[string]any{
+							"type":        "array",
+							"description": "Models the hook runs for (all when empty)",
+							"items": map[string]any{
+								"type": "string",
+							},
+						},
+						"timeout": map[string]any{
+							"type":        "integer",
+							"description": "Timeout in seconds",
+							"default":     10,
+							"minimum":     1,
+						},
+					},
+				},
+			},
+		},
+	}
+
 	schema["properties"].(map[string]any)["contextPaths"] = map[string]any{
 		"type":        "array",
 		"description": "Context paths for the application",
@@ -210,6 +384,101 @@ func generateSchema() map[string]any {
 
 	schema["properties"].(map[string]any)["providers"] = providerSchema
 
+	// Add custom providers and models
+	schema["properties"].(map[string]any)["customProviders"] = map[string]any{
+		"type":        "object",
+		"description": "OpenAI-compatible providers by name",
+		"additionalProperties": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"baseURL": map[string]any{
+					"type":        "string",
+					"description": "Base URL of the OpenAI-compatible API",
+				},
+				"headers": map[string]any{
+					"type":        "object",
+					"description": "HTTP headers sent with every request",
+					"additionalProperties": map[string]any{
+						"type": "string",
+					},
+				},
+				"apiKeyEnv": map[string]any{
+					"type":        "string",
+					"description": "Environment variable holding the API key, empty for APIs that need none",
+				},
+			},
+			"required": []string{"baseURL"},
+		},
+	}
+	schema["properties"].(map[string]any)["models"] = map[string]any{
+		"type":        "object",
+		"description": "Models in addition to the built-in ones, by ID",
+		"additionalProperties": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"provider": map[string]any{
+					"type":        "string",
+					"description": "A custom provider or a built-in provider, the provider of the built-in model with the same ID if empty",
+				},
+				"name": map[string]any{
+					"type":        "string",
+					"description": "Display name, the ID if empty",
+				},
+				"apiModel": map[string]any{
+					"type":        "string",
+					"description": "Model name sent to the API, the ID if empty",
+				},
+				"contextWindow": map[string]any{
+					"type":        "integer",
+					"description": "Context window in tokens",
+					"minimum":     1,
+				},
+				"defaultMaxTokens": map[string]any{
+					"type":        "integer",
+					"description": "Default maximum tokens of a response",
+					"minimum":     1,
+				},
+				"costPer1MIn": map[string]any{
+					"type":        "number",
+					"description": "Cost in USD per million input tokens",
+					"minimum":     0,
+				},
+				"costPer1MOut": map[string]any{
+					"type":        "number",
+					"description": "Cost in USD per million output tokens",
+					"minimum":     0,
+				},
+				"costPer1MInCached": map[string]any{
+					"type":        "number",
+					"description": "Cost in USD per million tokens written to the cache",
+					"minimum":     0,
+				},
+				"costPer1MOutCached": map[string]any{
+					"type":        "number",
+					"description": "Cost in USD per million tokens read from the cache",
+					"minimum":     0,
+				},
+				"canReason": map[string]any{
+					"type":        "boolean",
+					"description": "Whether the model supports reasoning effort",
+				},
+				"supportsAttachments": map[string]any{
+					"type":        "boolean",
+					"description": "Whether the model accepts images and files",
+				},
+				"api": map[string]any{
+					"type":        "string",
+					"description": "API used to call an OpenAI model",
+					"enum":        []string{string(models.APIChatCompletions), string(models.APIResponses)},
+				},
+				"chainResponses": map[string]any{
+					"type":        "boolean",
+					"description": "Whether responses are stored and continued instead of resending the conversation",
+				},
+			},
+		},
+	}
+
 	// Add agents
 	agentSchema := map[string]any{
 		"type":        "object",
@@ -232,8 +501,35 @@ func generateSchema() map[string]any {
 					"description": "Reasoning effort for models that support it (OpenAI, Anthropic)",
 					"enum":        []string{"low", "medium", "high"},
 				},
+				"description": map[string]any{
+					"type":        "string",
+					"description": "What a user-defined agent is for, shown to the model and in the agent list",
+				},
+				"prompt": map[string]any{
+					"type":        "string",
+					"description": "System prompt of a user-defined agent",
+				},
+				"tools": map[string]any{
+					"type":        "array",
+					"description": "Tools a user-defined agent may use, all coder tools if empty",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
+				"fallback": map[string]any{
+					"type":        "array",
+					"description": "Models to switch to, in order, when the provider of the current model fails",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
+				"permission": map[string]any{
+					"type":        "string",
+					"description": "How permission requests of a user-defined agent are answered",
+					"enum":        []string{string(config.PermissionAsk), string(config.PermissionAuto), string(config.PermissionDeny)},
+					"default":     string(config.PermissionAsk),
+				},
 			},
-			"required": []string{"model"},
 		},
 	}
 
@@ -242,7 +538,18 @@ func generateSchema() map[string]any {
 	for modelID := range models.SupportedModels {
 		modelEnum = append(modelEnum, string(modelID))
 	}
-	agentSchema["additionalProperties"].(map[string]any)["properties"].(map[string]any)["model"].(map[string]any)["enum"] = modelEnum
+	// Models declared in the config are not known here, so the built-in ones
+	// are only suggested
+	modelSchema := map[string]any{
+		"type":        "string",
+		"description": "Model ID for the agent",
+		"anyOf": []map[string]any{
+			{"enum": modelEnum},
+			{"type": "string"},
+		},
+	}
+	agentSchema["additionalProperties"].(map[string]any)["properties"].(map[string]any)["model"] = modelSchema
+	agentSchema["additionalProperties"].(map[string]any)["properties"].(map[string]any)["fallback"].(map[string]any)["items"] = modelSchema
 
 	// Add specific agent properties
 	agentProperties := map[string]any{}
@@ -267,6 +574,11 @@ func generateSchema() map[string]any {
 	}
 
 	schema["properties"].(map[string]any)["agents"] = combinedAgentSchema
+	schema["properties"].(map[string]any)["primaryAgent"] = map[string]any{
+		"type":        "string",
+		"description": "Agent that answers prompts, coder or a user-defined agent",
+		"default":     string(config.AgentCoder),
+	}
 	schema["definitions"] = map[string]any{
 		"agent": agentSchema["additionalProperties"],
 	}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Inspect and fork sessions",
+	Example: `
+  # List sessions
+  opencode session list
+
+  # List the messages of a session
+  opencode session messages <session-id>
+
+  # Branch a session from one of its messages
+  opencode session fork <session-id> <message-id>
+  `,
+}
+
+var sessionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sessions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := connectStore()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		sessions, err := session.NewService(db.New(conn), conn).List(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTITLE\tMESSAGES\tCOST\tUPDATED")
+		for _, s := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%d\t$%.2f\t%s\n",
+				s.ID,
+				s.Title,
+				s.MessageCount,
+				s.Cost,
+				time.Unix(s.UpdatedAt, 0).Format(time.DateTime),
+			)
+		}
+		return w.Flush()
+	},
+}
+
+var sessionMessagesCmd = &cobra.Command{
+	Use:   "messages <session-id>",
+	Short: "List the messages of a session",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := connectStore()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		messages, err := message.NewService(db.New(conn)).List(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tROLE\tCONTENT")
+		for _, msg := range messages {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", msg.ID, msg.Role, messagePreview(msg))
+		}
+		return w.Flush()
+	},
+}
+
+var sessionForkCmd = &cobra.Command{
+	Use:   "fork <session-id> <message-id>",
+	Short: "Create a new session from the history up to a message",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := connectStore()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		forked, err := session.NewService(db.New(conn), conn).Fork(cmd.Context(), args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("failed to fork session: %w", err)
+		}
+		fmt.Println(forked.ID)
+		return nil
+	},
+}
+
+// connectStore loads the configuration for the current directory and opens
+// the session database without starting the rest of the application.
+func connectStore() (*sql.DB, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %v", err)
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return nil, err
+	}
+	return db.Connect()
+}
+
+func messagePreview(msg message.Message) string {
+	text := msg.Content().String()
+	if text == "" {
+		for _, call := range msg.ToolCalls() {
+			return "[tool call: " + call.Name + "]"
+		}
+		for _, result := range msg.ToolResults() {
+			return "[tool result: " + result.Name + "]"
+		}
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > 60 {
+		text = string(runes[:57]) + "..."
+	}
+	return text
+}
+
+func init() {
+	sessionCmd.AddCommand(sessionListCmd, sessionMessagesCmd, sessionForkCmd)
+	rootCmd.AddCommand(sessionCmd)
+}
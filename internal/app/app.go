@@ -13,6 +13,7 @@ import (
 	"github.com/opencode-ai/opencode/internal/db"
 	"github.com/opencode-ai/opencode/internal/format"
 	"github.com/opencode-ai/opencode/internal/history"
+	"github.com/opencode-ai/opencode/internal/hooks"
 	"github.com/opencode-ai/opencode/internal/llm/agent"
 	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/lsp"
@@ -20,6 +21,7 @@ import (
 	"github.com/opencode-ai/opencode/internal/permission"
 	"github.com/opencode-ai/opencode/internal/session"
 	"github.com/opencode-ai/opencode/internal/tui/theme"
+	"github.com/opencode-ai/opencode/internal/worktree"
 )
 
 type App struct {
@@ -41,7 +43,7 @@ type App struct {
 
 func New(ctx context.Context, conn *sql.DB) (*App, error) {
 	q := db.New(conn)
-	sessions := session.NewService(q)
+	sessions := session.NewService(q, conn)
 	messages := message.NewService(q)
 	files := history.NewService(q, conn)
 
@@ -76,10 +78,29 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 		logging.Error("Failed to create coder agent", err)
 		return nil, err
 	}
+	if primary := config.PrimaryAgent(); primary != config.AgentCoder {
+		if err := app.SwitchAgent(primary); err != nil {
+			logging.Error("Failed to switch to the primary agent", "agent", primary, "error", err)
+			return nil, err
+		}
+	}
 
 	return app, nil
 }
 
+// SwitchAgent makes agentName the agent that answers the user's prompts.
+func (app *App) SwitchAgent(agentName config.AgentName) error {
+	_, err := app.CoderAgent.SwitchAgent(agentName, agent.AgentTools(
+		agentName,
+		app.Permissions,
+		app.Sessions,
+		app.Messages,
+		app.History,
+		app.LSPClients,
+	))
+	return err
+}
+
 // initTheme sets the application theme based on the configuration
 func (app *App) initTheme() {
 	cfg := config.Get()
@@ -96,26 +117,19 @@ func (app *App) initTheme() {
 	}
 }
 
-// RunNonInteractive handles the execution flow when a prompt is provided via CLI flag.
-func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat string, quiet bool) error {
+// RunNonInteractive handles the execution flow when prompts are provided via CLI flag.
+// The prompts run one after another in a single new session and each response is printed.
+func (a *App) RunNonInteractive(ctx context.Context, prompts []string, outputFormat string, quiet bool) error {
 	logging.Info("Running in non-interactive mode")
 
-	// Start spinner if not in quiet mode
-	var spinner *format.Spinner
-	if !quiet {
-		spinner = format.NewSpinner("Thinking...")
-		spinner.Start()
-		defer spinner.Stop()
-	}
-
 	const maxPromptLengthForTitle = 100
 	titlePrefix := "Non-interactive: "
 	var titleSuffix string
 
-	if len(prompt) > maxPromptLengthForTitle {
-		titleSuffix = prompt[:maxPromptLengthForTitle] + "..."
+	if len(prompts[0]) > maxPromptLengthForTitle {
+		titleSuffix = prompts[0][:maxPromptLengthForTitle] + "..."
 	} else {
-		titleSuffix = prompt
+		titleSuffix = prompts[0]
 	}
 	title := titlePrefix + titleSuffix
 
@@ -128,18 +142,44 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 	// Automatically approve all permission requests for this non-interactive session
 	a.Permissions.AutoApproveSession(sess.ID)
 
-	done, err := a.CoderAgent.Run(ctx, sess.ID, prompt)
+	for _, prompt := range prompts {
+		done, err := a.runNonInteractivePrompt(ctx, sess.ID, prompt, outputFormat, quiet)
+		if err != nil || !done {
+			return err
+		}
+	}
+
+	logging.Info("Non-interactive run completed", "session_id", sess.ID)
+
+	return nil
+}
+
+// runNonInteractivePrompt sends a single prompt and prints the response. It
+// reports false when the run was cancelled and no further prompts should run.
+func (a *App) runNonInteractivePrompt(ctx context.Context, sessionID, prompt, outputFormat string, quiet bool) (bool, error) {
+	// Start spinner if not in quiet mode
+	var spinner *format.Spinner
+	if !quiet {
+		spinner = format.NewSpinner("Thinking...")
+		spinner.Start()
+		defer spinner.Stop()
+	}
+
+	done, err := a.CoderAgent.Run(ctx, sessionID, prompt)
 	if err != nil {
-		return fmt.Errorf("failed to start agent processing stream: %w", err)
+		return false, fmt.Errorf("failed to start agent processing stream: %w", err)
 	}
 
 	result := <-done
 	if result.Error != nil {
 		if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
-			logging.Info("Agent processing cancelled", "session_id", sess.ID)
-			return nil
+			logging.Info("Agent processing cancelled", "session_id", sessionID)
+			return false, nil
 		}
-		return fmt.Errorf("agent processing failed: %w", result.Error)
+		return false, fmt.Errorf("agent processing failed: %w", result.Error)
+	}
+	if result.Limit != nil {
+		return false, fmt.Errorf("agent stopped: %w", result.Limit)
 	}
 
 	// Stop spinner before printing output
@@ -154,10 +194,7 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 	}
 
 	fmt.Println(format.FormatOutput(content, outputFormat))
-
-	logging.Info("Non-interactive run completed", "session_id", sess.ID)
-
-	return nil
+	return true, nil
 }
 
 // Shutdown performs a clean shutdown of the application
@@ -170,6 +207,11 @@ func (app *App) Shutdown() {
 	app.cancelFuncsMutex.Unlock()
 	app.watcherWG.Wait()
 
+	if err := worktree.RemoveAll(); err != nil {
+		logging.Error("Failed to remove agent worktrees", "error", err)
+	}
+	hooks.Close()
+
 	// Perform additional cleanup for LSP clients
 	app.clientsMutex.RLock()
 	clients := make(map[string]*lsp.Client, len(app.LSPClients))
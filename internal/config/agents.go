@@ -0,0 +1,238 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// PermissionMode controls how the permission requests of an agent's tools are
+// answered.
+type PermissionMode string
+
+const (
+	// PermissionAsk asks the user, this is the default.
+	PermissionAsk PermissionMode = "ask"
+	// PermissionAuto approves every request.
+	PermissionAuto PermissionMode = "auto"
+	// PermissionDeny rejects every request, making the agent read-only.
+	PermissionDeny PermissionMode = "deny"
+)
+
+var builtinAgents = []AgentName{AgentCoder, AgentSummarizer, AgentTask, AgentTitle}
+
+// agentFile is the front matter of an agent defined in a markdown file. The
+// body of the file is the system prompt.
+type agentFile struct {
+	Description     string         `yaml:"description"`
+	Model           string         `yaml:"model"`
+	MaxTokens       int64          `yaml:"maxTokens"`
+	ReasoningEffort string         `yaml:"reasoningEffort"`
+	Tools           []string       `yaml:"tools"`
+	Permission      PermissionMode `yaml:"permission"`
+	Fallback        []string       `yaml:"fallback"`
+}
+
+// IsCustomAgent reports whether name is a user-defined agent rather than one
+// of the built-in ones.
+func IsCustomAgent(name AgentName) bool {
+	return !slices.Contains(builtinAgents, name)
+}
+
+// CustomAgents returns the names of the user-defined agents, sorted.
+func CustomAgents() []AgentName {
+	var names []AgentName
+	if cfg == nil {
+		return names
+	}
+	for name := range cfg.Agents {
+		if IsCustomAgent(name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// PrimaryAgent returns the agent that answers the user's prompts.
+func PrimaryAgent() AgentName {
+	if cfg == nil || cfg.PrimaryAgent == "" {
+		return AgentCoder
+	}
+	return cfg.PrimaryAgent
+}
+
+// UpdatePrimaryAgent switches the primary agent for the running process.
+// Only the coder and user-defined agents can be primary.
+func UpdatePrimaryAgent(name AgentName) error {
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if _, ok := cfg.Agents[name]; !ok || (name != AgentCoder && !IsCustomAgent(name)) {
+		return fmt.Errorf("agent %s can not be used as the primary agent", name)
+	}
+	cfg.PrimaryAgent = name
+	return nil
+}
+
+// loadAgentFiles adds the agents defined as markdown files in the user and
+// project agents directories. Fields set for the same agent in the config
+// files take precedence, and built-in agent names are reserved.
+func loadAgentFiles() {
+	var dirs []string
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			xdgConfigHome = filepath.Join(home, ".config")
+		}
+	}
+	if xdgConfigHome != "" {
+		dirs = append(dirs, filepath.Join(xdgConfigHome, appName, "agents"))
+	}
+	dirs = append(dirs, filepath.Join(cfg.Data.Directory, "agents"))
+
+	if cfg.Agents == nil {
+		cfg.Agents = make(map[AgentName]Agent)
+	}
+	configured := make(map[AgentName]Agent, len(cfg.Agents))
+	for name, agent := range cfg.Agents {
+		configured[name] = agent
+	}
+	// Later directories override earlier ones, so project agents win over
+	// user agents with the same name.
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".md") {
+				continue
+			}
+			name := AgentName(strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))))
+			if !IsCustomAgent(name) {
+				logging.Warn("ignoring agent file with a reserved name", "file", entry.Name())
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			content, err := os.ReadFile(path)
+			if err != nil {
+				logging.Warn("failed to read agent file", "file", path, "error", err)
+				continue
+			}
+			agent, err := parseAgentFile(content)
+			if err != nil {
+				logging.Warn("failed to parse agent file", "file", path, "error", err)
+				continue
+			}
+			if override, ok := configured[name]; ok {
+				agent = mergeAgent(agent, override)
+			}
+			cfg.Agents[name] = agent
+		}
+	}
+}
+
+// mergeAgent returns base with the fields set in override replacing its own.
+func mergeAgent(base, override Agent) Agent {
+	if override.Model != "" {
+		base.Model = override.Model
+	}
+	if override.MaxTokens > 0 {
+		base.MaxTokens = override.MaxTokens
+	}
+	if override.ReasoningEffort != "" {
+		base.ReasoningEffort = override.ReasoningEffort
+	}
+	if override.Description != "" {
+		base.Description = override.Description
+	}
+	if override.Prompt != "" {
+		base.Prompt = override.Prompt
+	}
+	if len(override.Tools) > 0 {
+		base.Tools = override.Tools
+	}
+	if override.Permission != "" {
+		base.Permission = override.Permission
+	}
+	if len(override.Fallback) > 0 {
+		base.Fallback = override.Fallback
+	}
+	return base
+}
+
+// parseAgentFile reads an agent definition made of an optional YAML front
+// matter block followed by the system prompt.
+func parseAgentFile(content []byte) (Agent, error) {
+	var meta agentFile
+	body := content
+	if rest, ok := bytes.CutPrefix(bytes.TrimPrefix(content, []byte("\ufeff")), []byte("---")); ok {
+		frontMatter, prompt, found := bytes.Cut(rest, []byte("\n---"))
+		if !found {
+			return Agent{}, fmt.Errorf("unterminated front matter")
+		}
+		if err := yaml.Unmarshal(frontMatter, &meta); err != nil {
+			return Agent{}, fmt.Errorf("invalid front matter: %w", err)
+		}
+		// Drop the rest of the closing delimiter line
+		if _, after, found := bytes.Cut(prompt, []byte("\n")); found {
+			body = after
+		} else {
+			body = nil
+		}
+	}
+	var fallback []models.ModelID
+	for _, modelID := range meta.Fallback {
+		fallback = append(fallback, models.ModelID(modelID))
+	}
+	return Agent{
+		Model:           models.ModelID(meta.Model),
+		MaxTokens:       meta.MaxTokens,
+		ReasoningEffort: meta.ReasoningEffort,
+		Description:     meta.Description,
+		Prompt:          strings.TrimSpace(string(body)),
+		Tools:           meta.Tools,
+		Permission:      meta.Permission,
+		Fallback:        fallback,
+	}, nil
+}
+
+// applyAgentDefaults fills in what user-defined agents leave out: they use the
+// coder model and its fallbacks and ask for permissions unless configured
+// otherwise.
+func applyAgentDefaults() {
+	for name, agent := range cfg.Agents {
+		if !IsCustomAgent(name) {
+			continue
+		}
+		if agent.Model == "" {
+			agent.Model = cfg.Agents[AgentCoder].Model
+			if len(agent.Fallback) == 0 {
+				agent.Fallback = cfg.Agents[AgentCoder].Fallback
+			}
+		}
+		switch agent.Permission {
+		case PermissionAsk, PermissionAuto, PermissionDeny:
+		case "":
+			agent.Permission = PermissionAsk
+		default:
+			logging.Warn("invalid agent permission mode, asking instead", "agent", name, "permission", agent.Permission)
+			agent.Permission = PermissionAsk
+		}
+		cfg.Agents[name] = agent
+	}
+	if cfg.PrimaryAgent != "" {
+		if _, ok := cfg.Agents[cfg.PrimaryAgent]; !ok || !IsCustomAgent(cfg.PrimaryAgent) && cfg.PrimaryAgent != AgentCoder {
+			logging.Warn("unknown primary agent, using coder", "agent", cfg.PrimaryAgent)
+			cfg.PrimaryAgent = AgentCoder
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAgentFile(t *testing.T) {
+	content := `---
+description: Reviews changes for bugs
+model: claude-3.7-sonnet
+maxTokens: 2000
+tools: [view, grep]
+permission: deny
+fallback: [gpt-4.1]
+---
+You are a careful code reviewer.
+
+Report problems only.
+`
+	agent, err := parseAgentFile([]byte(content))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Reviews changes for bugs", agent.Description)
+	assert.Equal(t, "claude-3.7-sonnet", string(agent.Model))
+	assert.Equal(t, int64(2000), agent.MaxTokens)
+	assert.Equal(t, []string{"view", "grep"}, agent.Tools)
+	assert.Equal(t, PermissionDeny, agent.Permission)
+	assert.Equal(t, []models.ModelID{"gpt-4.1"}, agent.Fallback)
+	assert.Equal(t, "You are a careful code reviewer.\n\nReport problems only.", agent.Prompt)
+}
+
+func TestParseAgentFile_WithoutFrontMatter(t *testing.T) {
+	agent, err := parseAgentFile([]byte("Just a prompt\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Just a prompt", agent.Prompt)
+	assert.Empty(t, agent.Model)
+}
+
+func TestParseAgentFile_Unterminated(t *testing.T) {
+	_, err := parseAgentFile([]byte("---\nmodel: x\n"))
+	assert.Error(t, err)
+}
+
+func TestMergeAgent(t *testing.T) {
+	base := Agent{Model: "a", Prompt: "prompt", Tools: []string{"view"}}
+	merged := mergeAgent(base, Agent{Model: "b", MaxTokens: 100})
+
+	assert.Equal(t, "b", string(merged.Model))
+	assert.Equal(t, int64(100), merged.MaxTokens)
+	assert.Equal(t, "prompt", merged.Prompt)
+	assert.Equal(t, []string{"view"}, merged.Tools)
+}
@@ -8,7 +8,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/opencode-ai/opencode/internal/llm/models"
 	"github.com/opencode-ai/opencode/internal/logging"
@@ -44,10 +46,18 @@ const (
 )
 
 // Agent defines configuration for different LLM models and their token limits.
+// The remaining fields are only used by user-defined agents.
 type Agent struct {
 	Model           models.ModelID `json:"model"`
 	MaxTokens       int64          `json:"maxTokens"`
 	ReasoningEffort string         `json:"reasoningEffort"` // For openai models low,medium,heigh
+	Description     string         `json:"description,omitempty"`
+	Prompt          string         `json:"prompt,omitempty"`
+	Tools           []string       `json:"tools,omitempty"` // Allowed tool names, all coder tools if empty
+	Permission      PermissionMode `json:"permission,omitempty"`
+	// Fallback lists the models to switch to, in order, when the provider
+	// of the current model fails.
+	Fallback []models.ModelID `json:"fallback,omitempty"`
 }
 
 // Provider defines configuration for an LLM provider.
@@ -80,6 +90,62 @@ type ShellConfig struct {
 	Args []string `json:"args,omitempty"`
 }
 
+// BudgetConfig defines limits that stop the agent before it spends too much.
+// A zero value disables the corresponding limit.
+type BudgetConfig struct {
+	MaxSessionCost    float64 `json:"maxSessionCost,omitempty"`
+	MaxDailyCost      float64 `json:"maxDailyCost,omitempty"`
+	MaxToolIterations int     `json:"maxToolIterations,omitempty"`
+}
+
+// RetryConfig defines how requests that failed on rate limits, server errors
+// or overloads are retried. MaxWait is the number of seconds a request may
+// spend waiting between its retries in total.
+type RetryConfig struct {
+	MaxRetries int `json:"maxRetries,omitempty"`
+	MaxWait    int `json:"maxWait,omitempty"`
+}
+
+// CassetteMode is whether provider HTTP traffic is recorded into a cassette
+// or replayed from one.
+type CassetteMode string
+
+const (
+	CassetteRecord CassetteMode = "record"
+	CassetteReplay CassetteMode = "replay"
+)
+
+// CassetteConfig defines the cassette provider calls are recorded into or
+// replayed from. Name is the file name of the cassette in the cassettes
+// directory of the data directory. Recordings get a new name per run unless
+// one is given.
+type CassetteConfig struct {
+	Mode CassetteMode `json:"mode,omitempty"`
+	Name string       `json:"name,omitempty"`
+}
+
+// CompactionStrategy is how the agent shrinks a conversation that no longer
+// fits the context window.
+type CompactionStrategy string
+
+const (
+	// CompactionAuto prunes old tool results first and summarizes when that
+	// is not enough.
+	CompactionAuto CompactionStrategy = "auto"
+	// CompactionPrune only replaces old tool results with a placeholder.
+	CompactionPrune CompactionStrategy = "prune"
+	// CompactionSummarize replaces older turns with a summary.
+	CompactionSummarize CompactionStrategy = "summarize"
+)
+
+// CompactionConfig defines when and how the agent compacts the conversation.
+// Threshold is the share of the model's context window the estimated
+// conversation may use before it is compacted.
+type CompactionConfig struct {
+	Threshold float64            `json:"threshold,omitempty"`
+	Strategy  CompactionStrategy `json:"strategy,omitempty"`
+}
+
 // Config is the main configuration structure for the application.
 type Config struct {
 	Data         Data                              `json:"data"`
@@ -88,12 +154,25 @@ type Config struct {
 	Providers    map[models.ModelProvider]Provider `json:"providers,omitempty"`
 	LSP          map[string]LSPConfig              `json:"lsp,omitempty"`
 	Agents       map[AgentName]Agent               `json:"agents,omitempty"`
+	PrimaryAgent AgentName                         `json:"primaryAgent,omitempty"`
 	Debug        bool                              `json:"debug,omitempty"`
 	DebugLSP     bool                              `json:"debugLSP,omitempty"`
 	ContextPaths []string                          `json:"contextPaths,omitempty"`
 	TUI          TUIConfig                         `json:"tui"`
 	Shell        ShellConfig                       `json:"shell,omitempty"`
 	AutoCompact  bool                              `json:"autoCompact,omitempty"`
+	Compaction   CompactionConfig                  `json:"compaction,omitempty"`
+	Budget       BudgetConfig                      `json:"budget,omitempty"`
+	Retry        RetryConfig                       `json:"retry,omitempty"`
+	Cassette     CassetteConfig                    `json:"cassette,omitempty"`
+	Hooks        HooksConfig                       `json:"hooks,omitempty"`
+	// CustomProviders are OpenAI-compatible APIs by name.
+	CustomProviders map[string]CustomProvider `json:"customProviders,omitempty"`
+	// Models declares models in addition to the built-in ones, by ID.
+	Models map[string]CustomModel `json:"models,omitempty"`
+	// RefreshModels refreshes the cached model catalogs of the configured
+	// providers at startup once they are a day old.
+	RefreshModels bool `json:"refreshModels,omitempty"`
 }
 
 // Application constants
@@ -103,6 +182,10 @@ const (
 	appName              = "opencode"
 
 	MaxTokensFallbackDefault = 4096
+
+	defaultCompactionThreshold = 0.8
+	defaultMaxRetries          = 8
+	defaultMaxRetryWait        = 300
 )
 
 var defaultContextPaths = []string{
@@ -155,6 +238,9 @@ func Load(workingDir string, debug bool) (*Config, error) {
 		return cfg, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	loadModelCatalog()
+	loadCustomModels()
+	loadAgentFiles()
 	applyDefaultValues()
 	defaultLevel := slog.LevelInfo
 	if cfg.Debug {
@@ -232,6 +318,10 @@ func setDefaults(debug bool) {
 	viper.SetDefault("contextPaths", defaultContextPaths)
 	viper.SetDefault("tui.theme", "opencode")
 	viper.SetDefault("autoCompact", true)
+	viper.SetDefault("compaction.threshold", defaultCompactionThreshold)
+	viper.SetDefault("compaction.strategy", string(CompactionAuto))
+	viper.SetDefault("retry.maxRetries", defaultMaxRetries)
+	viper.SetDefault("retry.maxWait", defaultMaxRetryWait)
 
 	// Set default shell from environment or fallback to /bin/bash
 	shellPath := os.Getenv("SHELL")
@@ -471,14 +561,13 @@ func applyDefaultValues() {
 			cfg.MCPServers[k] = v
 		}
 	}
+	applyAgentDefaults()
 }
 
 // It validates model IDs and providers, ensuring they are supported.
 func validateAgent(cfg *Config, name AgentName, agent Agent) error {
-	// Check if model exists
-	// TODO:	If a copilot model is specified, but model is not found,
-	// 		 	it might be new model. The https://api.githubcopilot.com/models
-	// 		 	endpoint should be queried to validate if the model is supported.
+	// Check if model exists, models new to a provider are known once its
+	// catalog was refreshed with `opencode models refresh`
 	model, modelExists := models.SupportedModels[agent.Model]
 	if !modelExists {
 		return fmt.Errorf("unsupported model '%s' configured for agent %s", agent.Model, name)
@@ -539,7 +628,7 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 	}
 
 	// Validate reasoning effort for models that support reasoning
-	if model.CanReason && provider == models.ProviderOpenAI || provider == models.ProviderLocal {
+	if model.CanReason && (provider == models.ProviderOpenAI || provider == models.ProviderGemini || provider == models.ProviderVertexAI || IsCustomProvider(provider)) || provider == models.ProviderLocal {
 		if agent.ReasoningEffort == "" {
 			// Set default reasoning effort for models that support it
 			logging.Info("setting default reasoning effort for model that supports reasoning",
@@ -565,6 +654,19 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 				cfg.Agents[name] = updatedAgent
 			}
 		}
+	} else if model.CanReason && (provider == models.ProviderAnthropic || provider == models.ProviderOllama) {
+		// Anthropic and Ollama models only think with an effort, which sets
+		// the budget of Anthropic models
+		if effort := strings.ToLower(agent.ReasoningEffort); effort != "" && effort != "low" && effort != "medium" && effort != "high" {
+			logging.Warn("invalid reasoning effort, setting to medium",
+				"agent", name,
+				"model", agent.Model,
+				"reasoning_effort", agent.ReasoningEffort)
+
+			updatedAgent := cfg.Agents[name]
+			updatedAgent.ReasoningEffort = "medium"
+			cfg.Agents[name] = updatedAgent
+		}
 	} else if !model.CanReason && agent.ReasoningEffort != "" {
 		// Model doesn't support reasoning but reasoning effort is set
 		logging.Warn("model doesn't support reasoning but reasoning effort is set, ignoring",
@@ -578,9 +680,46 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 		cfg.Agents[name] = updatedAgent
 	}
 
+	// Drop the fallback models that can not be used
+	if len(agent.Fallback) > 0 {
+		updatedAgent := cfg.Agents[name]
+		updatedAgent.Fallback = validFallbacks(cfg, name, agent)
+		cfg.Agents[name] = updatedAgent
+	}
+
 	return nil
 }
 
+// validFallbacks returns the fallback models of an agent whose providers are
+// available, adding providers configured through the environment.
+func validFallbacks(cfg *Config, name AgentName, agent Agent) []models.ModelID {
+	var valid []models.ModelID
+	for _, modelID := range agent.Fallback {
+		model, ok := models.SupportedModels[modelID]
+		if !ok {
+			logging.Warn("unsupported fallback model, ignoring", "agent", name, "model", modelID)
+			continue
+		}
+		if modelID == agent.Model || slices.Contains(valid, modelID) {
+			continue
+		}
+		providerCfg, ok := cfg.Providers[model.Provider]
+		if !ok {
+			apiKey := getProviderAPIKey(model.Provider)
+			if apiKey == "" {
+				logging.Warn("provider of fallback model not configured, ignoring", "agent", name, "model", modelID, "provider", model.Provider)
+				continue
+			}
+			cfg.Providers[model.Provider] = Provider{APIKey: apiKey}
+		} else if providerCfg.Disabled || providerCfg.APIKey == "" {
+			logging.Warn("provider of fallback model not available, ignoring", "agent", name, "model", modelID, "provider", model.Provider)
+			continue
+		}
+		valid = append(valid, modelID)
+	}
+	return valid
+}
+
 // Validate checks if the configuration is valid and applies defaults where needed.
 func Validate() error {
 	if cfg == nil {
@@ -604,6 +743,48 @@ func Validate() error {
 		}
 	}
 
+	// Validate hooks
+	validateHooks(cfg)
+
+	// Validate compaction
+	if cfg.Compaction.Threshold <= 0 || cfg.Compaction.Threshold > 1 {
+		logging.Warn("compaction threshold must be between 0 and 1, using default", "threshold", cfg.Compaction.Threshold, "default", defaultCompactionThreshold)
+		cfg.Compaction.Threshold = defaultCompactionThreshold
+	}
+	switch cfg.Compaction.Strategy {
+	case CompactionAuto, CompactionPrune, CompactionSummarize:
+	default:
+		logging.Warn("unknown compaction strategy, using auto", "strategy", cfg.Compaction.Strategy)
+		cfg.Compaction.Strategy = CompactionAuto
+	}
+
+	// Validate retries
+	if cfg.Retry.MaxRetries < 0 {
+		logging.Warn("negative retry count, using default", "maxRetries", cfg.Retry.MaxRetries, "default", defaultMaxRetries)
+		cfg.Retry.MaxRetries = defaultMaxRetries
+	}
+	if cfg.Retry.MaxWait < 0 {
+		logging.Warn("negative retry wait, using default", "maxWait", cfg.Retry.MaxWait, "default", defaultMaxRetryWait)
+		cfg.Retry.MaxWait = defaultMaxRetryWait
+	}
+
+	// Validate cassette
+	switch cfg.Cassette.Mode {
+	case "":
+	case CassetteRecord:
+		if cfg.Cassette.Name == "" {
+			cfg.Cassette.Name = time.Now().Format("20060102-150405")
+		}
+	case CassetteReplay:
+		if cfg.Cassette.Name == "" {
+			logging.Warn("no cassette to replay, calling providers")
+			cfg.Cassette.Mode = ""
+		}
+	default:
+		logging.Warn("unknown cassette mode, calling providers", "mode", cfg.Cassette.Mode)
+		cfg.Cassette.Mode = ""
+	}
+
 	// Validate LSP configurations
 	for language, lspConfig := range cfg.LSP {
 		if lspConfig.Command == "" && !lspConfig.Disabled {
@@ -886,11 +1067,9 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 		maxTokens = model.DefaultMaxTokens
 	}
 
-	newAgentCfg := Agent{
-		Model:           modelID,
-		MaxTokens:       maxTokens,
-		ReasoningEffort: existingAgentCfg.ReasoningEffort,
-	}
+	newAgentCfg := existingAgentCfg
+	newAgentCfg.Model = modelID
+	newAgentCfg.MaxTokens = maxTokens
 	cfg.Agents[agentName] = newAgentCfg
 	logging.Info("updated agent config", "agent", agentName, "newModel", modelID)
 
@@ -905,7 +1084,13 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 		if config.Agents == nil {
 			config.Agents = make(map[AgentName]Agent)
 		}
-		config.Agents[agentName] = newAgentCfg
+		// Only the model settings are written, the rest of a user-defined
+		// agent may come from its markdown file.
+		fileAgentCfg := config.Agents[agentName]
+		fileAgentCfg.Model = newAgentCfg.Model
+		fileAgentCfg.MaxTokens = newAgentCfg.MaxTokens
+		fileAgentCfg.ReasoningEffort = newAgentCfg.ReasoningEffort
+		config.Agents[agentName] = fileAgentCfg
 	})
 }
 
@@ -924,6 +1109,34 @@ func UpdateTheme(themeName string) error {
 	})
 }
 
+// UpdateBudget replaces the budget limits for the running process.
+// The change is intentionally not written to the config file.
+func UpdateBudget(budget BudgetConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	cfg.Budget = budget
+	return nil
+}
+
+// ModelCatalogPath returns the path of the cached model catalog.
+func ModelCatalogPath() string {
+	return filepath.Join(cfg.Data.Directory, "models.json")
+}
+
+// loadModelCatalog merges the cached model catalog into the supported models.
+func loadModelCatalog() {
+	catalog, err := models.ReadCatalog(ModelCatalogPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.Warn("failed to read the model catalog", "error", err)
+		}
+		return
+	}
+	models.MergeCatalog(catalog.Models)
+}
+
 // Tries to load Github token from all possible locations
 func LoadGitHubToken() (string, error) {
 	// First check environment variable
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidFallbacks(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("GROQ_API_KEY", "key")
+	cfg := &Config{Providers: map[models.ModelProvider]Provider{
+		models.ProviderAnthropic: {APIKey: "key"},
+		models.ProviderOpenAI:    {APIKey: "key", Disabled: true},
+	}}
+	agent := Agent{
+		Model: models.Claude37Sonnet,
+		Fallback: []models.ModelID{
+			"unknown-model",
+			models.Claude37Sonnet,
+			models.Claude4Sonnet,
+			models.GPT41,
+			models.Gemini25Flash,
+			models.QWENQwq,
+			models.Claude4Sonnet,
+		},
+	}
+
+	assert.Equal(t, []models.ModelID{models.Claude4Sonnet, models.QWENQwq}, validFallbacks(cfg, AgentCoder, agent))
+	assert.Equal(t, "key", cfg.Providers[models.ProviderGROQ].APIKey)
+}
+
+func TestLoadCustomModels(t *testing.T) {
+	t.Setenv("TOGETHER_API_KEY", "secret")
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg = &Config{
+		Providers: map[models.ModelProvider]Provider{},
+		CustomProviders: map[string]CustomProvider{
+			"together": {BaseURL: "https://api.together.xyz/v1", APIKeyEnv: "TOGETHER_API_KEY"},
+			"ollama":   {BaseURL: "http://localhost:11434/v1"},
+			"openai":   {BaseURL: "https://example.com"},
+			"nourl":    {},
+		},
+		Models: map[string]CustomModel{
+			"together.llama-3.3-70b": {Provider: "together", APIModel: "meta-llama/Llama-3.3-70B-Instruct-Turbo", ContextWindow: 131072, CostPer1MIn: 0.88},
+			"gpt-4.2":                {Provider: models.ProviderOpenAI, ContextWindow: 100000, DefaultMaxTokens: 20000},
+			"unknown.model":          {Provider: "unknown", ContextWindow: 1000},
+			"ollama.nowindow":        {Provider: "ollama"},
+			"o4-mini":                {API: models.APIResponses, CostPer1MIn: 1},
+			"noprovider":             {ContextWindow: 1000},
+		},
+	}
+	builtin := models.SupportedModels[models.O4Mini]
+	t.Cleanup(func() {
+		for _, id := range []models.ModelID{"together.llama-3.3-70b", "gpt-4.2"} {
+			delete(models.SupportedModels, id)
+		}
+		models.SupportedModels[models.O4Mini] = builtin
+	})
+
+	loadCustomModels()
+
+	assert.Equal(t, "secret", cfg.Providers["together"].APIKey)
+	assert.Equal(t, noAPIKey, cfg.Providers["ollama"].APIKey)
+	assert.NotContains(t, cfg.CustomProviders, "openai")
+	assert.NotContains(t, cfg.CustomProviders, "nourl")
+	assert.True(t, IsCustomProvider("together"))
+
+	llama := models.SupportedModels["together.llama-3.3-70b"]
+	assert.Equal(t, models.ModelProvider("together"), llama.Provider)
+	assert.Equal(t, "meta-llama/Llama-3.3-70B-Instruct-Turbo", llama.APIModel)
+	assert.Equal(t, "together.llama-3.3-70b", llama.Name)
+	assert.Equal(t, int64(MaxTokensFallbackDefault), llama.DefaultMaxTokens)
+	assert.Equal(t, 0.88, llama.CostPer1MIn)
+
+	gpt := models.SupportedModels["gpt-4.2"]
+	assert.Equal(t, "gpt-4.2", gpt.APIModel)
+	assert.Equal(t, int64(20000), gpt.DefaultMaxTokens)
+
+	assert.NotContains(t, models.SupportedModels, models.ModelID("unknown.model"))
+	assert.NotContains(t, models.SupportedModels, models.ModelID("ollama.nowindow"))
+	assert.NotContains(t, models.SupportedModels, models.ModelID("noprovider"))
+
+	o4Mini := models.SupportedModels[models.O4Mini]
+	assert.Equal(t, models.APIResponses, o4Mini.API)
+	assert.Equal(t, 1.0, o4Mini.CostPer1MIn)
+	assert.Equal(t, builtin.ContextWindow, o4Mini.ContextWindow)
+	assert.Equal(t, builtin.APIModel, o4Mini.APIModel)
+	assert.Equal(t, models.ProviderOpenAI, o4Mini.Provider)
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"cmp"
+	"os"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// CustomProvider is an OpenAI-compatible API whose models are declared under
+// models. Its name is used as the provider of those models.
+type CustomProvider struct {
+	BaseURL string            `json:"baseURL"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// APIKeyEnv names the environment variable holding the API key. APIs
+	// that need no key can leave it empty.
+	APIKeyEnv string `json:"apiKeyEnv,omitempty"`
+}
+
+// CustomModel declares a model of a custom or a built-in provider. It is
+// added to the supported models under its ID. The fields it sets override
+// those of a built-in model with the same ID and provider.
+type CustomModel struct {
+	Provider            models.ModelProvider `json:"provider,omitempty"`
+	Name                string               `json:"name,omitempty"`
+	APIModel            string               `json:"apiModel,omitempty"`
+	ContextWindow       int64                `json:"contextWindow"`
+	DefaultMaxTokens    int64                `json:"defaultMaxTokens,omitempty"`
+	CostPer1MIn         float64              `json:"costPer1MIn,omitempty"`
+	CostPer1MOut        float64              `json:"costPer1MOut,omitempty"`
+	CostPer1MInCached   float64              `json:"costPer1MInCached,omitempty"`
+	CostPer1MOutCached  float64              `json:"costPer1MOutCached,omitempty"`
+	CanReason           bool                 `json:"canReason,omitempty"`
+	SupportsAttachments bool                 `json:"supportsAttachments,omitempty"`
+	API                 models.ModelAPI      `json:"api,omitempty"`
+	ChainResponses      bool                 `json:"chainResponses,omitempty"`
+}
+
+// noAPIKey stands in for the key of custom providers that need none, so that
+// they are not taken for unconfigured providers.
+const noAPIKey = "none"
+
+// IsCustomProvider reports whether provider is one of the configured custom
+// providers.
+func IsCustomProvider(provider models.ModelProvider) bool {
+	if cfg == nil {
+		return false
+	}
+	_, ok := cfg.CustomProviders[string(provider)]
+	return ok
+}
+
+// GetCustomProvider returns the configuration of a custom provider.
+func GetCustomProvider(provider models.ModelProvider) (CustomProvider, bool) {
+	if cfg == nil {
+		return CustomProvider{}, false
+	}
+	custom, ok := cfg.CustomProviders[string(provider)]
+	return custom, ok
+}
+
+// loadCustomModels registers the configured custom providers and models so
+// they can be used like the built-in ones.
+func loadCustomModels() {
+	for name, custom := range cfg.CustomProviders {
+		provider := models.ModelProvider(name)
+		if _, builtin := models.ProviderPopularity[provider]; builtin || provider == models.ProviderLocal {
+			logging.Warn("custom provider uses the name of a built-in provider, ignoring", "provider", name)
+			delete(cfg.CustomProviders, name)
+			continue
+		}
+		if custom.BaseURL == "" {
+			logging.Warn("custom provider has no base URL, ignoring", "provider", name)
+			delete(cfg.CustomProviders, name)
+			continue
+		}
+		// A key set under providers takes precedence
+		if providerCfg, ok := cfg.Providers[provider]; ok && providerCfg.APIKey != "" {
+			continue
+		}
+		apiKey := noAPIKey
+		if custom.APIKeyEnv != "" {
+			// Left empty the provider is disabled during validation
+			apiKey = os.Getenv(custom.APIKeyEnv)
+		}
+		providerCfg := cfg.Providers[provider]
+		providerCfg.APIKey = apiKey
+		cfg.Providers[provider] = providerCfg
+	}
+
+	for id, custom := range cfg.Models {
+		modelID := models.ModelID(id)
+		model, builtin := models.SupportedModels[modelID]
+		if custom.Provider == "" {
+			if !builtin {
+				logging.Warn("model has no provider, ignoring", "model", id)
+				continue
+			}
+			custom.Provider = model.Provider
+		}
+		if _, known := models.ProviderPopularity[custom.Provider]; !known && !IsCustomProvider(custom.Provider) {
+			logging.Warn("model uses an unknown provider, ignoring", "model", id, "provider", custom.Provider)
+			continue
+		}
+		if !builtin || model.Provider != custom.Provider {
+			model = models.Model{ID: modelID, Provider: custom.Provider}
+		}
+		switch custom.API {
+		case "", models.APIChatCompletions, models.APIResponses:
+		default:
+			logging.Warn("unknown model API, ignoring", "model", id, "api", custom.API)
+			custom.API = ""
+		}
+
+		model.Name = cmp.Or(custom.Name, model.Name, id)
+		model.APIModel = cmp.Or(custom.APIModel, model.APIModel, id)
+		model.ContextWindow = cmp.Or(custom.ContextWindow, model.ContextWindow)
+		model.DefaultMaxTokens = cmp.Or(custom.DefaultMaxTokens, model.DefaultMaxTokens)
+		model.CostPer1MIn = cmp.Or(custom.CostPer1MIn, model.CostPer1MIn)
+		model.CostPer1MOut = cmp.Or(custom.CostPer1MOut, model.CostPer1MOut)
+		model.CostPer1MInCached = cmp.Or(custom.CostPer1MInCached, model.CostPer1MInCached)
+		model.CostPer1MOutCached = cmp.Or(custom.CostPer1MOutCached, model.CostPer1MOutCached)
+		model.CanReason = custom.CanReason || model.CanReason
+		model.SupportsAttachments = custom.SupportsAttachments || model.SupportsAttachments
+		model.API = cmp.Or(custom.API, model.API)
+		model.ChainResponses = custom.ChainResponses || model.ChainResponses
+
+		if model.ContextWindow <= 0 {
+			logging.Warn("model has no context window, ignoring", "model", id)
+			continue
+		}
+		if model.DefaultMaxTokens <= 0 {
+			model.DefaultMaxTokens = min(MaxTokensFallbackDefault, model.ContextWindow/2)
+		}
+		models.SupportedModels[modelID] = model
+	}
+}
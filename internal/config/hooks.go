@@ -0,0 +1,155 @@
+package config
+
+import (
+	"path"
+	"slices"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// TurnHook runs after each completed turn of the matching agents and models.
+// It is either a shell command that receives the turn as JSON on stdin, or a
+// tool of one of the configured MCP servers that receives it as arguments.
+type TurnHook struct {
+	Command   string           `json:"command,omitempty"`
+	MCPServer string           `json:"mcpServer,omitempty"`
+	Tool      string           `json:"tool,omitempty"`
+	Agents    []AgentName      `json:"agents,omitempty"`
+	Models    []models.ModelID `json:"models,omitempty"`
+	// Timeout is in seconds.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// ToolHook runs a shell command around the calls of the matching tools, with
+// the call as JSON on stdin. Tools are matched by name and Paths are glob
+// patterns, relative to the working directory, matched against the files the
+// call works on.
+type ToolHook struct {
+	Command string   `json:"command"`
+	Tools   []string `json:"tools,omitempty"`
+	Paths   []string `json:"paths,omitempty"`
+	// Timeout is in seconds.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// HooksConfig defines the hooks that run around the agent.
+type HooksConfig struct {
+	PostTurn    []TurnHook `json:"postTurn,omitempty"`
+	PreToolUse  []ToolHook `json:"preToolUse,omitempty"`
+	PostToolUse []ToolHook `json:"postToolUse,omitempty"`
+}
+
+const defaultHookTimeout = 10
+
+// Matches reports whether the hook applies to the agent running the model.
+// Empty agent and model lists match everything.
+func (h TurnHook) Matches(agentName AgentName, modelID models.ModelID) bool {
+	return (len(h.Agents) == 0 || slices.Contains(h.Agents, agentName)) &&
+		(len(h.Models) == 0 || slices.Contains(h.Models, modelID))
+}
+
+// PostTurnHooks returns the post-turn hooks that apply to the agent running
+// the model.
+func PostTurnHooks(agentName AgentName, modelID models.ModelID) []TurnHook {
+	if cfg == nil {
+		return nil
+	}
+	var hooks []TurnHook
+	for _, hook := range cfg.Hooks.PostTurn {
+		if hook.Matches(agentName, modelID) {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
+
+// PreToolUseHooks returns the configured pre-tool-use hooks.
+func PreToolUseHooks() []ToolHook {
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Hooks.PreToolUse
+}
+
+// PostToolUseHooks returns the configured post-tool-use hooks.
+func PostToolUseHooks() []ToolHook {
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Hooks.PostToolUse
+}
+
+// Matches reports whether the hook applies to a call of the named tool working
+// on paths. Empty tool and path lists match every call, a hook with paths
+// only matches calls on at least one matching file.
+func (h ToolHook) Matches(toolName string, paths []string) bool {
+	if len(h.Tools) > 0 && !slices.ContainsFunc(h.Tools, func(pattern string) bool {
+		matched, _ := path.Match(pattern, toolName)
+		return matched
+	}) {
+		return false
+	}
+	if len(h.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range h.Paths {
+		for _, p := range paths {
+			if doublestar.MatchUnvalidated(pattern, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateHooks drops the hooks that can not run and sets default timeouts.
+func validateHooks(cfg *Config) {
+	valid := cfg.Hooks.PostTurn[:0]
+	for i, hook := range cfg.Hooks.PostTurn {
+		switch {
+		case hook.Command == "" && hook.MCPServer == "":
+			logging.Warn("post-turn hook has neither a command nor an MCP server, ignoring", "hook", i)
+			continue
+		case hook.Command != "" && hook.MCPServer != "":
+			logging.Warn("post-turn hook has both a command and an MCP server, ignoring", "hook", i)
+			continue
+		case hook.MCPServer != "":
+			if _, ok := cfg.MCPServers[hook.MCPServer]; !ok {
+				logging.Warn("post-turn hook uses an unknown MCP server, ignoring", "hook", i, "server", hook.MCPServer)
+				continue
+			}
+			if hook.Tool == "" {
+				logging.Warn("post-turn hook has no MCP tool, ignoring", "hook", i, "server", hook.MCPServer)
+				continue
+			}
+		}
+		if hook.Timeout <= 0 {
+			hook.Timeout = defaultHookTimeout
+		}
+		valid = append(valid, hook)
+	}
+	cfg.Hooks.PostTurn = valid
+	cfg.Hooks.PreToolUse = validToolHooks("pre-tool-use", cfg.Hooks.PreToolUse)
+	cfg.Hooks.PostToolUse = validToolHooks("post-tool-use", cfg.Hooks.PostToolUse)
+}
+
+func validToolHooks(kind string, toolHooks []ToolHook) []ToolHook {
+	valid := toolHooks[:0]
+	for i, hook := range toolHooks {
+		if hook.Command == "" {
+			logging.Warn(kind+" hook has no command, ignoring", "hook", i)
+			continue
+		}
+		if j := slices.IndexFunc(hook.Paths, func(pattern string) bool { return !doublestar.ValidatePattern(pattern) }); j != -1 {
+			logging.Warn(kind+" hook has an invalid path pattern, ignoring", "hook", i, "pattern", hook.Paths[j])
+			continue
+		}
+		if hook.Timeout <= 0 {
+			hook.Timeout = defaultHookTimeout
+		}
+		valid = append(valid, hook)
+	}
+	return valid
+}
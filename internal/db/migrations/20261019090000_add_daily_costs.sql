@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS daily_costs (
+    day TEXT PRIMARY KEY,  -- Local date, YYYY-MM-DD
+    cost REAL NOT NULL DEFAULT 0.0 CHECK (cost >= 0.0)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS daily_costs;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages ADD COLUMN seq INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE files ADD COLUMN seq INTEGER NOT NULL DEFAULT 0;
+
+-- Number the existing messages and file versions in the order they were
+-- created, breaking ties between equal timestamps by insertion order
+CREATE TEMP TABLE history_order AS
+SELECT kind, id, ROW_NUMBER() OVER (ORDER BY created_at, kind, row) AS seq
+FROM (
+    SELECT 0 AS kind, id, created_at, rowid AS row FROM messages
+    UNION ALL
+    SELECT 1 AS kind, id, created_at, rowid AS row FROM files
+);
+UPDATE messages SET seq = o.seq FROM history_order o WHERE o.kind = 0 AND o.id = messages.id;
+UPDATE files SET seq = o.seq FROM history_order o WHERE o.kind = 1 AND o.id = files.id;
+DROP TABLE history_order;
+
+CREATE INDEX IF NOT EXISTS idx_messages_seq ON messages (seq);
+CREATE INDEX IF NOT EXISTS idx_files_seq ON files (seq);
+
+-- The last sequence number given to a message or file version. Sequence
+-- numbers order them within and across sessions, unlike the timestamps that
+-- only have a resolution of a second.
+CREATE VIEW IF NOT EXISTS history_seq AS
+SELECT MAX(seq) AS seq
+FROM (
+    SELECT MAX(seq) AS seq FROM messages
+    UNION ALL
+    SELECT MAX(seq) AS seq FROM files
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW IF EXISTS history_seq;
+DROP INDEX IF EXISTS idx_files_seq;
+DROP INDEX IF EXISTS idx_messages_seq;
+ALTER TABLE files DROP COLUMN seq;
+ALTER TABLE messages DROP COLUMN seq;
+-- +goose StatementEnd
@@ -10,6 +10,22 @@ import (
 	"database/sql"
 )
 
+const addDailyCost = `-- name: AddDailyCost :exec
+INSERT INTO daily_costs (day, cost)
+VALUES (?, ?)
+ON CONFLICT (day) DO UPDATE SET cost = cost + excluded.cost
+`
+
+type AddDailyCostParams struct {
+	Day  string  `json:"day"`
+	Cost float64 `json:"cost"`
+}
+
+func (q *Queries) AddDailyCost(ctx context.Context, arg AddDailyCostParams) error {
+	_, err := q.exec(ctx, q.addDailyCostStmt, addDailyCost, arg.Day, arg.Cost)
+	return err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
     id,
@@ -20,6 +36,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    forked_from_message_id,
     updated_at,
     created_at
 ) VALUES (
@@ -31,19 +48,21 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
-) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, forked_from_message_id
 `
 
 type CreateSessionParams struct {
-	ID               string         `json:"id"`
-	ParentSessionID  sql.NullString `json:"parent_session_id"`
-	Title            string         `json:"title"`
-	MessageCount     int64          `json:"message_count"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	Cost             float64        `json:"cost"`
+	ID                  string         `json:"id"`
+	ParentSessionID     sql.NullString `json:"parent_session_id"`
+	Title               string         `json:"title"`
+	MessageCount        int64          `json:"message_count"`
+	PromptTokens        int64          `json:"prompt_tokens"`
+	CompletionTokens    int64          `json:"completion_tokens"`
+	Cost                float64        `json:"cost"`
+	ForkedFromMessageID sql.NullString `json:"forked_from_message_id"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -55,6 +74,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.PromptTokens,
 		arg.CompletionTokens,
 		arg.Cost,
+		arg.ForkedFromMessageID,
 	)
 	var i Session
 	err := row.Scan(
@@ -68,6 +88,7 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.ForkedFromMessageID,
 	)
 	return i, err
 }
@@ -82,8 +103,21 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 	return err
 }
 
+const getDailyCost = `-- name: GetDailyCost :one
+SELECT CAST(COALESCE(SUM(cost), 0.0) AS REAL) AS total_cost
+FROM daily_costs
+WHERE day = ?
+`
+
+func (q *Queries) GetDailyCost(ctx context.Context, day string) (float64, error) {
+	row := q.queryRow(ctx, q.getDailyCostStmt, getDailyCost, day)
+	var total_cost float64
+	err := row.Scan(&total_cost)
+	return total_cost, err
+}
+
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, forked_from_message_id
 FROM sessions
 WHERE id = ? LIMIT 1
 `
@@ -102,14 +136,59 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (Session, error
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.ForkedFromMessageID,
 	)
 	return i, err
 }
 
+const listAgentSessions = `-- name: ListAgentSessions :many
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, forked_from_message_id
+FROM sessions
+WHERE parent_session_id is NOT NULL
+  AND forked_from_message_id is NULL
+  AND id NOT LIKE 'title-%'
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListAgentSessions(ctx context.Context) ([]Session, error) {
+	rows, err := q.query(ctx, q.listAgentSessionsStmt, listAgentSessions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentSessionID,
+			&i.Title,
+			&i.MessageCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.SummaryMessageID,
+			&i.ForkedFromMessageID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSessions = `-- name: ListSessions :many
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, forked_from_message_id
 FROM sessions
-WHERE parent_session_id is NULL
+WHERE parent_session_id is NULL OR forked_from_message_id is NOT NULL
 ORDER BY created_at DESC
 `
 
@@ -133,6 +212,7 @@ func (q *Queries) ListSessions(ctx context.Context) ([]Session, error) {
 			&i.UpdatedAt,
 			&i.CreatedAt,
 			&i.SummaryMessageID,
+			&i.ForkedFromMessageID,
 		); err != nil {
 			return nil, err
 		}
@@ -156,7 +236,7 @@ SET
     summary_message_id = ?,
     cost = ?
 WHERE id = ?
-RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, forked_from_message_id
 `
 
 type UpdateSessionParams struct {
@@ -189,6 +269,7 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.ForkedFromMessageID,
 	)
 	return i, err
 }
@@ -7,20 +7,36 @@ WHERE id = ? LIMIT 1;
 SELECT *
 FROM files
 WHERE path = ? AND session_id = ?
-ORDER BY created_at DESC
+ORDER BY seq DESC
 LIMIT 1;
 
 -- name: ListFilesBySession :many
 SELECT *
 FROM files
 WHERE session_id = ?
-ORDER BY created_at ASC;
+ORDER BY seq ASC;
 
 -- name: ListFilesByPath :many
 SELECT *
 FROM files
 WHERE path = ?
-ORDER BY created_at DESC;
+ORDER BY seq DESC;
+
+-- name: CopyFile :one
+INSERT INTO files (
+    id,
+    session_id,
+    path,
+    content,
+    version,
+    is_new,
+    created_at,
+    updated_at,
+    seq
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(seq, 0) + 1 FROM history_seq)
+)
+RETURNING *;
 
 -- name: CreateFile :one
 INSERT INTO files (
@@ -29,10 +45,12 @@ INSERT INTO files (
     path,
     content,
     version,
+    is_new,
     created_at,
-    updated_at
+    updated_at,
+    seq
 ) VALUES (
-    ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
+    ?, ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now'), (SELECT COALESCE(seq, 0) + 1 FROM history_seq)
 )
 RETURNING *;
 
@@ -57,10 +75,10 @@ WHERE session_id = ?;
 SELECT f.*
 FROM files f
 INNER JOIN (
-    SELECT path, MAX(created_at) as max_created_at
+    SELECT path, MAX(seq) as max_seq
     FROM files
     GROUP BY path
-) latest ON f.path = latest.path AND f.created_at = latest.max_created_at
+) latest ON f.path = latest.path AND f.seq = latest.max_seq
 WHERE f.session_id = ?
 ORDER BY f.path;
 
@@ -68,4 +86,4 @@ ORDER BY f.path;
 SELECT *
 FROM files
 WHERE is_new = 1
-ORDER BY created_at DESC;
+ORDER BY seq DESC;
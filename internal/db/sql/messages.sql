@@ -7,7 +7,23 @@ WHERE id = ? LIMIT 1;
 SELECT *
 FROM messages
 WHERE session_id = ?
-ORDER BY created_at ASC;
+ORDER BY seq ASC;
+
+-- name: CopyMessage :one
+INSERT INTO messages (
+    id,
+    session_id,
+    role,
+    parts,
+    model,
+    created_at,
+    updated_at,
+    finished_at,
+    seq
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(seq, 0) + 1 FROM history_seq)
+)
+RETURNING *;
 
 -- name: CreateMessage :one
 INSERT INTO messages (
@@ -17,9 +33,10 @@ INSERT INTO messages (
     parts,
     model,
     created_at,
-    updated_at
+    updated_at,
+    seq
 ) VALUES (
-    ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
+    ?, ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now'), (SELECT COALESCE(seq, 0) + 1 FROM history_seq)
 )
 RETURNING *;
 
@@ -36,6 +53,10 @@ WHERE id = ?;
 DELETE FROM messages
 WHERE id = ?;
 
+-- name: DeleteMessagesFrom :exec
+DELETE FROM messages
+WHERE session_id = ? AND seq >= ?;
+
 -- name: DeleteSessionMessages :exec
 DELETE FROM messages
 WHERE session_id = ?;
@@ -8,6 +8,7 @@ INSERT INTO sessions (
     completion_tokens,
     cost,
     summary_message_id,
+    forked_from_message_id,
     updated_at,
     created_at
 ) VALUES (
@@ -19,6 +20,7 @@ INSERT INTO sessions (
     ?,
     ?,
     null,
+    ?,
     strftime('%s', 'now'),
     strftime('%s', 'now')
 ) RETURNING *;
@@ -28,10 +30,18 @@ SELECT *
 FROM sessions
 WHERE id = ? LIMIT 1;
 
+-- name: ListAgentSessions :many
+SELECT *
+FROM sessions
+WHERE parent_session_id is NOT NULL
+  AND forked_from_message_id is NULL
+  AND id NOT LIKE 'title-%'
+ORDER BY created_at ASC;
+
 -- name: ListSessions :many
 SELECT *
 FROM sessions
-WHERE parent_session_id is NULL
+WHERE parent_session_id is NULL OR forked_from_message_id is NOT NULL
 ORDER BY created_at DESC;
 
 -- name: UpdateSession :one
@@ -49,3 +59,13 @@ RETURNING *;
 -- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = ?;
+
+-- name: AddDailyCost :exec
+INSERT INTO daily_costs (day, cost)
+VALUES (?, ?)
+ON CONFLICT (day) DO UPDATE SET cost = cost + excluded.cost;
+
+-- name: GetDailyCost :one
+SELECT CAST(COALESCE(SUM(cost), 0.0) AS REAL) AS total_cost
+FROM daily_costs
+WHERE day = ?;
@@ -23,13 +23,20 @@ type File struct {
 	Path      string
 	Content   string
 	Version   string
+	// IsNew marks the initial version of a file the session created, which
+	// records that the file did not exist before.
+	IsNew     bool
 	CreatedAt int64
 	UpdatedAt int64
+	// Seq orders the version among the messages and file versions of all
+	// sessions, even those created within the same second.
+	Seq int64
 }
 
 type Service interface {
 	pubsub.Suscriber[File]
 	Create(ctx context.Context, sessionID, path, content string) (File, error)
+	CreateNew(ctx context.Context, sessionID, path string) (File, error)
 	CreateVersion(ctx context.Context, sessionID, path, content string) (File, error)
 	Get(ctx context.Context, id string) (File, error)
 	GetByPathAndSession(ctx context.Context, path, sessionID string) (File, error)
@@ -38,6 +45,8 @@ type Service interface {
 	Update(ctx context.Context, file File) (File, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
+	PlanRewind(ctx context.Context, sessionID string, before int64) ([]Restore, error)
+	Rewind(ctx context.Context, sessionID string, restores []Restore) error
 }
 
 type service struct {
@@ -55,7 +64,13 @@ func NewService(q *db.Queries, db *sql.DB) Service {
 }
 
 func (s *service) Create(ctx context.Context, sessionID, path, content string) (File, error) {
-	return s.createWithVersion(ctx, sessionID, path, content, InitialVersion)
+	return s.createWithVersion(ctx, sessionID, path, content, InitialVersion, false)
+}
+
+// CreateNew records that the session is creating a file that did not exist,
+// so rewinding past it deletes the file instead of emptying it.
+func (s *service) CreateNew(ctx context.Context, sessionID, path string) (File, error) {
+	return s.createWithVersion(ctx, sessionID, path, "", InitialVersion, true)
 }
 
 func (s *service) CreateVersion(ctx context.Context, sessionID, path, content string) (File, error) {
@@ -71,7 +86,7 @@ func (s *service) CreateVersion(ctx context.Context, sessionID, path, content st
 	}
 
 	// Get the latest version
-	latestFile := files[0] // Files are ordered by created_at DESC
+	latestFile := files[0] // Files are ordered newest first
 	latestVersion := latestFile.Version
 
 	// Generate the next version
@@ -91,10 +106,10 @@ func (s *service) CreateVersion(ctx context.Context, sessionID, path, content st
 		nextVersion = fmt.Sprintf("v%d", latestFile.CreatedAt)
 	}
 
-	return s.createWithVersion(ctx, sessionID, path, content, nextVersion)
+	return s.createWithVersion(ctx, sessionID, path, content, nextVersion, false)
 }
 
-func (s *service) createWithVersion(ctx context.Context, sessionID, path, content, version string) (File, error) {
+func (s *service) createWithVersion(ctx context.Context, sessionID, path, content, version string, isNew bool) (File, error) {
 	// Maximum number of retries for transaction conflicts
 	const maxRetries = 3
 	var file File
@@ -118,6 +133,7 @@ func (s *service) createWithVersion(ctx context.Context, sessionID, path, conten
 			Path:      path,
 			Content:   content,
 			Version:   version,
+			IsNew:     isNew,
 		})
 		if txErr != nil {
 			// Rollback the transaction
@@ -246,7 +262,9 @@ func (s *service) fromDBItem(item db.File) File {
 		Path:      item.Path,
 		Content:   item.Content,
 		Version:   item.Version,
+		IsNew:     item.IsNew,
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
+		Seq:       item.Seq,
 	}
 }
@@ -0,0 +1,106 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Restore describes how a single file is rolled back by a rewind.
+type Restore struct {
+	Path string
+	// Current is the content currently on disk, empty if the file is missing.
+	Current string
+	// Content is the content the file is restored to.
+	Content string
+	// Delete is set for files the session created after the rewind point.
+	Delete bool
+}
+
+// PlanRewind works out how to restore every file changed in the session at or
+// after before (the Seq of a message) to the latest version recorded before
+// that message, without touching the disk. Files the session created after it
+// are planned for deletion. Files already in their target state are left out.
+func (s *service) PlanRewind(ctx context.Context, sessionID string, before int64) ([]Restore, error) {
+	files, err := s.ListBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions are ordered by sequence number, so the last one seen before the
+	// rewind point is the content to restore and the first one overall holds
+	// the content from before the session touched the file.
+	type target struct {
+		content string
+		found   bool
+		changed bool
+		created bool
+	}
+	var paths []string
+	targets := make(map[string]*target)
+	for _, file := range files {
+		t, ok := targets[file.Path]
+		if !ok {
+			t = &target{
+				content: file.Content,
+				created: file.IsNew,
+			}
+			targets[file.Path] = t
+			paths = append(paths, file.Path)
+		}
+		if file.Seq < before {
+			t.content = file.Content
+			t.found = true
+			t.created = false
+		} else {
+			t.changed = true
+		}
+	}
+
+	var restores []Restore
+	for _, path := range paths {
+		t := targets[path]
+		if !t.changed {
+			continue
+		}
+		current, err := os.ReadFile(path)
+		exists := err == nil
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		restore := Restore{
+			Path:    path,
+			Current: string(current),
+			Content: t.content,
+			Delete:  t.created && !t.found,
+		}
+		if restore.Delete && !exists {
+			continue
+		}
+		if !restore.Delete && exists && restore.Current == restore.Content {
+			continue
+		}
+		restores = append(restores, restore)
+	}
+	return restores, nil
+}
+
+// Rewind applies restores produced by PlanRewind and records each restored
+// content as a new version so the session history stays consistent.
+func (s *service) Rewind(ctx context.Context, sessionID string, restores []Restore) error {
+	for _, restore := range restores {
+		if restore.Delete {
+			if err := os.Remove(restore.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to remove %s: %w", restore.Path, err)
+			}
+		} else if err := os.WriteFile(restore.Path, []byte(restore.Content), 0o644); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", restore.Path, err)
+		}
+		if _, err := s.CreateVersion(ctx, sessionID, restore.Path, restore.Content); err != nil {
+			return fmt.Errorf("failed to record restored version of %s: %w", restore.Path, err)
+		}
+	}
+	return nil
+}
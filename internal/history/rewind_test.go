@@ -0,0 +1,84 @@
+package history
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRewind(t *testing.T) {
+	ctx := context.Background()
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Data.Directory = t.TempDir()
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	defer conn.Close()
+	q := db.New(conn)
+	sess, err := session.NewService(q, conn).Create(ctx, "session")
+	require.NoError(t, err)
+	files := NewService(q, conn)
+
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept.txt")
+	empty := filepath.Join(dir, "empty.txt")
+	created := filepath.Join(dir, "created.txt")
+
+	// An edit made before the message is kept
+	_, err = files.Create(ctx, sess.ID, kept, "old")
+	require.NoError(t, err)
+	_, err = files.CreateVersion(ctx, sess.ID, kept, "kept")
+	require.NoError(t, err)
+	msg, err := message.NewService(q).Create(ctx, sess.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "rewind to here"}},
+	})
+	require.NoError(t, err)
+	_, err = files.CreateVersion(ctx, sess.ID, kept, "later")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(kept, []byte("later"), 0o644))
+
+	// An existing empty file the agent edited is restored, not deleted
+	_, err = files.Create(ctx, sess.ID, empty, "")
+	require.NoError(t, err)
+	_, err = files.CreateVersion(ctx, sess.ID, empty, "edited")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(empty, []byte("edited"), 0o644))
+
+	_, err = files.CreateNew(ctx, sess.ID, created)
+	require.NoError(t, err)
+	_, err = files.CreateVersion(ctx, sess.ID, created, "new")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(created, []byte("new"), 0o644))
+
+	// Everything happened within the same second
+	_, err = conn.Exec("UPDATE messages SET created_at = 1")
+	require.NoError(t, err)
+	_, err = conn.Exec("UPDATE files SET created_at = 1")
+	require.NoError(t, err)
+
+	restores, err := files.PlanRewind(ctx, sess.ID, msg.Seq)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Restore{
+		{Path: kept, Current: "later", Content: "kept"},
+		{Path: empty, Current: "edited", Content: ""},
+		{Path: created, Current: "new", Content: "", Delete: true},
+	}, restores)
+
+	require.NoError(t, files.Rewind(ctx, sess.ID, restores))
+	content, err := os.ReadFile(kept)
+	require.NoError(t, err)
+	assert.Equal(t, "kept", string(content))
+	content, err = os.ReadFile(empty)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+	assert.NoFileExists(t, created)
+}
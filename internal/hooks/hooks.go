@@ -0,0 +1,115 @@
+// Package hooks runs the user-configured hooks around the agent.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+)
+
+// Message is a message of the conversation as seen by hooks.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TurnInput is what post-turn hooks receive, as JSON on stdin for commands
+// and as arguments for MCP tools.
+type TurnInput struct {
+	SessionID string    `json:"session_id"`
+	Agent     string    `json:"agent"`
+	Model     string    `json:"model"`
+	Prompt    string    `json:"prompt"`
+	Response  string    `json:"response"`
+	Messages  []Message `json:"messages"`
+}
+
+// TurnOutput is what post-turn hooks answer with. Output that is not JSON is
+// taken as a single suggestion.
+type TurnOutput struct {
+	Suggestions []string `json:"suggestions"`
+}
+
+// Suggestion is a follow-up prompt proposed by a post-turn hook.
+type Suggestion struct {
+	// Hook describes the hook that made the suggestion.
+	Hook string
+	Text string
+}
+
+// Name describes a hook for the user.
+func Name(hook config.TurnHook) string {
+	if hook.MCPServer != "" {
+		return hook.MCPServer + "/" + hook.Tool
+	}
+	return hook.Command
+}
+
+// RunPostTurn runs a post-turn hook and returns its suggestions.
+func RunPostTurn(ctx context.Context, hook config.TurnHook, input TurnInput) ([]Suggestion, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(hook.Timeout)*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var output string
+	if hook.MCPServer != "" {
+		var args map[string]any
+		if err := json.Unmarshal(data, &args); err != nil {
+			return nil, err
+		}
+		output, err = callTool(ctx, hook.MCPServer, hook.Tool, args)
+	} else {
+		output, err = runCommand(ctx, config.WorkingDirectory(), hook.Command, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hook %s: %w", Name(hook), err)
+	}
+
+	var texts []string
+	var parsed TurnOutput
+	if err := json.Unmarshal([]byte(output), &parsed); err == nil {
+		texts = parsed.Suggestions
+	} else if text := strings.TrimSpace(output); text != "" {
+		texts = []string{text}
+	}
+	var suggestions []Suggestion
+	for _, text := range texts {
+		if text = strings.TrimSpace(text); text != "" {
+			suggestions = append(suggestions, Suggestion{Hook: Name(hook), Text: text})
+		}
+	}
+	return suggestions, nil
+}
+
+// runCommand runs command with sh in dir, passing input on stdin, and returns
+// its stdout. Errors include what the command wrote to stderr.
+func runCommand(ctx context.Context, dir, command string, input []byte) (string, error) {
+	stdout, stderr, err := execCommand(ctx, dir, command, input)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout, nil
+}
+
+func execCommand(ctx context.Context, dir, command string, input []byte) (string, string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
@@ -0,0 +1,120 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPostTurn_Command(t *testing.T) {
+	input := TurnInput{SessionID: "s1", Prompt: "fix the bug", Response: "done"}
+
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "json suggestions",
+			command: `echo '{"suggestions": ["add a test", " ", "update the docs"]}'`,
+			want:    []string{"add a test", "update the docs"},
+		},
+		{
+			name:    "reads the turn from stdin",
+			command: `grep -q '"prompt":"fix the bug"' && echo 'run the tests'`,
+			want:    []string{"run the tests"},
+		},
+		{
+			name:    "no output",
+			command: `cat > /dev/null`,
+		},
+		{
+			name:    "failing command",
+			command: `echo broken >&2; exit 1`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := config.TurnHook{Command: tt.command, Timeout: 5}
+			suggestions, err := RunPostTurn(context.Background(), hook, input)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "broken")
+				return
+			}
+			require.NoError(t, err)
+			var texts []string
+			for _, suggestion := range suggestions {
+				assert.Equal(t, tt.command, suggestion.Hook)
+				texts = append(texts, suggestion.Text)
+			}
+			assert.Equal(t, tt.want, texts)
+		})
+	}
+}
+
+func TestPreToolUse(t *testing.T) {
+	call := ToolCall{ID: "c1", Name: "write", Input: json.RawMessage(`{"file_path":"migrations/001.sql","content":"x"}`)}
+
+	t.Run("blocks with a non-zero exit", func(t *testing.T) {
+		toolHooks := []config.ToolHook{{Command: `echo "no migrations" >&2; exit 2`, Tools: []string{"write", "edit"}, Paths: []string{"migrations/**"}, Timeout: 5}}
+		_, blocked := PreToolUse(context.Background(), toolHooks, t.TempDir(), "s1", call)
+		assert.Contains(t, blocked, "no migrations")
+	})
+
+	t.Run("blocks with a decision", func(t *testing.T) {
+		toolHooks := []config.ToolHook{{Command: `echo '{"decision":"block","reason":"read only"}'`, Timeout: 5}}
+		_, blocked := PreToolUse(context.Background(), toolHooks, t.TempDir(), "s1", call)
+		assert.Contains(t, blocked, "read only")
+	})
+
+	t.Run("skips hooks that do not match", func(t *testing.T) {
+		toolHooks := []config.ToolHook{
+			{Command: `exit 1`, Tools: []string{"bash"}, Timeout: 5},
+			{Command: `exit 1`, Paths: []string{"internal/**"}, Timeout: 5},
+		}
+		input, blocked := PreToolUse(context.Background(), toolHooks, t.TempDir(), "s1", call)
+		assert.Empty(t, blocked)
+		assert.JSONEq(t, string(call.Input), string(input))
+	})
+
+	t.Run("modifies the input", func(t *testing.T) {
+		toolHooks := []config.ToolHook{
+			{Command: `echo '{"input":{"file_path":"other.sql","content":"y"}}'`, Timeout: 5},
+			{Command: `grep -q '"paths":\["other.sql"\]'`, Timeout: 5},
+		}
+		input, blocked := PreToolUse(context.Background(), toolHooks, t.TempDir(), "s1", call)
+		assert.Empty(t, blocked)
+		assert.JSONEq(t, `{"file_path":"other.sql","content":"y"}`, string(input))
+	})
+}
+
+func TestPostToolUse(t *testing.T) {
+	call := ToolCall{ID: "c1", Name: "bash", Input: json.RawMessage(`{"command":"ls"}`)}
+	toolHooks := []config.ToolHook{
+		{Command: `grep -q '"content":"a.go"' && echo formatted`, Tools: []string{"bash"}, Timeout: 5},
+		{Command: `cat > /dev/null`, Timeout: 5},
+		{Command: `echo oops >&2; exit 1`, Tools: []string{"ba*"}, Timeout: 5},
+		{Command: `echo never`, Tools: []string{"edit"}, Timeout: 5},
+	}
+	feedback := PostToolUse(context.Background(), toolHooks, t.TempDir(), "s1", call, ToolResult{Content: "a.go"})
+	assert.Contains(t, feedback, "formatted")
+	assert.Contains(t, feedback, "oops")
+	assert.NotContains(t, feedback, "never")
+}
+
+func TestToolPaths(t *testing.T) {
+	wd := filepath.Join(string(filepath.Separator), "project")
+	input := json.RawMessage(`{"file_path":"` + filepath.ToSlash(filepath.Join(wd, "a", "b.go")) + `","path":"/elsewhere/c.go"}`)
+	assert.Equal(t, []string{"a/b.go", "/elsewhere/c.go"}, ToolPaths(wd, input))
+
+	patch := json.RawMessage(`{"patch_text":"*** Begin Patch\n*** Add File: new.go\n+package x\n*** End Patch"}`)
+	assert.Equal(t, []string{"new.go"}, ToolPaths(wd, patch))
+}
@@ -0,0 +1,119 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/version"
+)
+
+// MCP servers used by hooks are started once and kept running, unlike the
+// servers behind MCP tools which are started for every call.
+var (
+	poolMu sync.Mutex
+	pool   = make(map[string]client.MCPClient)
+)
+
+// mcpClient returns the running client of the named server, starting and
+// initializing it when needed.
+func mcpClient(ctx context.Context, name string) (client.MCPClient, error) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	if c, ok := pool[name]; ok {
+		return c, nil
+	}
+
+	server, ok := config.Get().MCPServers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown MCP server %s", name)
+	}
+	var c client.MCPClient
+	switch server.Type {
+	case config.MCPSse:
+		sse, err := client.NewSSEMCPClient(server.URL, client.WithHeaders(server.Headers))
+		if err != nil {
+			return nil, err
+		}
+		// The connection outlives the request that opened it
+		if err := sse.Start(context.Background()); err != nil {
+			return nil, err
+		}
+		c = sse
+	default:
+		stdio, err := client.NewStdioMCPClient(server.Command, server.Env, server.Args...)
+		if err != nil {
+			return nil, err
+		}
+		c = stdio
+	}
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "OpenCode",
+		Version: version.Version,
+	}
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		c.Close()
+		return nil, err
+	}
+	pool[name] = c
+	return c, nil
+}
+
+// drop closes the client of the named server so the next call restarts it.
+func drop(name string, c client.MCPClient) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	if pool[name] == c {
+		delete(pool, name)
+		c.Close()
+	}
+}
+
+// callTool calls a tool of a pooled MCP server and returns its text output.
+func callTool(ctx context.Context, server, tool string, args map[string]any) (string, error) {
+	c, err := mcpClient(ctx, server)
+	if err != nil {
+		return "", err
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool
+	request.Params.Arguments = args
+	result, err := c.CallTool(ctx, request)
+	if err != nil {
+		drop(server, c)
+		return "", err
+	}
+
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	output := strings.Join(texts, "\n")
+	if result.IsError {
+		return "", errors.New(output)
+	}
+	return output, nil
+}
+
+// Close stops the MCP servers started for hooks.
+func Close() {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	for name, c := range pool {
+		if err := c.Close(); err != nil {
+			logging.Warn("failed to close hook MCP server", "server", name, "error", err)
+		}
+		delete(pool, name)
+	}
+}
@@ -0,0 +1,167 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/diff"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// ToolCall is a tool call as seen by tool hooks.
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// ToolResult is the result of a tool call as seen by post-tool-use hooks.
+type ToolResult struct {
+	Content string `json:"content"`
+	IsError bool   `json:"is_error"`
+}
+
+// ToolUseInput is what tool hooks receive as JSON on stdin.
+type ToolUseInput struct {
+	SessionID string   `json:"session_id"`
+	ToolCall  ToolCall `json:"tool_call"`
+	// Paths are the files the call works on, relative to the working
+	// directory when they are inside it.
+	Paths  []string    `json:"paths,omitempty"`
+	Result *ToolResult `json:"result,omitempty"`
+}
+
+// PreToolUseOutput is what pre-tool-use hooks may answer with. A hook blocks
+// the call by exiting with a non-zero status or by setting Decision to
+// "block", and changes it by returning a new Input.
+type PreToolUseOutput struct {
+	Decision string          `json:"decision,omitempty"`
+	Reason   string          `json:"reason,omitempty"`
+	Input    json.RawMessage `json:"input,omitempty"`
+}
+
+const decisionBlock = "block"
+
+// PreToolUse runs the pre-tool-use hooks matching the call in order, each one
+// seeing the input left by the previous one. It returns the input to run the
+// call with, or the reason a hook blocked the call.
+func PreToolUse(ctx context.Context, toolHooks []config.ToolHook, workingDir, sessionID string, call ToolCall) (json.RawMessage, string) {
+	if len(toolHooks) == 0 {
+		return call.Input, ""
+	}
+	paths := ToolPaths(workingDir, call.Input)
+	for _, hook := range toolHooks {
+		if !hook.Matches(call.Name, paths) {
+			continue
+		}
+		data, err := json.Marshal(ToolUseInput{SessionID: sessionID, ToolCall: call, Paths: paths})
+		if err != nil {
+			return nil, err.Error()
+		}
+		stdout, stderr, err := runToolHook(ctx, workingDir, hook, data)
+		if err != nil {
+			reason := strings.TrimSpace(stderr)
+			if reason == "" {
+				reason = strings.TrimSpace(stdout)
+			}
+			if reason == "" {
+				reason = err.Error()
+			}
+			return nil, fmt.Sprintf("blocked by hook %s: %s", hook.Command, reason)
+		}
+		if strings.TrimSpace(stdout) == "" {
+			continue
+		}
+		var output PreToolUseOutput
+		if err := json.Unmarshal([]byte(stdout), &output); err != nil {
+			logging.Warn("ignoring pre-tool-use hook output that is not JSON", "hook", hook.Command, "error", err)
+			continue
+		}
+		if output.Decision == decisionBlock {
+			reason := output.Reason
+			if reason == "" {
+				reason = "no reason given"
+			}
+			return nil, fmt.Sprintf("blocked by hook %s: %s", hook.Command, reason)
+		}
+		if len(output.Input) > 0 {
+			call.Input = output.Input
+			paths = ToolPaths(workingDir, call.Input)
+		}
+	}
+	return call.Input, ""
+}
+
+// PostToolUse runs the post-tool-use hooks matching the call and returns what
+// they printed, to be appended to the result the model sees. Failing hooks
+// report their error output instead.
+func PostToolUse(ctx context.Context, toolHooks []config.ToolHook, workingDir, sessionID string, call ToolCall, result ToolResult) string {
+	if len(toolHooks) == 0 {
+		return ""
+	}
+	paths := ToolPaths(workingDir, call.Input)
+	var feedback []string
+	for _, hook := range toolHooks {
+		if !hook.Matches(call.Name, paths) {
+			continue
+		}
+		data, err := json.Marshal(ToolUseInput{SessionID: sessionID, ToolCall: call, Paths: paths, Result: &result})
+		if err != nil {
+			continue
+		}
+		stdout, stderr, err := runToolHook(ctx, workingDir, hook, data)
+		output := strings.TrimSpace(stdout)
+		if err != nil {
+			logging.Warn("post-tool-use hook failed", "hook", hook.Command, "error", err)
+			output = strings.TrimSpace(output + "\n" + stderr + "\n" + err.Error())
+		}
+		if output != "" {
+			feedback = append(feedback, fmt.Sprintf("Hook %s:\n%s", hook.Command, output))
+		}
+	}
+	return strings.Join(feedback, "\n\n")
+}
+
+func runToolHook(ctx context.Context, workingDir string, hook config.ToolHook, input []byte) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(hook.Timeout)*time.Second)
+	defer cancel()
+	return execCommand(ctx, workingDir, hook.Command, input)
+}
+
+// ToolPaths returns the files a tool call works on, taken from the file_path
+// and path parameters and from the file headers of patches. Paths inside
+// workingDir are made relative to it.
+func ToolPaths(workingDir string, input json.RawMessage) []string {
+	var params struct {
+		FilePath  string `json:"file_path"`
+		Path      string `json:"path"`
+		PatchText string `json:"patch_text"`
+	}
+	if err := json.Unmarshal(input, &params); err != nil {
+		return nil
+	}
+	var paths []string
+	for _, p := range []string{params.FilePath, params.Path} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if params.PatchText != "" {
+		paths = append(paths, diff.IdentifyFilesNeeded(params.PatchText)...)
+		paths = append(paths, diff.IdentifyFilesAdded(params.PatchText)...)
+	}
+	for i, p := range paths {
+		if filepath.IsAbs(p) {
+			if rel, err := filepath.Rel(workingDir, p); err == nil && !strings.HasPrefix(rel, "..") {
+				p = rel
+			}
+		}
+		paths[i] = filepath.ToSlash(filepath.Clean(p))
+	}
+	return paths
+}
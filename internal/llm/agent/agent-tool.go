@@ -4,18 +4,56 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/history"
 	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/llm/tools/shell"
+	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/lsp"
 	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/permission"
 	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/opencode-ai/opencode/internal/worktree"
 )
 
+// agentWorktree is the git worktree a sub-agent works in, with the shell its
+// commands run in.
+type agentWorktree struct {
+	*worktree.Worktree
+	shell *shell.PersistentShell
+}
+
+// savedWorktree is what is kept of the worktree of a sub-session between
+// runs: the commit it was checked out from and the patch of its changes,
+// empty when it made none.
+type savedWorktree struct {
+	base  string
+	patch string
+}
+
 type agentTool struct {
-	sessions   session.Service
-	messages   message.Service
-	lspClients map[string]*lsp.Client
+	// costMu serializes updates of the parent session cost by concurrent
+	// sub-agents.
+	costMu sync.Mutex
+	// writeMu keeps sub-agents that can modify the workspace from running at
+	// the same time.
+	writeMu sync.Mutex
+	// runningMu guards running, the sub-sessions an agent is working in, and
+	// worktrees, the saved worktrees of sub-sessions by session ID.
+	runningMu   sync.Mutex
+	running     map[string]bool
+	worktrees   map[string]savedWorktree
+	permissions permission.Service
+	sessions    session.Service
+	messages    message.Service
+	history     history.Service
+	lspClients  map[string]*lsp.Client
 }
 
 const (
@@ -23,23 +61,63 @@ const (
 )
 
 type AgentParams struct {
-	Prompt string `json:"prompt"`
+	Prompt    string `json:"prompt"`
+	Subagent  string `json:"subagent"`
+	SessionID string `json:"session_id"`
+	Worktree  bool   `json:"worktree"`
 }
 
+type AgentResponseMetadata struct {
+	SessionID string `json:"session_id"`
+	Patch     string `json:"patch,omitempty"`
+}
+
+// agentTitleLength caps the length of the prompt used as sub-session title.
+const agentTitleLength = 60
+
 func (b *agentTool) Info() tools.ToolInfo {
 	return tools.ToolInfo{
 		Name:        AgentToolName,
-		Description: "Launch a new agent that has access to the following tools: GlobTool, GrepTool, LS, View. When you are searching for a keyword or file and are not confident that you will find the right match on the first try, use the Agent tool to perform the search for you. For example:\n\n- If you are searching for a keyword like \"config\" or \"logger\", or for questions like \"which file does X?\", the Agent tool is strongly recommended\n- If you want to read a specific file path, use the View or GlobTool tool instead of the Agent tool, to find the match more quickly\n- If you are searching for a specific class definition like \"class Foo\", use the GlobTool tool instead, to find the match more quickly\n\nUsage notes:\n1. Launch multiple agents concurrently whenever possible, to maximize performance; to do that, use a single message with multiple tool uses\n2. When the agent is done, it will return a single message back to you. The result returned by the agent is not visible to the user. To show the user the result, you should send a text message back to the user with a concise summary of the result.\n3. Each agent invocation is stateless. You will not be able to send additional messages to the agent, nor will the agent be able to communicate with you outside of its final report. Therefore, your prompt should contain a highly detailed task description for the agent to perform autonomously and you should specify exactly what information the agent should return back to you in its final and only message to you.\n4. The agent's outputs should generally be trusted\n5. IMPORTANT: The agent can not use Bash, Replace, Edit, so can not modify files. If you want to use these tools, use them directly instead of going through the agent.",
+		Description: "Launch a new agent. Unless another agent is selected with the subagent parameter, it has access to the following tools: GlobTool, GrepTool, LS, View. When you are searching for a keyword or file and are not confident that you will find the right match on the first try, use the Agent tool to perform the search for you. For example:\n\n- If you are searching for a keyword like \"config\" or \"logger\", or for questions like \"which file does X?\", the Agent tool is strongly recommended\n- If you want to read a specific file path, use the View or GlobTool tool instead of the Agent tool, to find the match more quickly\n- If you are searching for a specific class definition like \"class Foo\", use the GlobTool tool instead, to find the match more quickly\n\nUsage notes:\n1. Launch multiple agents concurrently whenever possible, to maximize performance; to do that, use a single message with multiple tool uses\n2. When the agent is done, it will return a single message back to you. The result returned by the agent is not visible to the user. To show the user the result, you should send a text message back to the user with a concise summary of the result.\n3. The agent can not communicate with you outside of its final report, so your prompt should contain a highly detailed task description for the agent to perform autonomously and you should specify exactly what information the agent should return back to you. The result ends with the agent's session ID. To send follow-up instructions to the same agent, for example to dig deeper into files it has already read, pass that ID as session_id instead of launching a new agent; it keeps its previous conversation in context.\n4. The agent's outputs should generally be trusted\n5. IMPORTANT: The default agent can not use Bash, Replace, Edit, so can not modify files. If you want to use these tools, use them directly instead of going through the agent.\n6. Set worktree to true to let the agent modify files and run commands in a temporary git worktree holding a copy of the current working tree. Its changes do not affect the working tree; they are returned as a diff along with the path of a patch file that can be applied with git apply once reviewed. Use this to try out changes in isolation. Continuing the session checks out a new worktree holding the changes it made before." + customAgentsDescription(),
 		Parameters: map[string]any{
 			"prompt": map[string]any{
 				"type":        "string",
 				"description": "The task for the agent to perform",
 			},
+			"subagent": map[string]any{
+				"type":        "string",
+				"description": "The name of the agent to run, defaults to the read-only task agent",
+			},
+			"session_id": map[string]any{
+				"type":        "string",
+				"description": "The session ID returned by a previous agent call, to continue that agent instead of starting a new one",
+			},
+			"worktree": map[string]any{
+				"type":        "boolean",
+				"description": "Run the agent in a temporary git worktree where it can modify files and run commands, and return its changes as a diff",
+			},
 		},
 		Required: []string{"prompt"},
 	}
 }
 
+// Concurrent lets several read-only sub-agents of one message run in
+// parallel. Sub-agents with tools that modify files or ask for permissions,
+// in the working tree or in a worktree, run one after the other.
+func (b *agentTool) Concurrent(call tools.ToolCall) bool {
+	var params AgentParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return true
+	}
+	agentName, ok := subAgentName(params)
+	if !ok {
+		return true
+	}
+	_, saved := b.savedWorktree(params.SessionID)
+	inWorktree := params.Worktree || saved
+	return readOnly(b.agentTools(agentName, inWorktree))
+}
+
 func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
 	var params AgentParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
@@ -54,17 +132,67 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 		return tools.ToolResponse{}, fmt.Errorf("session_id and message_id are required")
 	}
 
-	agent, err := NewAgent(config.AgentTask, b.sessions, b.messages, TaskAgentTools(b.lspClients))
+	var session session.Session
+	if params.SessionID != "" {
+		existing, err := b.sessions.Get(ctx, params.SessionID)
+		if err != nil || existing.ParentSessionID != sessionID {
+			return tools.NewTextErrorResponse(fmt.Sprintf("unknown agent session: %s", params.SessionID)), nil
+		}
+		session = existing
+		if !b.claim(session.ID) {
+			return tools.NewTextErrorResponse(fmt.Sprintf("agent session %s is already running", session.ID)), nil
+		}
+		defer b.release(session.ID)
+	}
+
+	agentName, ok := subAgentName(params)
+	if !ok {
+		return tools.NewTextErrorResponse(fmt.Sprintf("unknown agent: %s", params.Subagent)), nil
+	}
+	subSessionID := session.ID
+	if subSessionID == "" {
+		subSessionID = call.ID
+	}
+	// Continued sessions get their worktree back with the changes they made
+	saved, reopen := b.savedWorktree(subSessionID)
+	var wt *agentWorktree
+	if reopen || params.Worktree {
+		var err error
+		if wt, err = b.openWorktree(ctx, saved, reopen); err != nil {
+			return tools.NewTextErrorResponse(fmt.Sprintf("error creating worktree: %s", err)), nil
+		}
+		// The worktree is checked out again from the saved patch when the
+		// session is continued
+		defer removeWorktree(wt)
+	}
+
+	prompt := params.Prompt
+	agentTools := b.agentTools(agentName, wt != nil)
+	if wt != nil {
+		ctx = tools.WithWorkspace(ctx, wt.Dir, wt.shell)
+		prompt += fmt.Sprintf("\n\nYou are working in a temporary git worktree at %s, make all changes there. They are returned to the caller as a diff when you finish.", wt.Dir)
+		if reopen {
+			prompt += " It holds the changes you made before, checked out again in a new directory."
+		}
+	} else if !readOnly(agentTools) {
+		// Agents that can modify the workspace run one at a time
+		b.writeMu.Lock()
+		defer b.writeMu.Unlock()
+	}
+	agent, err := NewAgent(agentName, b.sessions, b.messages, agentTools)
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error creating agent: %s", err)
 	}
 
-	session, err := b.sessions.CreateTaskSession(ctx, call.ID, sessionID, "New Agent Session")
-	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
+	if session.ID == "" {
+		session, err = b.sessions.CreateTaskSession(ctx, call.ID, sessionID, agentSessionTitle(agentName, params.Prompt))
+		if err != nil {
+			return tools.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
+		}
 	}
+	costBefore := session.Cost
 
-	done, err := agent.Run(ctx, session.ID, params.Prompt)
+	done, err := agent.Run(ctx, session.ID, prompt)
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error generating agent: %s", err)
 	}
@@ -82,28 +210,197 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error getting session: %s", err)
 	}
+	b.costMu.Lock()
+	defer b.costMu.Unlock()
 	parentSession, err := b.sessions.Get(ctx, sessionID)
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error getting parent session: %s", err)
 	}
 
-	parentSession.Cost += updatedSession.Cost
+	parentSession.Cost += updatedSession.Cost - costBefore
 
 	_, err = b.sessions.Save(ctx, parentSession)
 	if err != nil {
 		return tools.ToolResponse{}, fmt.Errorf("error saving parent session: %s", err)
 	}
-	return tools.NewTextResponse(response.Content().String()), nil
+
+	text := response.Content().String()
+	metadata := AgentResponseMetadata{SessionID: session.ID}
+	if wt != nil {
+		changes, patchPath, err := b.worktreeChanges(ctx, session.ID, wt)
+		if err != nil {
+			changes = fmt.Sprintf("Failed to collect the changes made in the worktree: %s", err)
+		} else {
+			b.saveWorktree(session.ID, savedWorktree{base: wt.Base(), patch: patchPath})
+		}
+		text += "\n\n" + changes
+		metadata.Patch = patchPath
+	}
+	return tools.WithResponseMetadata(
+		tools.NewTextResponse(fmt.Sprintf("%s\n\n<agent_session_id>%s</agent_session_id>", text, session.ID)),
+		metadata,
+	), nil
+}
+
+// subAgentName returns the agent a call launches, false when it is unknown.
+func subAgentName(params AgentParams) (config.AgentName, bool) {
+	if params.Subagent == "" || params.Subagent == string(config.AgentTask) {
+		return config.AgentTask, true
+	}
+	agentName := config.AgentName(params.Subagent)
+	if _, ok := config.Get().Agents[agentName]; !ok || !config.IsCustomAgent(agentName) {
+		return agentName, false
+	}
+	return agentName, true
+}
+
+// agentTools returns the tools of a sub-agent working in the working tree or
+// in a worktree.
+func (b *agentTool) agentTools(agentName config.AgentName, inWorktree bool) []tools.BaseTool {
+	if inWorktree {
+		return WorktreeAgentTools(agentName, b.permissions, b.sessions, b.messages, b.history)
+	}
+	return SubAgentTools(agentName, b.permissions, b.sessions, b.messages, b.history, b.lspClients)
+}
+
+// readOnly reports whether none of the tools modifies files or asks for
+// permissions. Sub-agent tools never include the agent tool, so they do not
+// depend on the call.
+func readOnly(agentTools []tools.BaseTool) bool {
+	return !slices.ContainsFunc(agentTools, func(t tools.BaseTool) bool {
+		return !tools.IsConcurrent(t, tools.ToolCall{})
+	})
+}
+
+// savedWorktree returns the worktree saved by the last run of a sub-session,
+// false when it did not work in one.
+func (b *agentTool) savedWorktree(sessionID string) (savedWorktree, bool) {
+	b.runningMu.Lock()
+	defer b.runningMu.Unlock()
+	saved, ok := b.worktrees[sessionID]
+	return saved, ok
+}
+
+func (b *agentTool) saveWorktree(sessionID string, saved savedWorktree) {
+	b.runningMu.Lock()
+	defer b.runningMu.Unlock()
+	b.worktrees[sessionID] = saved
+}
+
+// openWorktree checks out the working tree in a new git worktree, or the
+// saved worktree of a continued sub-session when reopen is set, with a shell
+// of its own.
+func (b *agentTool) openWorktree(ctx context.Context, saved savedWorktree, reopen bool) (*agentWorktree, error) {
+	var w *worktree.Worktree
+	var err error
+	if reopen {
+		w, err = worktree.Reopen(ctx, config.WorkingDirectory(), saved.base, saved.patch)
+	} else {
+		w, err = worktree.Create(ctx, config.WorkingDirectory())
+	}
+	if err != nil {
+		return nil, err
+	}
+	sh := shell.NewPersistentShell(w.Dir)
+	if sh == nil {
+		w.Remove()
+		return nil, fmt.Errorf("failed to start a shell in %s", w.Dir)
+	}
+	w.OnRemove(sh.Close)
+	return &agentWorktree{Worktree: w, shell: sh}, nil
+}
+
+func removeWorktree(wt *agentWorktree) {
+	if err := wt.Remove(); err != nil {
+		logging.Warn("failed to remove worktree", "dir", wt.Dir, "error", err)
+	}
+}
+
+// worktreeChanges describes the changes made in the worktree of a sub-session
+// and saves them as a patch in the data directory. It returns the description
+// and the path of the patch, empty when nothing changed.
+func (b *agentTool) worktreeChanges(ctx context.Context, sessionID string, wt *agentWorktree) (string, string, error) {
+	patch, err := wt.Diff(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if patch == "" {
+		return "The agent made no changes in its worktree.", "", nil
+	}
+	patchPath, err := filepath.Abs(filepath.Join(config.Get().Data.Directory, "worktrees", sessionID+".patch"))
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(patchPath), 0o755); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(patchPath, []byte(patch), 0o644); err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf(
+		"Changes made in the worktree:\n\n```diff\n%s```\n\nThe patch is saved at %s. Once reviewed, apply it to the working tree with `git -C %s apply %s`.",
+		patch, patchPath, wt.Repo, patchPath,
+	), patchPath, nil
+}
+
+func (b *agentTool) claim(sessionID string) bool {
+	b.runningMu.Lock()
+	defer b.runningMu.Unlock()
+	if b.running[sessionID] {
+		return false
+	}
+	b.running[sessionID] = true
+	return true
+}
+
+func (b *agentTool) release(sessionID string) {
+	b.runningMu.Lock()
+	defer b.runningMu.Unlock()
+	delete(b.running, sessionID)
+}
+
+// agentSessionTitle names a sub-session after its agent and the start of the
+// prompt so it can be recognized in the session dialog.
+func agentSessionTitle(agentName config.AgentName, prompt string) string {
+	title := strings.Join(strings.Fields(prompt), " ")
+	if runes := []rune(title); len(runes) > agentTitleLength {
+		title = string(runes[:agentTitleLength-1]) + "…"
+	}
+	return fmt.Sprintf("%s: %s", agentName, title)
+}
+
+// customAgentsDescription lists the user-defined agents the model can pick
+// with the subagent parameter.
+func customAgentsDescription() string {
+	names := config.CustomAgents()
+	if len(names) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\nAvailable agents for the subagent parameter:")
+	for _, name := range names {
+		sb.WriteString("\n- " + string(name))
+		if description := config.Get().Agents[name].Description; description != "" {
+			sb.WriteString(": " + description)
+		}
+	}
+	return sb.String()
 }
 
 func NewAgentTool(
+	Permissions permission.Service,
 	Sessions session.Service,
 	Messages message.Service,
+	History history.Service,
 	LspClients map[string]*lsp.Client,
 ) tools.BaseTool {
 	return &agentTool{
-		sessions:   Sessions,
-		messages:   Messages,
-		lspClients: LspClients,
+		permissions: Permissions,
+		sessions:    Sessions,
+		messages:    Messages,
+		history:     History,
+		lspClients:  LspClients,
+		running:     make(map[string]bool),
+		worktrees:   make(map[string]savedWorktree),
 	}
 }
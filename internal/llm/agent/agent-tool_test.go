@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentToolConcurrent(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	agentTool := NewAgentTool(nil, nil, nil, nil, nil).(tools.ConcurrentTool)
+
+	call := func(input string) tools.ToolCall {
+		return tools.ToolCall{ID: "call_1", Name: AgentToolName, Input: input}
+	}
+	// The task agent only reads
+	assert.True(t, agentTool.Concurrent(call(`{"prompt":"find the config"}`)))
+	// In a worktree it edits files and runs commands, which ask for permissions
+	assert.False(t, agentTool.Concurrent(call(`{"prompt":"fix the bug","worktree":true}`)))
+	// Calls that fail before starting an agent do not wait
+	assert.True(t, agentTool.Concurrent(call(`{"prompt":"x","subagent":"unknown"}`)))
+	assert.True(t, agentTool.Concurrent(call(`not json`)))
+}
@@ -5,19 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os/exec"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/hooks"
 	"github.com/opencode-ai/opencode/internal/llm/models"
 	"github.com/opencode-ai/opencode/internal/llm/prompt"
 	"github.com/opencode-ai/opencode/internal/llm/provider"
 	"github.com/opencode-ai/opencode/internal/llm/tools"
 	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/message"
-	"github.com/opencode-ai/opencode/internal/permission"
 	"github.com/opencode-ai/opencode/internal/pubsub"
 	"github.com/opencode-ai/opencode/internal/session"
 )
@@ -28,12 +28,21 @@ var (
 	ErrSessionBusy      = errors.New("session is currently processing another request")
 )
 
+// retryWarningTime keeps a provider warning in the status bar until the next
+// one arrives.
+const retryWarningTime = 1500 * time.Millisecond
+
 type AgentEventType string
 
 const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	AgentEventTypeLimit     AgentEventType = "limit"
+	AgentEventTypeQueue     AgentEventType = "queue"
+	AgentEventTypeSuggest   AgentEventType = "suggest"
+	AgentEventTypeCompact   AgentEventType = "compact"
+	AgentEventTypeUsage     AgentEventType = "usage"
 )
 
 type AgentEvent struct {
@@ -41,6 +50,18 @@ type AgentEvent struct {
 	Message message.Message
 	Error   error
 
+	// When a budget limit stopped the run
+	Limit *LimitExceeded
+
+	// When the prompt queue of SessionID changed
+	Queue []QueuedPrompt
+
+	// When post-turn hooks suggested follow-up prompts for SessionID
+	Suggestions []hooks.Suggestion
+
+	// When a response of SessionID was billed
+	Usage *provider.TokenUsage
+
 	// When summarizing
 	SessionID string
 	Progress  string
@@ -51,15 +72,23 @@ type Service interface {
 	pubsub.Suscriber[AgentEvent]
 	Model() models.Model
 	Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error)
+	Continue(ctx context.Context, sessionID string) (<-chan AgentEvent, error)
+	Queue(sessionID string) []QueuedPrompt
+	UpdateQueued(sessionID, id, content string, attachments ...message.Attachment) error
+	RemoveQueued(sessionID, id string) error
+	Steer(sessionID, note string) error
 	Cancel(sessionID string)
 	IsSessionBusy(sessionID string) bool
 	IsBusy() bool
 	Update(agentName config.AgentName, modelID models.ModelID) (models.Model, error)
+	AgentName() config.AgentName
+	SwitchAgent(agentName config.AgentName, agentTools []tools.BaseTool) (models.Model, error)
 	Summarize(ctx context.Context, sessionID string) error
 }
 
 type agent struct {
 	*pubsub.Broker[AgentEvent]
+	name     config.AgentName
 	sessions session.Service
 	messages message.Service
 
@@ -70,6 +99,10 @@ type agent struct {
 	summarizeProvider provider.Provider
 
 	activeRequests sync.Map
+
+	queueMu  sync.Mutex
+	queues   map[string][]*queuedRun
+	steering map[string][]string
 }
 
 func NewAgent(
@@ -90,16 +123,20 @@ func NewAgent(
 			return nil, err
 		}
 	}
-	var summarizeProvider provider.Provider
-	if agentName == config.AgentCoder {
-		summarizeProvider, err = createAgentProvider(config.AgentSummarizer)
-		if err != nil {
+	// Every agent summarizes to compact its context, only the coder agent
+	// requires a summarizer for manual summaries
+	summarizeProvider, err := createAgentProvider(config.AgentSummarizer)
+	if err != nil {
+		if agentName == config.AgentCoder {
 			return nil, err
 		}
+		logging.Warn("no summarizer available, context compaction will only prune", "agent", agentName, "error", err)
+		summarizeProvider = nil
 	}
 
 	agent := &agent{
 		Broker:            pubsub.NewBroker[AgentEvent](),
+		name:              agentName,
 		provider:          agentProvider,
 		messages:          messages,
 		sessions:          sessions,
@@ -107,6 +144,8 @@ func NewAgent(
 		titleProvider:     titleProvider,
 		summarizeProvider: summarizeProvider,
 		activeRequests:    sync.Map{},
+		queues:            make(map[string][]*queuedRun),
+		steering:          make(map[string][]string),
 	}
 
 	return agent, nil
@@ -201,12 +240,43 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 	if !a.provider.Model().SupportsAttachments && attachments != nil {
 		attachments = nil
 	}
-	events := make(chan AgentEvent)
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	if !a.IsSessionBusy(sessionID) && len(a.queues[sessionID]) == 0 {
+		return a.startLocked(ctx, sessionID, make(chan AgentEvent, 1), a.promptProcess(sessionID, content, attachments))
+	}
+	return a.enqueueLocked(ctx, sessionID, content, attachments), nil
+}
+
+func (a *agent) promptProcess(sessionID, content string, attachments []message.Attachment) func(ctx context.Context) AgentEvent {
+	var attachmentParts []message.ContentPart
+	for _, attachment := range attachments {
+		attachmentParts = append(attachmentParts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
+	}
+	return func(genCtx context.Context) AgentEvent {
+		return a.processGeneration(genCtx, sessionID, content, attachmentParts)
+	}
+}
+
+// Continue resumes the agent loop on the existing session history without
+// adding a new user message, e.g. after a budget limit was raised.
+func (a *agent) Continue(ctx context.Context, sessionID string) (<-chan AgentEvent, error) {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	return a.startLocked(ctx, sessionID, make(chan AgentEvent, 1), func(genCtx context.Context) AgentEvent {
+		return a.processContinuation(genCtx, sessionID)
+	})
+}
+
+// startLocked runs process in the background as the active request of the
+// session. When it completes normally the next queued prompt is dispatched.
+// The caller must hold queueMu.
+func (a *agent) startLocked(ctx context.Context, sessionID string, events chan AgentEvent, process func(ctx context.Context) AgentEvent) (<-chan AgentEvent, error) {
 	if a.IsSessionBusy(sessionID) {
 		return nil, ErrSessionBusy
 	}
 
-	genCtx, cancel := context.WithCancel(ctx)
+	genCtx, cancel := context.WithCancel(a.withRunProvider(ctx))
 
 	a.activeRequests.Store(sessionID, cancel)
 	go func() {
@@ -214,17 +284,21 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 		defer logging.RecoverPanic("agent.Run", func() {
 			events <- a.err(fmt.Errorf("panic while running the agent"))
 		})
-		var attachmentParts []message.ContentPart
-		for _, attachment := range attachments {
-			attachmentParts = append(attachmentParts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
-		}
-		result := a.processGeneration(genCtx, sessionID, content, attachmentParts)
+		result := process(genCtx)
 		if result.Error != nil && !errors.Is(result.Error, ErrRequestCancelled) && !errors.Is(result.Error, context.Canceled) {
 			logging.ErrorPersist(result.Error.Error())
 		}
 		logging.Debug("Request completed", "sessionID", sessionID)
+		a.queueMu.Lock()
 		a.activeRequests.Delete(sessionID)
 		cancel()
+		a.requeueSteeringLocked(sessionID)
+		// Errors, cancellations, denied permissions and budget stops pause
+		// the queue until the user sends another prompt.
+		if result.Error == nil && result.Limit == nil && !interrupted(result.Message.FinishReason()) {
+			a.dispatchLocked(sessionID)
+		}
+		a.queueMu.Unlock()
 		a.Publish(pubsub.CreatedEvent, result)
 		events <- result
 		close(events)
@@ -233,7 +307,6 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 }
 
 func (a *agent) processGeneration(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
-	cfg := config.Get()
 	// List existing messages; if none, start title generation asynchronously.
 	msgs, err := a.messages.List(ctx, sessionID)
 	if err != nil {
@@ -250,9 +323,42 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			}
 		}()
 	}
+	msgs, err = a.history(ctx, sessionID, msgs)
+	if err != nil {
+		return a.err(err)
+	}
+
+	userMsg, err := a.createUserMessage(ctx, sessionID, content, attachmentParts)
+	if err != nil {
+		return a.err(fmt.Errorf("failed to create user message: %w", err))
+	}
+	// Append the new user message to the conversation history.
+	msgHistory := append(msgs, userMsg)
+
+	return a.generate(ctx, sessionID, content, msgHistory)
+}
+
+func (a *agent) processContinuation(ctx context.Context, sessionID string) AgentEvent {
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return a.err(fmt.Errorf("failed to list messages: %w", err))
+	}
+	msgHistory, err := a.history(ctx, sessionID, msgs)
+	if err != nil {
+		return a.err(err)
+	}
+	if len(msgHistory) == 0 {
+		return a.err(fmt.Errorf("no messages to continue from"))
+	}
+	return a.generate(ctx, sessionID, "", msgHistory)
+}
+
+// history trims msgs to what should be sent to the provider: everything from
+// the session summary onwards, without budget stop notices.
+func (a *agent) history(ctx context.Context, sessionID string, msgs []message.Message) ([]message.Message, error) {
 	session, err := a.sessions.Get(ctx, sessionID)
 	if err != nil {
-		return a.err(fmt.Errorf("failed to get session: %w", err))
+		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 	if session.SummaryMessageID != "" {
 		summaryMsgInex := -1
@@ -265,17 +371,22 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		if summaryMsgInex != -1 {
 			msgs = msgs[summaryMsgInex:]
 			msgs[0].Role = message.User
+			msgs[0].Summary = true
 		}
 	}
-
-	userMsg, err := a.createUserMessage(ctx, sessionID, content, attachmentParts)
-	if err != nil {
-		return a.err(fmt.Errorf("failed to create user message: %w", err))
+	history := make([]message.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if isLimitMessage(msg) {
+			continue
+		}
+		history = append(history, msg)
 	}
-	// Append the new user message to the conversation history.
-	msgHistory := append(msgs, userMsg)
+	return history, nil
+}
 
-	for {
+func (a *agent) generate(ctx context.Context, sessionID, content string, msgHistory []message.Message) AgentEvent {
+	cfg := config.Get()
+	for iteration := 0; ; iteration++ {
 		// Check for cancellation before each iteration
 		select {
 		case <-ctx.Done():
@@ -283,7 +394,15 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		default:
 			// Continue processing
 		}
-		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory)
+		limit, err := a.checkLimits(ctx, sessionID, iteration)
+		if err != nil {
+			return a.err(err)
+		}
+		if limit != nil {
+			return a.limitReached(ctx, sessionID, limit)
+		}
+		msgHistory = a.compact(ctx, sessionID, msgHistory)
+		agentMessage, toolResults, err := a.streamWithFallback(ctx, sessionID, msgHistory)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				agentMessage.AddFinish(message.FinishReasonCanceled)
@@ -299,60 +418,34 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		} else {
 			logging.Info("Result", "message", agentMessage.FinishReason(), "toolResults", toolResults)
 		}
+		// A turn that was interrupted stops the run, pending steering notes
+		// are requeued for the next one.
+		var steering []message.Message
+		if !interrupted(agentMessage.FinishReason()) {
+			steering, err = a.takeSteering(ctx, sessionID)
+			if err != nil {
+				return a.err(err)
+			}
+		}
 		if (agentMessage.FinishReason() == message.FinishReasonToolUse) && toolResults != nil {
 			// We are not done, we need to respond with the tool response
 			msgHistory = append(msgHistory, agentMessage, *toolResults)
+			msgHistory = append(msgHistory, steering...)
+			continue
+		}
+		if len(steering) > 0 {
+			// A note arrived while the final answer was generated, let the
+			// model respond to it before finishing.
+			msgHistory = append(msgHistory, agentMessage)
+			if toolResults != nil {
+				msgHistory = append(msgHistory, *toolResults)
+			}
+			msgHistory = append(msgHistory, steering...)
 			continue
 		}
 
-		// Phase 1: Grok Self-Reflection Hook (post-process, async for non-blocking)
-		if a.provider.Model().ID == "xai2.grok-4-0709" && agentMessage.FinishReason() != message.FinishReasonError {
-			go func() {
-				defer logging.RecoverPanic("reflection", func() {
-					logging.Error("Panic in reflection—like a warp core breach!")
-				})
-
-				// Ultrathink: Build comprehensive input for deep analysis
-				reflectionInput := map[string]interface{}{
-					"sessionID": sessionID,
-					"content":   content,
-					"history":   msgHistory, // Full convo for context
-					"output":    agentMessage.Content().String(),
-					"success":   true,
-				}
-				inputJSON, _ := json.Marshal(reflectionInput)
-
-				// Call PoC MCP (your stub, with timeout)
-				mcpResp, mcpErr := a.CallMCP("reflection", string(inputJSON))
-				if mcpErr != nil {
-					logging.Error(fmt.Sprintf("MCP failed—beam me up: %v", mcpErr))
-					return
-				}
-
-				// Parse response
-				var reflection map[string]interface{}
-				json.Unmarshal([]byte(mcpResp), &reflection)
-
-				// Propose suggestions with approval (Trek quip + logging/TUI stub)
-				suggestions, ok := reflection["suggestions"].([]interface{})
-				if ok {
-					for _, sug := range suggestions {
-						proposal := fmt.Sprintf("Grok ultrathinks: %s (Trek: Engage fixes!). Approve? Y/N", sug)
-						// TODO: Tie to TUI/pubsub for real approval (publish event)
-						logging.Info(proposal) // Stub; replace with pubsub.Publish("approval", proposal)
-						approved := true       // TODO: Get from user/TUI
-						if approved {
-							if sugStr, ok := sug.(string); ok {
-								a.addDynamicTool(sugStr) // Deploy example
-							}
-							a.storeToRAG(reflection) // Phase 3
-							logging.Info("Approved—like Picard saying 'Make it so!'")
-						} else {
-							logging.Info("Denied—like Spock's logic veto.")
-						}
-					}
-				}
-			}()
+		if agentMessage.FinishReason() != message.FinishReasonError {
+			a.runPostTurnHooks(ctx, sessionID, content, append(msgHistory, agentMessage))
 		}
 
 		return AgentEvent{
@@ -374,12 +467,13 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 
 func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, a.tools)
+	runProvider := a.currentProvider(ctx)
+	eventChan := runProvider.StreamResponse(ctx, msgHistory, a.tools)
 
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:  message.Assistant,
 		Parts: []message.ContentPart{},
-		Model: a.provider.Model().ID,
+		Model: runProvider.Model().ID,
 	})
 	if err != nil {
 		return assistantMsg, nil, fmt.Errorf("failed to create assistant message: %w", err)
@@ -400,106 +494,13 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		}
 	}
 
-	toolResults := make([]message.ToolResult, len(assistantMsg.ToolCalls()))
-	toolCalls := assistantMsg.ToolCalls()
-	for i, toolCall := range toolCalls {
-		select {
-		case <-ctx.Done():
-			a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled)
-			// Make all future tool calls cancelled
-			for j := i; j < len(toolCalls); j++ {
-				toolResults[j] = message.ToolResult{
-					ToolCallID: toolCalls[j].ID,
-					Content:    "Tool execution canceled by user",
-					IsError:    true,
-				}
-			}
-			goto out
-		default:
-			// Continue processing
-			var tool tools.BaseTool
-			for _, availableTool := range a.tools {
-				if availableTool.Info().Name == toolCall.Name {
-					tool = availableTool
-					break
-				}
-				// Handle tool name repetition (e.g., "writewritewrite" -> "write")
-				// Some models repeat tool names multiple times
-				toolName := availableTool.Info().Name
-				if len(toolCall.Name) >= len(toolName)*2 && strings.HasPrefix(toolCall.Name, toolName) {
-					// Check if the call name is the tool name repeated
-					repeated := true
-					for k := 0; k < len(toolCall.Name); k += len(toolName) {
-						if k+len(toolName) > len(toolCall.Name) {
-							if toolCall.Name[k:] != toolName[:len(toolCall.Name)-k] {
-								repeated = false
-								break
-							}
-						} else if toolCall.Name[k:k+len(toolName)] != toolName {
-							repeated = false
-							break
-						}
-					}
-					if repeated {
-						tool = availableTool
-						// Fix the tool name in the call
-						toolCall.Name = toolName
-						break
-					}
-				}
-			}
-
-			if tool == nil || !isValidToolName(toolCall.Name, a.tools) {
-				logging.Error("Invalid tool name", "name", toolCall.Name)
-				toolResults[i] = message.ToolResult{
-					ToolCallID: toolCall.ID,
-					Content:    fmt.Sprintf("Tool not found: %s", toolCall.Name),
-					IsError:    true,
-				}
-				continue
-			}
-			sanitizedInput, err := sanitizeToolInput(toolCall.Input)
-			if err != nil {
-				logging.Error("Param parse error", "error", err, "input", toolCall.Input)
-				toolResults[i] = message.ToolResult{
-					ToolCallID: toolCall.ID,
-					Content:    fmt.Sprintf("error parsing parameters: %v", err),
-					IsError:    true,
-				}
-				continue
-			}
-			toolResult, toolErr := tool.Run(ctx, tools.ToolCall{
-				ID:    toolCall.ID,
-				Name:  toolCall.Name,
-				Input: sanitizedInput,
-			})
-			if toolErr != nil {
-				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
-					toolResults[i] = message.ToolResult{
-						ToolCallID: toolCall.ID,
-						Content:    "Permission denied",
-						IsError:    true,
-					}
-					for j := i + 1; j < len(toolCalls); j++ {
-						toolResults[j] = message.ToolResult{
-							ToolCallID: toolCalls[j].ID,
-							Content:    "Tool execution canceled by user",
-							IsError:    true,
-						}
-					}
-					a.finishMessage(ctx, &assistantMsg, message.FinishReasonPermissionDenied)
-					break
-				}
-			}
-			toolResults[i] = message.ToolResult{
-				ToolCallID: toolCall.ID,
-				Content:    toolResult.Content,
-				Metadata:   toolResult.Metadata,
-				IsError:    toolResult.IsError,
-			}
-		}
+	toolResults, finishReason := a.runToolCalls(ctx, assistantMsg.ToolCalls())
+	switch finishReason {
+	case message.FinishReasonCanceled:
+		a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled)
+	case message.FinishReasonPermissionDenied:
+		a.finishMessage(ctx, &assistantMsg, message.FinishReasonPermissionDenied)
 	}
-out:
 	if len(toolResults) == 0 {
 		return assistantMsg, nil, nil
 	}
@@ -569,14 +570,20 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message with error: %w", err)
 		}
-		return event.Error
+		return &providerError{event.Error}
+	case provider.EventWarning:
+		// Retry countdowns arrive every second, each replacing the last
+		logging.WarnPersist(event.Content, logging.PersistTimeArg, retryWarningTime)
 	case provider.EventComplete:
 		assistantMsg.SetToolCalls(event.Response.ToolCalls)
+		if event.Response.State != nil {
+			assistantMsg.SetProviderState(*event.Response.State)
+		}
 		assistantMsg.AddFinish(event.Response.FinishReason)
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
 		}
-		return a.TrackUsage(ctx, sessionID, a.provider.Model(), event.Response.Usage)
+		return a.TrackUsage(ctx, sessionID, a.currentProvider(ctx).Model(), event.Response.Usage)
 	}
 
 	return nil
@@ -588,11 +595,7 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.M
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-
+	cost := usage.Cost(model)
 	sess.Cost += cost
 	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
 	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
@@ -601,6 +604,14 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.M
 	if err != nil {
 		return fmt.Errorf("failed to save session: %w", err)
 	}
+	if err := a.sessions.AddCost(ctx, time.Now(), cost); err != nil {
+		return fmt.Errorf("failed to record daily cost: %w", err)
+	}
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:      AgentEventTypeUsage,
+		SessionID: sessionID,
+		Usage:     &usage,
+	})
 	return nil
 }
 
@@ -630,6 +641,30 @@ func (a *agent) Update(agentName config.AgentName, modelID models.ModelID) (mode
 	return actualModel, nil
 }
 
+func (a *agent) AgentName() config.AgentName {
+	return a.name
+}
+
+// SwitchAgent makes another agent answer the prompts of this service, keeping
+// the title and summary providers. It is used to change the primary agent.
+func (a *agent) SwitchAgent(agentName config.AgentName, agentTools []tools.BaseTool) (models.Model, error) {
+	if a.IsBusy() {
+		return models.Model{}, fmt.Errorf("cannot switch agents while processing requests")
+	}
+	provider, err := createAgentProvider(agentName)
+	if err != nil {
+		return models.Model{}, fmt.Errorf("failed to create provider for agent %s: %w", agentName, err)
+	}
+	if err := config.UpdatePrimaryAgent(agentName); err != nil {
+		return models.Model{}, err
+	}
+	a.name = agentName
+	a.provider = provider
+	a.tools = agentTools
+	logging.Info("switched primary agent", "agent", agentName, "model", provider.Model().ID)
+	return provider.Model(), nil
+}
+
 func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	if a.summarizeProvider == nil {
 		return fmt.Errorf("summarize provider not available")
@@ -666,8 +701,6 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 			a.Publish(pubsub.CreatedEvent, event)
 			return
 		}
-		summarizeCtx = context.WithValue(summarizeCtx, tools.SessionIDContextKey, sessionID)
-
 		if len(msgs) == 0 {
 			event = AgentEvent{
 				Type:  AgentEventTypeError,
@@ -684,141 +717,91 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		}
 		a.Publish(pubsub.CreatedEvent, event)
 
-		// Add a system message to guide the summarization
-		summarizePrompt := "Provide a detailed but concise summary of our conversation above. Focus on information that would be helpful for continuing the conversation, including what we did, what we're doing, which files we're working on, and what we're going to do next."
-
-		// Create a new message with the summarize prompt
-		promptMsg := message.Message{
-			Role:  message.User,
-			Parts: []message.ContentPart{message.TextContent{Text: summarizePrompt}},
-		}
-
-		// Append the prompt to the messages
-		msgsWithPrompt := append(msgs, promptMsg)
-
 		event = AgentEvent{
 			Type:     AgentEventTypeSummarize,
 			Progress: "Generating summary...",
 		}
-
-		a.Publish(pubsub.CreatedEvent, event)
-
-		// Send the messages to the summarize provider
-		response, err := a.summarizeProvider.SendMessages(
-			summarizeCtx,
-			msgsWithPrompt,
-			make([]tools.BaseTool, 0),
-		)
-		if err != nil {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to summarize: %w", err),
-				Done:  true,
-			}
-			a.Publish(pubsub.CreatedEvent, event)
-			return
-		}
-
-		summary := strings.TrimSpace(response.Content)
-		if summary == "" {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("empty summary returned"),
-				Done:  true,
-			}
-			a.Publish(pubsub.CreatedEvent, event)
-			return
-		}
-		event = AgentEvent{
-			Type:     AgentEventTypeSummarize,
-			Progress: "Creating new session...",
-		}
-
 		a.Publish(pubsub.CreatedEvent, event)
-		oldSession, err := a.sessions.Get(summarizeCtx, sessionID)
-		if err != nil {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to get session: %w", err),
-				Done:  true,
-			}
 
-			a.Publish(pubsub.CreatedEvent, event)
-			return
-		}
-		// Create a message in the new session with the summary
-		msg, err := a.messages.Create(summarizeCtx, oldSession.ID, message.CreateMessageParams{
-			Role: message.Assistant,
-			Parts: []message.ContentPart{
-				message.TextContent{Text: summary},
-				message.Finish{
-					Reason: message.FinishReasonEndTurn,
-					Time:   time.Now().Unix(),
-				},
-			},
-			Model: a.summarizeProvider.Model().ID,
-		})
-		if err != nil {
+		if _, err := a.summarize(summarizeCtx, sessionID, msgs); err != nil {
 			event = AgentEvent{
 				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to create summary message: %w", err),
+				Error: err,
 				Done:  true,
 			}
-
 			a.Publish(pubsub.CreatedEvent, event)
 			return
 		}
-		oldSession.SummaryMessageID = msg.ID
-		oldSession.CompletionTokens = response.Usage.OutputTokens
-		oldSession.PromptTokens = 0
-		model := a.summarizeProvider.Model()
-		usage := response.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-		oldSession.Cost += cost
-		_, err = a.sessions.Save(summarizeCtx, oldSession)
-		if err != nil {
-			event = AgentEvent{
-				Type:  AgentEventTypeError,
-				Error: fmt.Errorf("failed to save session: %w", err),
-				Done:  true,
-			}
-			a.Publish(pubsub.CreatedEvent, event)
-		}
 
 		event = AgentEvent{
 			Type:      AgentEventTypeSummarize,
-			SessionID: oldSession.ID,
+			SessionID: sessionID,
 			Progress:  "Summary complete",
 			Done:      true,
 		}
 		a.Publish(pubsub.CreatedEvent, event)
-		// Send final success event with the new session ID
 	}()
 
 	return nil
 }
 
-// Deploy stubs
-func (a *agent) addDynamicTool(name string) {
-	// TODO: Implement dynamic tool addition
-	logging.Info("Adding dynamic tool: " + name)
-}
+// summarize has the summarizer summarize msgs and stores the summary as the
+// session summary, from which the history sent to the provider starts.
+func (a *agent) summarize(ctx context.Context, sessionID string, msgs []message.Message) (message.Message, error) {
+	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
 
-func (a *agent) storeToRAG(data map[string]interface{}) {
-	// Phase 3: Vector insert
-	// TODO: implement
-}
+	// Add a user message to guide the summarization
+	summarizePrompt := "Provide a detailed but concise summary of our conversation above. Focus on information that would be helpful for continuing the conversation, including what we did, what we're doing, which files we're working on, and what we're going to do next."
+	promptMsg := message.Message{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: summarizePrompt}},
+	}
+	msgsWithPrompt := append(msgs[:len(msgs):len(msgs)], promptMsg)
+
+	response, err := a.summarizeProvider.SendMessages(
+		ctx,
+		msgsWithPrompt,
+		make([]tools.BaseTool, 0),
+	)
+	if err != nil {
+		return message.Message{}, fmt.Errorf("failed to summarize: %w", err)
+	}
+	summary := strings.TrimSpace(response.Content)
+	if summary == "" {
+		return message.Message{}, fmt.Errorf("empty summary returned")
+	}
 
-func (a *agent) CallMCP(kind string, input string) (string, error) {
-	cmd := exec.Command("bash", "-c", fmt.Sprintf("echo '%s' | timeout 10s python3 /opt/aeo/opencode/mcps/reflection_mcp.py", input))
-	output, err := cmd.Output()
+	session, err := a.sessions.Get(ctx, sessionID)
 	if err != nil {
-		return "", err
+		return message.Message{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	msg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: summary},
+			message.Finish{
+				Reason: message.FinishReasonEndTurn,
+				Time:   time.Now().Unix(),
+			},
+		},
+		Model: a.summarizeProvider.Model().ID,
+	})
+	if err != nil {
+		return message.Message{}, fmt.Errorf("failed to create summary message: %w", err)
+	}
+	session.SummaryMessageID = msg.ID
+	session.CompletionTokens = response.Usage.OutputTokens
+	session.PromptTokens = 0
+	model := a.summarizeProvider.Model()
+	cost := response.Usage.Cost(model)
+	session.Cost += cost
+	if _, err := a.sessions.Save(ctx, session); err != nil {
+		return message.Message{}, fmt.Errorf("failed to save session: %w", err)
 	}
-	return string(output), nil
+	if err := a.sessions.AddCost(ctx, time.Now(), cost); err != nil {
+		return message.Message{}, fmt.Errorf("failed to record daily cost: %w", err)
+	}
+	return msg, nil
 }
 
 func createAgentProvider(agentName config.AgentName) (provider.Provider, error) {
@@ -828,12 +811,26 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 		logging.Error("agent not found", "agent", agentName)
 		return nil, fmt.Errorf("agent %s not found", agentName)
 	}
-	logging.Info("creating agent provider", "agent", agentName, "modelID", agentConfig.Model)
+	return createModelProvider(agentName, agentConfig, agentConfig.Model)
+}
+
+// NewModelProvider creates the provider of a model with the options the agent
+// would use for it, followed by opts.
+func NewModelProvider(agentName config.AgentName, agentConfig config.Agent, modelID models.ModelID, opts ...provider.ProviderClientOption) (provider.Provider, error) {
+	return createModelProvider(agentName, agentConfig, modelID, opts...)
+}
+
+// createModelProvider creates the provider of an agent running modelID, which
+// is either its configured model or one of its fallbacks, followed by
+// extraOpts.
+func createModelProvider(agentName config.AgentName, agentConfig config.Agent, modelID models.ModelID, extraOpts ...provider.ProviderClientOption) (provider.Provider, error) {
+	cfg := config.Get()
+	logging.Info("creating agent provider", "agent", agentName, "modelID", modelID)
 
-	model, ok := models.SupportedModels[agentConfig.Model]
+	model, ok := models.SupportedModels[modelID]
 	if !ok {
-		logging.Error("model not supported", "modelID", agentConfig.Model)
-		return nil, fmt.Errorf("model %s not supported", agentConfig.Model)
+		logging.Error("model not supported", "modelID", modelID)
+		return nil, fmt.Errorf("model %s not supported", modelID)
 	}
 	logging.Info("found model", "modelID", model.ID, "provider", model.Provider, "name", model.Name)
 
@@ -852,27 +849,49 @@ func createAgentProvider(agentName config.AgentName) (provider.Provider, error)
 	if agentConfig.MaxTokens > 0 {
 		maxTokens = agentConfig.MaxTokens
 	}
+	// The configured max tokens were validated against the configured model
+	if modelID != agentConfig.Model && model.ContextWindow > 0 && maxTokens > model.ContextWindow/2 {
+		maxTokens = model.DefaultMaxTokens
+	}
 	opts := []provider.ProviderClientOption{
 		provider.WithAPIKey(providerCfg.APIKey),
 		provider.WithModel(model),
 		provider.WithSystemMessage(prompt.GetAgentPrompt(agentName, model.Provider)),
 		provider.WithMaxTokens(maxTokens),
 	}
-	if model.Provider == models.ProviderOpenAI || model.Provider == models.ProviderLocal && model.CanReason {
+	var anthropicOpts []provider.AnthropicOption
+	if model.Provider == models.ProviderOpenAI || (model.Provider == models.ProviderLocal || config.IsCustomProvider(model.Provider)) && model.CanReason {
 		opts = append(
 			opts,
 			provider.WithOpenAIOptions(
 				provider.WithReasoningEffort(agentConfig.ReasoningEffort),
 			),
 		)
-	} else if model.Provider == models.ProviderAnthropic && model.CanReason && agentName == config.AgentCoder {
+	} else if (model.Provider == models.ProviderGemini || model.Provider == models.ProviderVertexAI) && model.CanReason && agentName != config.AgentTitle {
 		opts = append(
 			opts,
-			provider.WithAnthropicOptions(
-				provider.WithAnthropicShouldThinkFn(provider.DefaultShouldThinkFn),
+			provider.WithGeminiOptions(
+				provider.WithGeminiReasoningEffort(agentConfig.ReasoningEffort),
 			),
 		)
+	} else if model.Provider == models.ProviderOllama && model.CanReason && agentName != config.AgentTitle && agentConfig.ReasoningEffort != "" {
+		opts = append(opts, provider.WithOllamaOptions(provider.WithOllamaThink()))
+	} else if model.Provider == models.ProviderAnthropic && model.CanReason && agentName != config.AgentTitle {
+		if agentConfig.ReasoningEffort != "" {
+			anthropicOpts = append(anthropicOpts, provider.WithAnthropicReasoningEffort(agentConfig.ReasoningEffort))
+		} else if agentName == config.AgentCoder {
+			// Without an effort the coder thinks when asked to
+			anthropicOpts = append(anthropicOpts, provider.WithAnthropicShouldThinkFn(provider.DefaultShouldThinkFn))
+		}
+	}
+	// Leave overloaded models quickly when there is another one to try
+	if i := slices.Index(agentConfig.Fallback, modelID); i < len(agentConfig.Fallback)-1 {
+		anthropicOpts = append(anthropicOpts, provider.WithAnthropicFailOnOverload())
+	}
+	if len(anthropicOpts) > 0 {
+		opts = append(opts, provider.WithAnthropicOptions(anthropicOpts...))
 	}
+	opts = append(opts, extraOpts...)
 	agentProvider, err := provider.NewProvider(
 		model.Provider,
 		opts...,
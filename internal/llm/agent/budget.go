@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+// LimitKind identifies which configured budget stopped a run.
+type LimitKind string
+
+const (
+	LimitSessionCost    LimitKind = "session_cost"
+	LimitDailyCost      LimitKind = "daily_cost"
+	LimitToolIterations LimitKind = "tool_iterations"
+)
+
+// LimitExceeded describes a budget that was reached before a provider call.
+type LimitExceeded struct {
+	Kind    LimitKind
+	Limit   float64
+	Current float64
+}
+
+func (l LimitExceeded) Error() string {
+	switch l.Kind {
+	case LimitToolIterations:
+		return fmt.Sprintf("tool loop limit of %d iterations reached", int(l.Limit))
+	case LimitDailyCost:
+		return fmt.Sprintf("daily cost limit of $%.2f reached ($%.2f spent today)", l.Limit, l.Current)
+	default:
+		return fmt.Sprintf("session cost limit of $%.2f reached ($%.2f spent)", l.Limit, l.Current)
+	}
+}
+
+// checkLimits returns the first configured budget that would be exceeded by
+// another provider call, or nil if the run may continue. iterations is the
+// number of tool rounds already completed for the current request.
+func (a *agent) checkLimits(ctx context.Context, sessionID string, iterations int) (*LimitExceeded, error) {
+	budget := config.Get().Budget
+
+	if budget.MaxToolIterations > 0 && iterations >= budget.MaxToolIterations {
+		return &LimitExceeded{
+			Kind:    LimitToolIterations,
+			Limit:   float64(budget.MaxToolIterations),
+			Current: float64(iterations),
+		}, nil
+	}
+
+	if budget.MaxSessionCost > 0 {
+		sess, err := a.sessions.Get(ctx, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session: %w", err)
+		}
+		if sess.Cost >= budget.MaxSessionCost {
+			return &LimitExceeded{
+				Kind:    LimitSessionCost,
+				Limit:   budget.MaxSessionCost,
+				Current: sess.Cost,
+			}, nil
+		}
+	}
+
+	if budget.MaxDailyCost > 0 {
+		spent, err := a.sessions.DailyCost(ctx, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daily cost: %w", err)
+		}
+		if spent >= budget.MaxDailyCost {
+			return &LimitExceeded{
+				Kind:    LimitDailyCost,
+				Limit:   budget.MaxDailyCost,
+				Current: spent,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// limitReached records a stop message in the session so the user can see why
+// the run ended, and builds the event that offers to continue.
+func (a *agent) limitReached(ctx context.Context, sessionID string, limit *LimitExceeded) AgentEvent {
+	logging.WarnPersist(fmt.Sprintf("Agent stopped: %s", limit.Error()))
+	msg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: fmt.Sprintf("Stopped: %s.", limit.Error())},
+		},
+		Model: a.currentProvider(ctx).Model().ID,
+	})
+	if err != nil {
+		return a.err(fmt.Errorf("failed to create limit message: %w", err))
+	}
+	a.finishMessage(ctx, &msg, message.FinishReasonLimitReached)
+	return AgentEvent{
+		Type:    AgentEventTypeLimit,
+		Message: msg,
+		Limit:   limit,
+		Done:    true,
+	}
+}
+
+// isLimitMessage reports whether msg is a stop notice written by limitReached.
+// These notices are shown to the user but never sent back to the model.
+func isLimitMessage(msg message.Message) bool {
+	return msg.Role == message.Assistant &&
+		msg.FinishReason() == message.FinishReasonLimitReached &&
+		len(msg.ToolCalls()) == 0
+}
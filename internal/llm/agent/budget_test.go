@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBudgetAgent(t *testing.T, budget config.BudgetConfig) *agent {
+	t.Helper()
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Data.Directory = t.TempDir()
+	require.NoError(t, config.UpdateBudget(budget))
+
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	return &agent{
+		name:     config.AgentCoder,
+		sessions: session.NewService(q, conn),
+		messages: message.NewService(q),
+		provider: fakeProvider{models.Model{ID: "primary"}},
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		a := newBudgetAgent(t, config.BudgetConfig{})
+		sess, err := a.sessions.Create(ctx, "session")
+		require.NoError(t, err)
+		require.NoError(t, a.sessions.AddCost(ctx, time.Now(), 100))
+
+		limit, err := a.checkLimits(ctx, sess.ID, 1000)
+		require.NoError(t, err)
+		assert.Nil(t, limit)
+	})
+
+	t.Run("tool iterations", func(t *testing.T) {
+		a := newBudgetAgent(t, config.BudgetConfig{MaxToolIterations: 3})
+		sess, err := a.sessions.Create(ctx, "session")
+		require.NoError(t, err)
+
+		limit, err := a.checkLimits(ctx, sess.ID, 2)
+		require.NoError(t, err)
+		assert.Nil(t, limit)
+
+		limit, err = a.checkLimits(ctx, sess.ID, 3)
+		require.NoError(t, err)
+		assert.Equal(t, &LimitExceeded{Kind: LimitToolIterations, Limit: 3, Current: 3}, limit)
+	})
+
+	t.Run("session cost", func(t *testing.T) {
+		a := newBudgetAgent(t, config.BudgetConfig{MaxSessionCost: 1})
+		sess, err := a.sessions.Create(ctx, "session")
+		require.NoError(t, err)
+		sess.Cost = 1.5
+		_, err = a.sessions.Save(ctx, sess)
+		require.NoError(t, err)
+
+		limit, err := a.checkLimits(ctx, sess.ID, 0)
+		require.NoError(t, err)
+		assert.Equal(t, &LimitExceeded{Kind: LimitSessionCost, Limit: 1, Current: 1.5}, limit)
+	})
+
+	t.Run("daily cost", func(t *testing.T) {
+		a := newBudgetAgent(t, config.BudgetConfig{MaxDailyCost: 2})
+		sess, err := a.sessions.Create(ctx, "session")
+		require.NoError(t, err)
+		// A session that has been running for days only counts what it spent today
+		sess.Cost = 10
+		_, err = a.sessions.Save(ctx, sess)
+		require.NoError(t, err)
+		require.NoError(t, a.sessions.AddCost(ctx, time.Now().AddDate(0, 0, -1), 10))
+		require.NoError(t, a.sessions.AddCost(ctx, time.Now(), 1.5))
+
+		limit, err := a.checkLimits(ctx, sess.ID, 0)
+		require.NoError(t, err)
+		assert.Nil(t, limit)
+
+		require.NoError(t, a.sessions.AddCost(ctx, time.Now(), 0.5))
+		limit, err = a.checkLimits(ctx, sess.ID, 0)
+		require.NoError(t, err)
+		assert.Equal(t, &LimitExceeded{Kind: LimitDailyCost, Limit: 2, Current: 2}, limit)
+	})
+}
+
+func TestLimitReached(t *testing.T) {
+	ctx := context.Background()
+	a := newBudgetAgent(t, config.BudgetConfig{MaxSessionCost: 1})
+	sess, err := a.sessions.Create(ctx, "session")
+	require.NoError(t, err)
+
+	limit := &LimitExceeded{Kind: LimitSessionCost, Limit: 1, Current: 1.5}
+	event := a.limitReached(ctx, sess.ID, limit)
+	require.NoError(t, event.Error)
+	assert.Equal(t, AgentEventTypeLimit, event.Type)
+	assert.True(t, event.Done)
+	assert.Equal(t, limit, event.Limit)
+
+	msgs, err := a.messages.List(ctx, sess.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.True(t, isLimitMessage(msgs[0]))
+	assert.Equal(t, models.ModelID("primary"), msgs[0].Model)
+	assert.Equal(t, "Stopped: "+limit.Error()+".", msgs[0].Content().Text)
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+const (
+	// charsPerToken is a rough average that errs towards overestimating.
+	charsPerToken = 4
+	// binaryTokens is a rough estimate for an attached image or file.
+	binaryTokens = 1000
+
+	// keepToolResults is how many of the most recent tool messages keep
+	// their results when pruning.
+	keepToolResults = 3
+	// minPrunedLength is the length below which a tool result is left as is,
+	// the placeholder would not save anything.
+	minPrunedLength = 200
+
+	prunedToolResult = "[Output removed to save context. Run the tool again if you need it.]"
+)
+
+// estimateTokens returns a rough estimate of the tokens msgs and the
+// definitions of agentTools take up in a request.
+func estimateTokens(msgs []message.Message, agentTools []tools.BaseTool) int64 {
+	var chars, tokens int64
+	for _, msg := range msgs {
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case message.TextContent:
+				chars += int64(len(p.Text))
+			case message.ReasoningContent:
+				chars += int64(len(p.Thinking))
+			case message.ToolCall:
+				chars += int64(len(p.Name) + len(p.Input))
+			case message.ToolResult:
+				chars += int64(len(p.Name) + len(p.Content))
+			case message.BinaryContent, message.ImageURLContent:
+				tokens += binaryTokens
+			}
+		}
+	}
+	for _, tool := range agentTools {
+		if data, err := json.Marshal(tool.Info()); err == nil {
+			chars += int64(len(data))
+		}
+	}
+	return tokens + chars/charsPerToken
+}
+
+// pruneToolResults returns a copy of msgs in which the results of all but the
+// most recent tool messages are replaced with a placeholder. The stored
+// messages are left untouched.
+func pruneToolResults(msgs []message.Message) []message.Message {
+	pruned := make([]message.Message, len(msgs))
+	copy(pruned, msgs)
+	kept := 0
+	for i := len(pruned) - 1; i >= 0; i-- {
+		if pruned[i].Role != message.Tool {
+			continue
+		}
+		if kept < keepToolResults {
+			kept++
+			continue
+		}
+		parts := make([]message.ContentPart, len(pruned[i].Parts))
+		for j, part := range pruned[i].Parts {
+			if result, ok := part.(message.ToolResult); ok && len(result.Content) > minPrunedLength {
+				result.Content = prunedToolResult
+				result.Metadata = ""
+				part = result
+			}
+			parts[j] = part
+		}
+		pruned[i].Parts = parts
+	}
+	return pruned
+}
+
+// compact keeps the estimated size of msgHistory under the configured share
+// of the model's context window, pruning old tool results or summarizing
+// older turns depending on the strategy. It returns the history to continue
+// with, which is msgHistory when no compaction was needed or possible.
+func (a *agent) compact(ctx context.Context, sessionID string, msgHistory []message.Message) []message.Message {
+	cfg := config.Get()
+	if cfg == nil || !cfg.AutoCompact {
+		return msgHistory
+	}
+	contextWindow := a.currentProvider(ctx).Model().ContextWindow
+	if contextWindow <= 0 {
+		return msgHistory
+	}
+	limit := int64(float64(contextWindow) * cfg.Compaction.Threshold)
+	estimate := estimateTokens(msgHistory, a.tools)
+	if estimate <= limit {
+		return msgHistory
+	}
+
+	strategy := cfg.Compaction.Strategy
+	if strategy != config.CompactionPrune && a.summarizeProvider == nil {
+		strategy = config.CompactionPrune
+	}
+	if strategy != config.CompactionSummarize {
+		pruned := pruneToolResults(msgHistory)
+		prunedEstimate := estimateTokens(pruned, a.tools)
+		if prunedEstimate < estimate {
+			logging.Info("pruned tool results", "session", sessionID, "before", estimate, "after", prunedEstimate, "limit", limit)
+			a.publishCompacted(sessionID, "Context compacted by pruning old tool results")
+		}
+		msgHistory, estimate = pruned, prunedEstimate
+		if strategy == config.CompactionPrune || estimate <= limit {
+			return msgHistory
+		}
+	}
+
+	summary, err := a.summarize(ctx, sessionID, msgHistory)
+	if err != nil {
+		logging.Error("failed to compact context", "session", sessionID, "error", err)
+		return msgHistory
+	}
+	summary.Role = message.User
+	compacted := append([]message.Message{summary}, recentTurn(msgHistory, limit, a.tools)...)
+	logging.Info("summarized context", "session", sessionID, "before", estimate, "after", estimateTokens(compacted, a.tools), "limit", limit)
+	a.publishCompacted(sessionID, "Context compacted by summarizing older turns")
+	return compacted
+}
+
+// recentTurn returns the messages of msgs to keep after a summary: the turn
+// since the last user message if it fits in half of limit, otherwise only the
+// last prompt or tool call and its results so the model can carry on from
+// them.
+func recentTurn(msgs []message.Message, limit int64, agentTools []tools.BaseTool) []message.Message {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role != message.User {
+			continue
+		}
+		if turn := msgs[i:]; estimateTokens(turn, agentTools) <= limit/2 {
+			return turn
+		}
+		break
+	}
+	n := len(msgs)
+	if n >= 1 && msgs[n-1].Role == message.User {
+		return msgs[n-1:]
+	}
+	if n >= 2 && msgs[n-1].Role == message.Tool && msgs[n-2].Role == message.Assistant {
+		return msgs[n-2:]
+	}
+	return nil
+}
+
+func (a *agent) publishCompacted(sessionID, progress string) {
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:      AgentEventTypeCompact,
+		SessionID: sessionID,
+		Progress:  progress,
+	})
+}
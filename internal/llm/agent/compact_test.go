@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func toolTurn(id, output string) []message.Message {
+	return []message.Message{
+		{Role: message.Assistant, Parts: []message.ContentPart{message.ToolCall{ID: id, Name: "view", Input: `{"file_path":"a.go"}`}}},
+		{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{ToolCallID: id, Name: "view", Content: output}}},
+	}
+}
+
+func TestPruneToolResults(t *testing.T) {
+	long := strings.Repeat("x", 1000)
+	msgs := []message.Message{{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "read the files"}}}}
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		msgs = append(msgs, toolTurn(id, long)...)
+	}
+	msgs = append(msgs, toolTurn("6", "short")...)
+
+	pruned := pruneToolResults(msgs)
+	assert.Less(t, estimateTokens(pruned, nil), estimateTokens(msgs, nil))
+
+	var contents []string
+	for _, msg := range pruned {
+		for _, result := range msg.ToolResults() {
+			contents = append(contents, result.Content)
+		}
+	}
+	assert.Equal(t, []string{prunedToolResult, prunedToolResult, prunedToolResult, long, long, "short"}, contents)
+	// The original messages are left untouched
+	assert.Equal(t, long, msgs[2].ToolResults()[0].Content)
+}
+
+func TestRecentTurn(t *testing.T) {
+	prompt := message.Message{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "fix it"}}}
+	msgs := append([]message.Message{prompt}, toolTurn("1", strings.Repeat("x", 4000))...)
+
+	assert.Len(t, recentTurn(msgs, 10000, nil), 3)
+	assert.Equal(t, msgs[1:], recentTurn(msgs, 100, nil))
+	assert.Equal(t, []message.Message{prompt}, recentTurn(append(msgs, prompt), 100, nil))
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/provider"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+// providerError is an error reported by the provider while streaming a
+// response, as opposed to a failure of the agent itself.
+type providerError struct {
+	err error
+}
+
+func (e *providerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *providerError) Unwrap() error {
+	return e.err
+}
+
+type runProviderKey struct{}
+
+// runProvider is the provider a run answers with. Every run starts on the
+// model of the agent and moves on to its fallbacks when the provider fails,
+// without affecting the other runs.
+type runProvider struct {
+	provider  provider.Provider
+	fallbacks []models.ModelID
+}
+
+// withRunProvider starts a run of the agent on its model.
+func (a *agent) withRunProvider(ctx context.Context) context.Context {
+	current := a.provider.Model().ID
+	return context.WithValue(ctx, runProviderKey{}, &runProvider{
+		provider: a.provider,
+		fallbacks: slices.DeleteFunc(
+			slices.Clone(config.Get().Agents[a.name].Fallback),
+			func(modelID models.ModelID) bool { return modelID == current },
+		),
+	})
+}
+
+// currentProvider returns the provider the run of ctx answers with, the one of
+// the agent outside a run.
+func (a *agent) currentProvider(ctx context.Context) provider.Provider {
+	if run, ok := ctx.Value(runProviderKey{}).(*runProvider); ok {
+		return run.provider
+	}
+	return a.provider
+}
+
+// streamWithFallback streams a response and, when the provider is unavailable,
+// retries it with the next fallback model until one answers or none is left.
+func (a *agent) streamWithFallback(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
+	for {
+		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory)
+		var provErr *providerError
+		if err == nil || !errors.As(err, &provErr) || ctx.Err() != nil || !provider.Unavailable(provErr) || !a.fallback(ctx, agentMessage, provErr) {
+			return agentMessage, toolResults, err
+		}
+	}
+}
+
+// fallback switches the run of ctx to its next usable fallback model after
+// the provider failed to produce failed, which is removed so that the new
+// model answers in its place. It reports whether there is a model to retry
+// with.
+func (a *agent) fallback(ctx context.Context, failed message.Message, err error) bool {
+	run, ok := ctx.Value(runProviderKey{}).(*runProvider)
+	if !ok {
+		return false
+	}
+	current := run.provider.Model()
+	for {
+		if len(run.fallbacks) == 0 {
+			return false
+		}
+		modelID := run.fallbacks[0]
+		run.fallbacks = run.fallbacks[1:]
+		next, createErr := createModelProvider(a.name, config.Get().Agents[a.name], modelID)
+		if createErr != nil {
+			logging.Warn("failed to create fallback provider", "agent", a.name, "model", modelID, "error", createErr)
+			continue
+		}
+		run.provider = next
+		logging.WarnPersist(fmt.Sprintf("%s failed, switched to %s: %v", current.Name, next.Model().Name, err))
+		break
+	}
+
+	if deleteErr := a.messages.Delete(context.Background(), failed.ID); deleteErr != nil {
+		logging.Error("failed to delete failed message", "message", failed.ID, "error", deleteErr)
+	}
+	return true
+}
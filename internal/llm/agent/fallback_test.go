@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/provider"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	model models.Model
+}
+
+func (f fakeProvider) SendMessages(context.Context, []message.Message, []tools.BaseTool) (*provider.ProviderResponse, error) {
+	return &provider.ProviderResponse{}, nil
+}
+
+func (f fakeProvider) StreamResponse(context.Context, []message.Message, []tools.BaseTool) <-chan provider.ProviderEvent {
+	events := make(chan provider.ProviderEvent)
+	close(events)
+	return events
+}
+
+func (f fakeProvider) Model() models.Model {
+	return f.model
+}
+
+func TestRunProvider(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	agentCfg := config.Get().Agents[config.AgentCoder]
+	agentCfg.Fallback = []models.ModelID{"primary", "fallback-1", "fallback-2"}
+	config.Get().Agents[config.AgentCoder] = agentCfg
+
+	a := &agent{name: config.AgentCoder, provider: fakeProvider{models.Model{ID: "primary"}}}
+	assert.Equal(t, models.ModelID("primary"), a.currentProvider(context.Background()).Model().ID)
+
+	first := a.withRunProvider(context.Background())
+	second := a.withRunProvider(context.Background())
+	run := first.Value(runProviderKey{}).(*runProvider)
+	assert.Equal(t, []models.ModelID{"fallback-1", "fallback-2"}, run.fallbacks)
+
+	// A run that falls back does not move the agent or the other runs
+	run.provider = fakeProvider{models.Model{ID: "fallback-1"}}
+	assert.Equal(t, models.ModelID("fallback-1"), a.currentProvider(first).Model().ID)
+	assert.Equal(t, models.ModelID("primary"), a.currentProvider(second).Model().ID)
+	assert.Equal(t, models.ModelID("primary"), a.Model().ID)
+	assert.Equal(t, models.ModelID("primary"), a.currentProvider(a.withRunProvider(context.Background())).Model().ID)
+}
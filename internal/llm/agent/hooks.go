@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/hooks"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// runPostTurnHooks runs the post-turn hooks configured for the agent in the
+// background and publishes their suggestions for the user to approve.
+func (a *agent) runPostTurnHooks(ctx context.Context, sessionID, prompt string, msgs []message.Message) {
+	model := a.currentProvider(ctx).Model()
+	postTurn := config.PostTurnHooks(a.name, model.ID)
+	if len(postTurn) == 0 {
+		return
+	}
+	input := hooks.TurnInput{
+		SessionID: sessionID,
+		Agent:     string(a.name),
+		Model:     string(model.ID),
+		Prompt:    prompt,
+		Messages:  make([]hooks.Message, 0, len(msgs)),
+	}
+	for _, msg := range msgs {
+		input.Messages = append(input.Messages, hooks.Message{
+			Role:    string(msg.Role),
+			Content: msg.Content().String(),
+		})
+	}
+	if len(msgs) > 0 {
+		input.Response = msgs[len(msgs)-1].Content().String()
+	}
+
+	go func() {
+		defer logging.RecoverPanic("agent.runPostTurnHooks", nil)
+		var suggestions []hooks.Suggestion
+		for _, hook := range postTurn {
+			hookSuggestions, err := hooks.RunPostTurn(context.Background(), hook, input)
+			if err != nil {
+				logging.Warn("post-turn hook failed", "hook", hooks.Name(hook), "error", err)
+				continue
+			}
+			suggestions = append(suggestions, hookSuggestions...)
+		}
+		if len(suggestions) == 0 {
+			return
+		}
+		a.Publish(pubsub.CreatedEvent, AgentEvent{
+			Type:        AgentEventTypeSuggest,
+			SessionID:   sessionID,
+			Suggestions: suggestions,
+		})
+	}()
+}
@@ -234,3 +234,18 @@ func GetMcpTools(ctx context.Context, permissions permission.Service) []tools.Ba
 
 	return mcpTools
 }
+
+// withPermissions returns copies of the MCP tools that ask for permissions
+// through permissions instead of the service they were loaded with.
+func withPermissions(loaded []tools.BaseTool, permissions permission.Service) []tools.BaseTool {
+	bound := make([]tools.BaseTool, 0, len(loaded))
+	for _, t := range loaded {
+		if m, ok := t.(*mcpTool); ok {
+			c := *m
+			c.permissions = permissions
+			t = &c
+		}
+		bound = append(bound, t)
+	}
+	return bound
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+)
+
+// QueuedPrompt is a user prompt waiting for the running request of its session
+// to finish.
+type QueuedPrompt struct {
+	ID          string
+	SessionID   string
+	Content     string
+	Attachments []message.Attachment
+	CreatedAt   int64
+}
+
+type queuedRun struct {
+	ctx    context.Context
+	prompt QueuedPrompt
+	events chan AgentEvent
+}
+
+func newQueuedRun(ctx context.Context, sessionID, content string, attachments []message.Attachment) *queuedRun {
+	return &queuedRun{
+		ctx: ctx,
+		prompt: QueuedPrompt{
+			ID:          uuid.New().String(),
+			SessionID:   sessionID,
+			Content:     content,
+			Attachments: attachments,
+			CreatedAt:   time.Now().Unix(),
+		},
+		events: make(chan AgentEvent, 1),
+	}
+}
+
+// enqueueLocked appends a prompt to the session queue and returns the channel
+// that receives its result once it has been dispatched and completed. If the
+// session is idle, for example because the queue was paused by a
+// cancellation, the head of the queue is dispatched right away. The caller
+// must hold queueMu.
+func (a *agent) enqueueLocked(ctx context.Context, sessionID, content string, attachments []message.Attachment) <-chan AgentEvent {
+	run := newQueuedRun(ctx, sessionID, content, attachments)
+	a.queues[sessionID] = append(a.queues[sessionID], run)
+	if a.IsSessionBusy(sessionID) {
+		a.publishQueueLocked(sessionID)
+	} else {
+		a.dispatchLocked(sessionID)
+	}
+	return run.events
+}
+
+// dispatchLocked starts the oldest queued prompt of the session, if any. The
+// caller must hold queueMu.
+func (a *agent) dispatchLocked(sessionID string) {
+	queue := a.queues[sessionID]
+	if len(queue) == 0 {
+		return
+	}
+	next := queue[0]
+	if len(queue) == 1 {
+		delete(a.queues, sessionID)
+	} else {
+		a.queues[sessionID] = queue[1:]
+	}
+	a.publishQueueLocked(sessionID)
+
+	prompt := next.prompt
+	_, err := a.startLocked(next.ctx, sessionID, next.events, a.promptProcess(sessionID, prompt.Content, prompt.Attachments))
+	if err != nil {
+		next.events <- a.err(err)
+		close(next.events)
+	}
+}
+
+func (a *agent) publishQueueLocked(sessionID string) {
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:      AgentEventTypeQueue,
+		SessionID: sessionID,
+		Queue:     a.queueLocked(sessionID),
+	})
+}
+
+func (a *agent) queueLocked(sessionID string) []QueuedPrompt {
+	prompts := make([]QueuedPrompt, 0, len(a.queues[sessionID]))
+	for _, run := range a.queues[sessionID] {
+		prompts = append(prompts, run.prompt)
+	}
+	return prompts
+}
+
+// Queue returns the prompts waiting to be sent in the session, oldest first.
+func (a *agent) Queue(sessionID string) []QueuedPrompt {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	return a.queueLocked(sessionID)
+}
+
+// UpdateQueued replaces the content of a prompt that has not been sent yet.
+func (a *agent) UpdateQueued(sessionID, id, content string, attachments ...message.Attachment) error {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	for _, run := range a.queues[sessionID] {
+		if run.prompt.ID == id {
+			run.prompt.Content = content
+			run.prompt.Attachments = attachments
+			a.publishQueueLocked(sessionID)
+			return nil
+		}
+	}
+	return fmt.Errorf("queued prompt %s not found", id)
+}
+
+// RemoveQueued drops a prompt that has not been sent yet. Anyone waiting on
+// its result receives ErrRequestCancelled.
+func (a *agent) RemoveQueued(sessionID, id string) error {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	queue := a.queues[sessionID]
+	for i, run := range queue {
+		if run.prompt.ID != id {
+			continue
+		}
+		a.queues[sessionID] = append(queue[:i:i], queue[i+1:]...)
+		if len(a.queues[sessionID]) == 0 {
+			delete(a.queues, sessionID)
+		}
+		a.publishQueueLocked(sessionID)
+		run.events <- a.err(ErrRequestCancelled)
+		close(run.events)
+		return nil
+	}
+	return fmt.Errorf("queued prompt %s not found", id)
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+var ErrSessionNotBusy = errors.New("session has no request in progress")
+
+// Steer submits a note to the request running in the session. The note is
+// added to the conversation as a user message before the next model call,
+// after the results of the tool calls in progress, so the agent can change
+// course without the turn being cancelled.
+func (a *agent) Steer(sessionID, note string) error {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return fmt.Errorf("steering note is empty")
+	}
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	if !a.IsSessionBusy(sessionID) {
+		return ErrSessionNotBusy
+	}
+	a.steering[sessionID] = append(a.steering[sessionID], note)
+	return nil
+}
+
+// takeSteering stores the pending steering notes of the session as user
+// messages and returns them in submission order.
+func (a *agent) takeSteering(ctx context.Context, sessionID string) ([]message.Message, error) {
+	a.queueMu.Lock()
+	notes := a.steering[sessionID]
+	delete(a.steering, sessionID)
+	a.queueMu.Unlock()
+
+	msgs := make([]message.Message, 0, len(notes))
+	for _, note := range notes {
+		msg, err := a.createUserMessage(ctx, sessionID, note, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create steering message: %w", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// requeueSteeringLocked moves notes that arrived too late to be picked up by
+// the finished request to the front of the prompt queue so they are not lost.
+// The caller must hold queueMu.
+func (a *agent) requeueSteeringLocked(sessionID string) {
+	notes := a.steering[sessionID]
+	if len(notes) == 0 {
+		return
+	}
+	delete(a.steering, sessionID)
+	logging.Debug("Requeueing late steering notes", "sessionID", sessionID, "count", len(notes))
+	runs := make([]*queuedRun, 0, len(notes)+len(a.queues[sessionID]))
+	for _, note := range notes {
+		runs = append(runs, newQueuedRun(context.Background(), sessionID, note, nil))
+	}
+	a.queues[sessionID] = append(runs, a.queues[sessionID]...)
+	a.publishQueueLocked(sessionID)
+}
+
+// interrupted reports whether a turn that finished with reason was cut short
+// by an error, a cancellation or a denied permission rather than completed.
+func interrupted(reason message.FinishReason) bool {
+	switch reason {
+	case message.FinishReasonError, message.FinishReasonCanceled, message.FinishReasonPermissionDenied:
+		return true
+	}
+	return false
+}
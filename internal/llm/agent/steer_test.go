@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/provider"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/permission"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolCallProvider asks for a write on every request.
+type toolCallProvider struct {
+	fakeProvider
+	requests *atomic.Int32
+}
+
+func (p toolCallProvider) StreamResponse(context.Context, []message.Message, []tools.BaseTool) <-chan provider.ProviderEvent {
+	p.requests.Add(1)
+	events := make(chan provider.ProviderEvent, 1)
+	events <- provider.ProviderEvent{
+		Type: provider.EventComplete,
+		Response: &provider.ProviderResponse{
+			ToolCalls:    []message.ToolCall{{ID: "call_1", Name: "write", Input: "{}", Finished: true}},
+			FinishReason: message.FinishReasonToolUse,
+		},
+	}
+	close(events)
+	return events
+}
+
+// steeredTool receives a steering note while it waits for a permission the
+// user denies.
+type steeredTool struct {
+	agent *agent
+}
+
+func (steeredTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "write"}
+}
+
+func (t steeredTool) Run(ctx context.Context, _ tools.ToolCall) (tools.ToolResponse, error) {
+	sessionID, _ := ctx.Value(tools.SessionIDContextKey).(string)
+	if err := t.agent.Steer(sessionID, "use another file"); err != nil {
+		return tools.ToolResponse{}, err
+	}
+	return tools.ToolResponse{}, permission.ErrorPermissionDenied
+}
+
+func TestSteerDuringDeniedPermission(t *testing.T) {
+	ctx := context.Background()
+	a := newBudgetAgent(t, config.BudgetConfig{})
+	var requests atomic.Int32
+	a.Broker = pubsub.NewBroker[AgentEvent]()
+	a.provider = toolCallProvider{fakeProvider{models.Model{ID: "primary"}}, &requests}
+	a.tools = []tools.BaseTool{steeredTool{a}}
+	a.queues = make(map[string][]*queuedRun)
+	a.steering = make(map[string][]string)
+	sess, err := a.sessions.Create(ctx, "session")
+	require.NoError(t, err)
+
+	events, err := a.Run(ctx, sess.ID, "write the file")
+	require.NoError(t, err)
+	result := <-events
+	require.NoError(t, result.Error)
+
+	// The run stops at the denied tool call instead of answering the note
+	assert.Equal(t, message.FinishReasonPermissionDenied, result.Message.FinishReason())
+	assert.Equal(t, int32(1), requests.Load())
+	msgs, err := a.messages.List(ctx, sess.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, message.Tool, msgs[2].Role)
+
+	// The note waits in the queue for the next run
+	queue := a.Queue(sess.ID)
+	require.Len(t, queue, 1)
+	assert.Equal(t, "use another file", queue[0].Content)
+	assert.False(t, a.IsSessionBusy(sess.ID))
+}
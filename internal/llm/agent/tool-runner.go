@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/hooks"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/permission"
+)
+
+// maxConcurrentToolCalls bounds how many concurrent tool calls of a single
+// message run at the same time.
+const maxConcurrentToolCalls = 4
+
+type preparedToolCall struct {
+	tool tools.BaseTool
+	call tools.ToolCall
+	// result is set when the call failed before it could run
+	result *message.ToolResult
+}
+
+func (p preparedToolCall) concurrent() bool {
+	return p.result != nil || tools.IsConcurrent(p.tool, p.call)
+}
+
+// runToolCalls executes the tool calls of an assistant message and returns
+// their results in call order. Consecutive calls to concurrent tools run in
+// parallel, everything else runs one at a time. Cancellation and a denied
+// permission stop the remaining calls and are reported through the returned
+// finish reason, which is empty otherwise.
+func (a *agent) runToolCalls(ctx context.Context, toolCalls []message.ToolCall) ([]message.ToolResult, message.FinishReason) {
+	results := make([]message.ToolResult, len(toolCalls))
+	prepared := make([]preparedToolCall, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		prepared[i] = a.prepareToolCall(toolCall)
+	}
+
+	for start := 0; start < len(prepared); {
+		if ctx.Err() != nil {
+			cancelToolCalls(results, toolCalls, start)
+			return results, message.FinishReasonCanceled
+		}
+
+		end := start + 1
+		if prepared[start].concurrent() {
+			for end < len(prepared) && prepared[end].concurrent() {
+				end++
+			}
+		}
+
+		denied := make([]bool, end-start)
+		if end-start == 1 {
+			results[start], denied[0] = a.runToolCall(ctx, prepared[start])
+		} else {
+			var wg sync.WaitGroup
+			slots := make(chan struct{}, maxConcurrentToolCalls)
+			for i := start; i < end; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					defer logging.RecoverPanic("tool."+prepared[i].call.Name, func() {
+						results[i] = message.ToolResult{
+							ToolCallID: prepared[i].call.ID,
+							Content:    "Tool execution failed unexpectedly",
+							IsError:    true,
+						}
+					})
+					slots <- struct{}{}
+					defer func() { <-slots }()
+					results[i], denied[i-start] = a.runToolCall(ctx, prepared[i])
+				}(i)
+			}
+			wg.Wait()
+		}
+
+		for _, d := range denied {
+			if d {
+				cancelToolCalls(results, toolCalls, end)
+				return results, message.FinishReasonPermissionDenied
+			}
+		}
+		start = end
+	}
+	return results, ""
+}
+
+// prepareToolCall looks up the tool for a call and validates its input.
+func (a *agent) prepareToolCall(toolCall message.ToolCall) preparedToolCall {
+	var tool tools.BaseTool
+	for _, availableTool := range a.tools {
+		if availableTool.Info().Name == toolCall.Name {
+			tool = availableTool
+			break
+		}
+		// Handle tool name repetition (e.g., "writewritewrite" -> "write")
+		// Some models repeat tool names multiple times
+		toolName := availableTool.Info().Name
+		if len(toolCall.Name) >= len(toolName)*2 && strings.HasPrefix(toolCall.Name, toolName) {
+			// Check if the call name is the tool name repeated
+			repeated := true
+			for k := 0; k < len(toolCall.Name); k += len(toolName) {
+				if k+len(toolName) > len(toolCall.Name) {
+					if toolCall.Name[k:] != toolName[:len(toolCall.Name)-k] {
+						repeated = false
+						break
+					}
+				} else if toolCall.Name[k:k+len(toolName)] != toolName {
+					repeated = false
+					break
+				}
+			}
+			if repeated {
+				tool = availableTool
+				// Fix the tool name in the call
+				toolCall.Name = toolName
+				break
+			}
+		}
+	}
+
+	if tool == nil || !isValidToolName(toolCall.Name, a.tools) {
+		logging.Error("Invalid tool name", "name", toolCall.Name)
+		return preparedToolCall{
+			call: tools.ToolCall{ID: toolCall.ID, Name: toolCall.Name},
+			result: &message.ToolResult{
+				ToolCallID: toolCall.ID,
+				Content:    fmt.Sprintf("Tool not found: %s", toolCall.Name),
+				IsError:    true,
+			},
+		}
+	}
+	sanitizedInput, err := sanitizeToolInput(toolCall.Input)
+	if err != nil {
+		logging.Error("Param parse error", "error", err, "input", toolCall.Input)
+		return preparedToolCall{
+			call: tools.ToolCall{ID: toolCall.ID, Name: toolCall.Name},
+			result: &message.ToolResult{
+				ToolCallID: toolCall.ID,
+				Content:    fmt.Sprintf("error parsing parameters: %v", err),
+				IsError:    true,
+			},
+		}
+	}
+	return preparedToolCall{
+		tool: tool,
+		call: tools.ToolCall{
+			ID:    toolCall.ID,
+			Name:  toolCall.Name,
+			Input: sanitizedInput,
+		},
+	}
+}
+
+// runToolCall runs a single prepared call between the configured pre- and
+// post-tool-use hooks. It reports whether the user denied the permission the
+// tool asked for.
+func (a *agent) runToolCall(ctx context.Context, p preparedToolCall) (message.ToolResult, bool) {
+	if p.result != nil {
+		return *p.result, false
+	}
+	if ctx.Err() != nil {
+		return canceledToolResult(p.call.ID), false
+	}
+
+	sessionID, _ := tools.GetContextValues(ctx)
+	workingDir := tools.WorkingDirectory(ctx)
+	hookCall := hooks.ToolCall{ID: p.call.ID, Name: p.call.Name, Input: json.RawMessage(p.call.Input)}
+	input, blocked := hooks.PreToolUse(ctx, config.PreToolUseHooks(), workingDir, sessionID, hookCall)
+	if blocked != "" {
+		return message.ToolResult{
+			ToolCallID: p.call.ID,
+			Content:    "Tool call " + blocked,
+			IsError:    true,
+		}, false
+	}
+	p.call.Input = string(input)
+	hookCall.Input = input
+
+	toolResult, toolErr := p.tool.Run(ctx, p.call)
+	if toolErr != nil && errors.Is(toolErr, permission.ErrorPermissionDenied) {
+		return message.ToolResult{
+			ToolCallID: p.call.ID,
+			Content:    "Permission denied",
+			IsError:    true,
+		}, true
+	}
+	if toolResult.Type != tools.ToolResponseTypeImage {
+		toolResult.Content = tools.SpillOutput(sessionID, p.call.ID, toolResult.Content)
+	}
+	result := message.ToolResult{
+		ToolCallID: p.call.ID,
+		Content:    toolResult.Content,
+		Metadata:   toolResult.Metadata,
+		IsError:    toolResult.IsError,
+	}
+	feedback := hooks.PostToolUse(ctx, config.PostToolUseHooks(), workingDir, sessionID, hookCall, hooks.ToolResult{
+		Content: result.Content,
+		IsError: result.IsError,
+	})
+	if feedback != "" {
+		result.Content += "\n\n" + feedback
+	}
+	return result, false
+}
+
+func canceledToolResult(toolCallID string) message.ToolResult {
+	return message.ToolResult{
+		ToolCallID: toolCallID,
+		Content:    "Tool execution canceled by user",
+		IsError:    true,
+	}
+}
+
+// cancelToolCalls marks the calls from index from onwards as cancelled.
+func cancelToolCalls(results []message.ToolResult, toolCalls []message.ToolCall, from int) {
+	for j := from; j < len(toolCalls); j++ {
+		results[j] = canceledToolResult(toolCalls[j].ID)
+	}
+}
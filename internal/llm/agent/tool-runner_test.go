@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/permission"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTool struct {
+	name       string
+	concurrent bool
+	delay      time.Duration
+	err        error
+	running    *int32
+	maxRunning *int32
+}
+
+func (f *fakeTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: f.name}
+}
+
+func (f *fakeTool) Concurrent(tools.ToolCall) bool {
+	return f.concurrent
+}
+
+func (f *fakeTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	if f.running != nil {
+		n := atomic.AddInt32(f.running, 1)
+		defer atomic.AddInt32(f.running, -1)
+		for {
+			max := atomic.LoadInt32(f.maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(f.maxRunning, max, n) {
+				break
+			}
+		}
+	}
+	time.Sleep(f.delay)
+	if f.err != nil {
+		return tools.ToolResponse{}, f.err
+	}
+	return tools.NewTextResponse(call.ID), nil
+}
+
+func toolCalls(name string, ids ...string) []message.ToolCall {
+	calls := make([]message.ToolCall, 0, len(ids))
+	for _, id := range ids {
+		calls = append(calls, message.ToolCall{ID: id, Name: name, Input: "{}"})
+	}
+	return calls
+}
+
+func TestRunToolCalls_ParallelKeepsOrder(t *testing.T) {
+	var running, maxRunning int32
+	a := &agent{tools: []tools.BaseTool{&fakeTool{
+		name:       "view",
+		concurrent: true,
+		delay:      20 * time.Millisecond,
+		running:    &running,
+		maxRunning: &maxRunning,
+	}}}
+
+	ids := []string{"1", "2", "3", "4", "5", "6"}
+	results, reason := a.runToolCalls(context.Background(), toolCalls("view", ids...))
+
+	assert.Empty(t, reason)
+	for i, id := range ids {
+		assert.Equal(t, id, results[i].ToolCallID)
+		assert.Equal(t, id, results[i].Content)
+	}
+	assert.Greater(t, maxRunning, int32(1))
+	assert.LessOrEqual(t, maxRunning, int32(maxConcurrentToolCalls))
+}
+
+func TestRunToolCalls_SequentialTools(t *testing.T) {
+	var running, maxRunning int32
+	a := &agent{tools: []tools.BaseTool{&fakeTool{
+		name:       "write",
+		delay:      5 * time.Millisecond,
+		running:    &running,
+		maxRunning: &maxRunning,
+	}}}
+
+	results, reason := a.runToolCalls(context.Background(), toolCalls("write", "1", "2", "3"))
+
+	assert.Empty(t, reason)
+	assert.Len(t, results, 3)
+	assert.Equal(t, int32(1), maxRunning)
+}
+
+func TestRunToolCalls_PermissionDeniedCancelsRest(t *testing.T) {
+	a := &agent{tools: []tools.BaseTool{
+		&fakeTool{name: "view", concurrent: true},
+		&fakeTool{name: "write", err: permission.ErrorPermissionDenied},
+	}}
+	calls := append(toolCalls("view", "1"), toolCalls("write", "2")...)
+	calls = append(calls, toolCalls("view", "3", "4")...)
+
+	results, reason := a.runToolCalls(context.Background(), calls)
+
+	assert.Equal(t, message.FinishReasonPermissionDenied, reason)
+	assert.Equal(t, "1", results[0].Content)
+	assert.Equal(t, "Permission denied", results[1].Content)
+	assert.True(t, results[2].IsError)
+	assert.True(t, results[3].IsError)
+	assert.Equal(t, "4", results[3].ToolCallID)
+}
+
+func TestRunToolCalls_Cancelled(t *testing.T) {
+	a := &agent{tools: []tools.BaseTool{&fakeTool{name: "view", concurrent: true}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, reason := a.runToolCalls(ctx, toolCalls("view", "1", "2"))
+
+	assert.Equal(t, message.FinishReasonCanceled, reason)
+	for _, result := range results {
+		assert.True(t, result.IsError)
+	}
+}
@@ -2,9 +2,12 @@ package agent
 
 import (
 	"context"
+	"slices"
 
+	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/history"
 	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/lsp"
 	"github.com/opencode-ai/opencode/internal/message"
 	"github.com/opencode-ai/opencode/internal/permission"
@@ -19,7 +22,7 @@ func CoderAgentTools(
 	lspClients map[string]*lsp.Client,
 ) []tools.BaseTool {
 	ctx := context.Background()
-	otherTools := GetMcpTools(ctx, permissions)
+	otherTools := withPermissions(GetMcpTools(ctx, permissions), permissions)
 	if len(lspClients) > 0 {
 		otherTools = append(otherTools, tools.NewDiagnosticsTool(lspClients))
 	}
@@ -33,9 +36,10 @@ func CoderAgentTools(
 			tools.NewLsTool(),
 			tools.NewSourcegraphTool(),
 			tools.NewViewTool(lspClients),
+			tools.NewReadOutputTool(),
 			tools.NewPatchTool(lspClients, permissions, history),
 			tools.NewWriteTool(lspClients, permissions, history),
-			NewAgentTool(sessions, messages, lspClients),
+			NewAgentTool(permissions, sessions, messages, history, lspClients),
 		}, otherTools...,
 	)
 }
@@ -47,5 +51,86 @@ func TaskAgentTools(lspClients map[string]*lsp.Client) []tools.BaseTool {
 		tools.NewLsTool(),
 		tools.NewSourcegraphTool(),
 		tools.NewViewTool(lspClients),
+		tools.NewReadOutputTool(),
 	}
 }
+
+// AgentTools returns the tools of a primary agent. User-defined agents get the
+// coder tools they list, with permissions answered according to their mode,
+// and can always read the outputs that were too long to return.
+func AgentTools(
+	agentName config.AgentName,
+	permissions permission.Service,
+	sessions session.Service,
+	messages message.Service,
+	history history.Service,
+	lspClients map[string]*lsp.Client,
+) []tools.BaseTool {
+	if !config.IsCustomAgent(agentName) {
+		return CoderAgentTools(permissions, sessions, messages, history, lspClients)
+	}
+	agentCfg := config.Get().Agents[agentName]
+	all := CoderAgentTools(permission.WithMode(permissions, agentCfg.Permission), sessions, messages, history, lspClients)
+	if len(agentCfg.Tools) == 0 {
+		return all
+	}
+	allowed := make([]tools.BaseTool, 0, len(agentCfg.Tools))
+	for _, name := range agentCfg.Tools {
+		i := slices.IndexFunc(all, func(t tools.BaseTool) bool { return t.Info().Name == name })
+		if i == -1 {
+			logging.Warn("unknown tool configured for agent", "agent", agentName, "tool", name)
+			continue
+		}
+		allowed = append(allowed, all[i])
+	}
+	if !slices.Contains(agentCfg.Tools, tools.ReadOutputToolName) {
+		allowed = append(allowed, tools.NewReadOutputTool())
+	}
+	return allowed
+}
+
+// SubAgentTools returns the tools of an agent started through the agent tool.
+// The task agent is read-only and sub-agents can not start agents themselves.
+func SubAgentTools(
+	agentName config.AgentName,
+	permissions permission.Service,
+	sessions session.Service,
+	messages message.Service,
+	history history.Service,
+	lspClients map[string]*lsp.Client,
+) []tools.BaseTool {
+	if !config.IsCustomAgent(agentName) {
+		return TaskAgentTools(lspClients)
+	}
+	return slices.DeleteFunc(
+		AgentTools(agentName, permissions, sessions, messages, history, lspClients),
+		func(t tools.BaseTool) bool { return t.Info().Name == AgentToolName },
+	)
+}
+
+// WorktreeAgentTools returns the tools of a sub-agent working in a git
+// worktree. The task agent also gets the tools that modify files and run
+// commands. LSP clients are left out since they serve the main working tree.
+func WorktreeAgentTools(
+	agentName config.AgentName,
+	permissions permission.Service,
+	sessions session.Service,
+	messages message.Service,
+	history history.Service,
+) []tools.BaseTool {
+	if !config.IsCustomAgent(agentName) {
+		return []tools.BaseTool{
+			tools.NewBashTool(permissions),
+			tools.NewEditTool(nil, permissions, history),
+			tools.NewGlobTool(),
+			tools.NewGrepTool(),
+			tools.NewLsTool(),
+			tools.NewSourcegraphTool(),
+			tools.NewViewTool(nil),
+			tools.NewReadOutputTool(),
+			tools.NewPatchTool(nil, permissions, history),
+			tools.NewWriteTool(nil, permissions, history),
+		}
+	}
+	return SubAgentTools(agentName, permissions, sessions, messages, history, nil)
+}
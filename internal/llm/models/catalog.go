@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Catalog is a list of models fetched from the model catalogs of providers,
+// cached between runs.
+type Catalog struct {
+	UpdatedAt int64   `json:"updated_at"`
+	Models    []Model `json:"models"`
+}
+
+// Stale reports whether the catalog was fetched longer than maxAge ago.
+func (c Catalog) Stale(maxAge time.Duration) bool {
+	return time.Since(time.Unix(c.UpdatedAt, 0)) > maxAge
+}
+
+// ReadCatalog reads the catalog cached at path.
+func ReadCatalog(path string) (Catalog, error) {
+	var catalog Catalog
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return catalog, err
+	}
+	err = json.Unmarshal(data, &catalog)
+	return catalog, err
+}
+
+// WriteCatalog caches the catalog at path.
+func WriteCatalog(path string, catalog Catalog) error {
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a reader never sees half a catalog
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// MergeCatalog merges the models of a catalog into the supported models and
+// returns how many were added. A built-in model of the same provider and API
+// model keeps its ID and settings but takes the pricing and the context
+// window of the catalog.
+func MergeCatalog(catalog []Model) int {
+	builtins := make(map[ModelProvider]map[string]ModelID)
+	for id, model := range SupportedModels {
+		if builtins[model.Provider] == nil {
+			builtins[model.Provider] = make(map[string]ModelID)
+		}
+		builtins[model.Provider][model.APIModel] = id
+	}
+
+	added := 0
+	for _, entry := range catalog {
+		id, builtin := builtins[entry.Provider][entry.APIModel]
+		if !builtin {
+			if _, exists := SupportedModels[entry.ID]; exists || entry.ContextWindow <= 0 {
+				continue
+			}
+			SupportedModels[entry.ID] = entry
+			added++
+			continue
+		}
+
+		model := SupportedModels[id]
+		if entry.CostPer1MIn > 0 || entry.CostPer1MOut > 0 {
+			model.CostPer1MIn = entry.CostPer1MIn
+			model.CostPer1MOut = entry.CostPer1MOut
+			model.CostPer1MInCached = entry.CostPer1MInCached
+			model.CostPer1MOutCached = entry.CostPer1MOutCached
+		}
+		if entry.ContextWindow > 0 {
+			model.ContextWindow = entry.ContextWindow
+		}
+		SupportedModels[id] = model
+	}
+	return added
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeCatalog(t *testing.T) {
+	builtin := SupportedModels[OpenRouterGPT41]
+	t.Cleanup(func() {
+		SupportedModels[OpenRouterGPT41] = builtin
+		delete(SupportedModels, "openrouter.acme/small")
+	})
+
+	added := MergeCatalog([]Model{
+		{
+			ID:            "openrouter.openai/gpt-4.1",
+			Name:          "OpenRouter – OpenAI: GPT-4.1",
+			Provider:      ProviderOpenRouter,
+			APIModel:      "openai/gpt-4.1",
+			CostPer1MIn:   1.5,
+			CostPer1MOut:  6,
+			ContextWindow: 1047576,
+		},
+		{
+			ID:            "openrouter.acme/small",
+			Provider:      ProviderOpenRouter,
+			APIModel:      "acme/small",
+			ContextWindow: 8192,
+		},
+		{ID: "openrouter.acme/broken", Provider: ProviderOpenRouter, APIModel: "acme/broken"},
+	})
+	assert.Equal(t, 1, added)
+
+	// The built-in model keeps its ID and name but takes the new pricing
+	merged := SupportedModels[OpenRouterGPT41]
+	assert.Equal(t, builtin.Name, merged.Name)
+	assert.Equal(t, 1.5, merged.CostPer1MIn)
+	assert.Equal(t, 6.0, merged.CostPer1MOut)
+	assert.Equal(t, int64(1047576), merged.ContextWindow)
+	assert.NotContains(t, SupportedModels, ModelID("openrouter.openai/gpt-4.1"))
+
+	assert.Contains(t, SupportedModels, ModelID("openrouter.acme/small"))
+	assert.NotContains(t, SupportedModels, ModelID("openrouter.acme/broken"))
+}
+
+func TestCatalogCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "models.json")
+	_, err := ReadCatalog(path)
+	assert.Error(t, err)
+
+	catalog := Catalog{
+		UpdatedAt: time.Now().Add(-2 * time.Hour).Unix(),
+		Models:    []Model{{ID: "copilot.gpt-5", Provider: ProviderCopilot, APIModel: "gpt-5", ContextWindow: 128000}},
+	}
+	require.NoError(t, WriteCatalog(path, catalog))
+	cached, err := ReadCatalog(path)
+	require.NoError(t, err)
+	assert.Equal(t, catalog, cached)
+	assert.False(t, cached.Stale(24*time.Hour))
+	assert.True(t, cached.Stale(time.Hour))
+}
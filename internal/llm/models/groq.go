@@ -22,8 +22,8 @@ var GroqModels = map[ModelID]Model{
 		Provider:           ProviderGROQ,
 		APIModel:           "qwen-qwq-32b",
 		CostPer1MIn:        0.29,
-		CostPer1MInCached:  0.275,
-		CostPer1MOutCached: 0.0,
+		CostPer1MInCached:  0.0,
+		CostPer1MOutCached: 0.275,
 		CostPer1MOut:       0.39,
 		ContextWindow:      128_000,
 		DefaultMaxTokens:   50000,
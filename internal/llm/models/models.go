@@ -20,8 +20,22 @@ type Model struct {
 	DefaultMaxTokens    int64         `json:"default_max_tokens"`
 	CanReason           bool          `json:"can_reason"`
 	SupportsAttachments bool          `json:"supports_attachments"`
+	// API is the API of an OpenAI-compatible provider the model is called
+	// through, the Chat Completions API when empty.
+	API ModelAPI `json:"api,omitempty"`
+	// ChainResponses continues conversations from the previous response
+	// stored by the Responses API instead of sending the whole conversation.
+	ChainResponses bool `json:"chain_responses,omitempty"`
 }
 
+// ModelAPI is an API of OpenAI-compatible providers.
+type ModelAPI string
+
+const (
+	APIChatCompletions ModelAPI = "chat"
+	APIResponses       ModelAPI = "responses"
+)
+
 // Model IDs
 const ( // GEMINI
 	// Bedrock
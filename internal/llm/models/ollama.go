@@ -0,0 +1,203 @@
+package models
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/spf13/viper"
+)
+
+const (
+	ProviderOllama ModelProvider = "ollama"
+
+	ollamaDefaultPort        = "11434"
+	ollamaDefaultContext     = 4096
+	ollamaMaxContext         = 32768
+	ollamaDefaultMaxTokens   = 8192
+	ollamaDiscoveryTimeout   = 5 * time.Second
+	ollamaCapabilityChat     = "completion"
+	ollamaCapabilityTools    = "tools"
+	ollamaCapabilityVision   = "vision"
+	ollamaCapabilityThinking = "thinking"
+)
+
+// ollamaTools tells by model ID whether a discovered Ollama model can call
+// tools.
+var ollamaTools = map[ModelID]bool{}
+
+func init() {
+	// Like the client, look for a local server when OLLAMA_HOST is not set
+	host := cmp.Or(os.Getenv("OLLAMA_HOST"), "localhost")
+	if err := LoadOllamaModels(OllamaURL(host)); err != nil {
+		logging.Debug("Failed to load Ollama models",
+			"error", err,
+			"host", host,
+		)
+	}
+}
+
+// OllamaURL returns the base URL of the Ollama server at host, read the way
+// Ollama reads OLLAMA_HOST: without a scheme the port defaults to the one of
+// Ollama, with one to the one of the scheme.
+func OllamaURL(host string) string {
+	host = strings.TrimRight(host, "/")
+	scheme, port := "http", ollamaDefaultPort
+	if before, after, ok := strings.Cut(host, "://"); ok {
+		scheme, host, port = before, after, "80"
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	return scheme + "://" + host
+}
+
+// OllamaSupportsTools reports whether an Ollama model can call tools. Models
+// that were not discovered are assumed to.
+func OllamaSupportsTools(id ModelID) bool {
+	supported, known := ollamaTools[id]
+	return supported || !known
+}
+
+type ollamaTagList struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+type ollamaModelInfo struct {
+	Parameters   string         `json:"parameters"`
+	ModelInfo    map[string]any `json:"model_info"`
+	Capabilities []string       `json:"capabilities"`
+}
+
+// LoadOllamaModels adds the chat models of the Ollama server at baseURL to
+// the supported models, with the context length and the capabilities the
+// server reports for each.
+func LoadOllamaModels(baseURL string) error {
+	client := &http.Client{Timeout: ollamaDiscoveryTimeout}
+
+	var tags ollamaTagList
+	if err := ollamaRequest(client, http.MethodGet, baseURL+"/api/tags", nil, &tags); err != nil {
+		return err
+	}
+
+	loaded := 0
+	for _, tag := range tags.Models {
+		var info ollamaModelInfo
+		if err := ollamaRequest(client, http.MethodPost, baseURL+"/api/show", map[string]string{"model": tag.Name}, &info); err != nil {
+			logging.Debug("Failed to show Ollama model",
+				"error", err,
+				"model", tag.Name,
+			)
+			continue
+		}
+		// Older servers do not report capabilities
+		if len(info.Capabilities) > 0 && !slices.Contains(info.Capabilities, ollamaCapabilityChat) {
+			logging.Debug("Skipping Ollama model that can not chat",
+				"model", tag.Name,
+				"capabilities", info.Capabilities,
+			)
+			continue
+		}
+
+		model := convertOllamaModel(tag.Name, info)
+		SupportedModels[model.ID] = model
+		ollamaTools[model.ID] = len(info.Capabilities) == 0 || slices.Contains(info.Capabilities, ollamaCapabilityTools)
+
+		// The models are listed from the most recently used
+		if loaded == 0 {
+			viper.SetDefault("agents.coder.model", model.ID)
+			viper.SetDefault("agents.summarizer.model", model.ID)
+			viper.SetDefault("agents.task.model", model.ID)
+			viper.SetDefault("agents.title.model", model.ID)
+		}
+		loaded++
+	}
+	if loaded == 0 {
+		return fmt.Errorf("no chat models found at %s", baseURL)
+	}
+
+	viper.SetDefault("providers.ollama.apiKey", "ollama")
+	ProviderPopularity[ProviderOllama] = 0
+	return nil
+}
+
+func convertOllamaModel(name string, info ollamaModelInfo) Model {
+	contextWindow := min(ollamaContextLength(info), ollamaContextLimit())
+	return Model{
+		ID:                  ModelID("ollama." + name),
+		Name:                friendlyModelName(strings.TrimSuffix(name, ":latest")),
+		Provider:            ProviderOllama,
+		APIModel:            name,
+		ContextWindow:       contextWindow,
+		DefaultMaxTokens:    min(contextWindow/2, ollamaDefaultMaxTokens),
+		CanReason:           slices.Contains(info.Capabilities, ollamaCapabilityThinking),
+		SupportsAttachments: slices.Contains(info.Capabilities, ollamaCapabilityVision),
+	}
+}
+
+// ollamaContextLength returns the context length the model was trained for,
+// or the num_ctx of its parameters when the server does not tell it.
+func ollamaContextLength(info ollamaModelInfo) int64 {
+	if arch, ok := info.ModelInfo["general.architecture"].(string); ok {
+		if length, ok := info.ModelInfo[arch+".context_length"].(float64); ok && length > 0 {
+			return int64(length)
+		}
+	}
+	for _, line := range strings.Split(info.Parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			if length, err := strconv.ParseInt(fields[1], 10, 64); err == nil && length > 0 {
+				return length
+			}
+		}
+	}
+	return ollamaDefaultContext
+}
+
+// ollamaContextLimit returns the largest context window used for Ollama
+// models. Requests set num_ctx to the context window and Ollama allocates
+// memory for all of it, so the trained length of large models would rarely
+// fit. OLLAMA_CONTEXT_LENGTH raises or lowers the limit.
+func ollamaContextLimit() int64 {
+	if limit, err := strconv.ParseInt(os.Getenv("OLLAMA_CONTEXT_LENGTH"), 10, 64); err == nil && limit > 0 {
+		return limit
+	}
+	return ollamaMaxContext
+}
+
+func ollamaRequest(client *http.Client, method, url string, body any, result any) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, url, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, url, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
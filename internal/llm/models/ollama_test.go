@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaURL(t *testing.T) {
+	for host, want := range map[string]string{
+		"localhost":               "http://localhost:11434",
+		"0.0.0.0:8080":            "http://0.0.0.0:8080",
+		"https://ollama.example/": "https://ollama.example:443",
+		"http://gpu-box:11434":    "http://gpu-box:11434",
+		"[::1]":                   "http://[::1]:11434",
+	} {
+		assert.Equal(t, want, OllamaURL(host), host)
+	}
+}
+
+func TestLoadOllamaModels(t *testing.T) {
+	shows := map[string]string{
+		"qwen3:32b":               `{"parameters":"num_ctx 8192","model_info":{"general.architecture":"qwen3","qwen3.context_length":40960},"capabilities":["completion","tools","thinking"]}`,
+		"llava:latest":            `{"parameters":"num_ctx 4096\nstop \"</s>\"","capabilities":["completion","vision"]}`,
+		"nomic-embed-text:latest": `{"model_info":{"general.architecture":"nomic-bert","nomic-bert.context_length":2048},"capabilities":["embedding"]}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			fmt.Fprint(w, `{"models":[{"name":"qwen3:32b"},{"name":"llava:latest"},{"name":"nomic-embed-text:latest"},{"name":"gone:latest"}]}`)
+		case "/api/show":
+			var body struct {
+				Model string `json:"model"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			show, ok := shows[body.Model]
+			if !ok {
+				http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, show)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	t.Cleanup(func() {
+		for _, id := range []ModelID{"ollama.qwen3:32b", "ollama.llava:latest"} {
+			delete(SupportedModels, id)
+			delete(ollamaTools, id)
+		}
+		delete(ProviderPopularity, ProviderOllama)
+	})
+	require.NoError(t, LoadOllamaModels(ts.URL))
+
+	qwen := SupportedModels["ollama.qwen3:32b"]
+	assert.Equal(t, ProviderOllama, qwen.Provider)
+	assert.Equal(t, "qwen3:32b", qwen.APIModel)
+	assert.Equal(t, int64(32768), qwen.ContextWindow)
+	assert.Equal(t, int64(8192), qwen.DefaultMaxTokens)
+	assert.True(t, qwen.CanReason)
+	assert.False(t, qwen.SupportsAttachments)
+	assert.True(t, OllamaSupportsTools(qwen.ID))
+
+	llava := SupportedModels["ollama.llava:latest"]
+	assert.Equal(t, int64(4096), llava.ContextWindow)
+	assert.Equal(t, int64(2048), llava.DefaultMaxTokens)
+	assert.False(t, llava.CanReason)
+	assert.True(t, llava.SupportsAttachments)
+	assert.False(t, OllamaSupportsTools(llava.ID))
+
+	assert.NotContains(t, SupportedModels, ModelID("ollama.nomic-embed-text:latest"))
+	assert.NotContains(t, SupportedModels, ModelID("ollama.gone:latest"))
+	assert.Contains(t, ProviderPopularity, ProviderOllama)
+}
+
+func TestLoadOllamaModelsUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+	assert.Error(t, LoadOllamaModels(ts.URL))
+	assert.NotContains(t, ProviderPopularity, ProviderOllama)
+}
+
+func TestOllamaContextLimit(t *testing.T) {
+	info := ollamaModelInfo{ModelInfo: map[string]any{"general.architecture": "llama", "llama.context_length": float64(131072)}}
+	assert.Equal(t, int64(ollamaMaxContext), convertOllamaModel("llama3.1", info).ContextWindow)
+
+	t.Setenv("OLLAMA_CONTEXT_LENGTH", "65536")
+	assert.Equal(t, int64(65536), convertOllamaModel("llama3.1", info).ContextWindow)
+	t.Setenv("OLLAMA_CONTEXT_LENGTH", "262144")
+	assert.Equal(t, int64(131072), convertOllamaModel("llama3.1", info).ContextWindow)
+}
@@ -20,6 +20,7 @@ var VertexAIGeminiModels = map[ModelID]Model{
 		CostPer1MOutCached:  GeminiModels[Gemini25Flash].CostPer1MOutCached,
 		ContextWindow:       GeminiModels[Gemini25Flash].ContextWindow,
 		DefaultMaxTokens:    GeminiModels[Gemini25Flash].DefaultMaxTokens,
+		CanReason:           true,
 		SupportsAttachments: true,
 	},
 	VertexAIGemini25: {
@@ -33,6 +34,7 @@ var VertexAIGeminiModels = map[ModelID]Model{
 		CostPer1MOutCached:  GeminiModels[Gemini25].CostPer1MOutCached,
 		ContextWindow:       GeminiModels[Gemini25].ContextWindow,
 		DefaultMaxTokens:    GeminiModels[Gemini25].DefaultMaxTokens,
+		CanReason:           true,
 		SupportsAttachments: true,
 	},
 }
@@ -42,9 +42,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-4-0709", // Use full name as primary
 		CostPer1MIn:         3.0,
-		CostPer1MInCached:   0.75,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        15.0,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  0.75,
 		ContextWindow:       256_000, // 256k context window
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -56,9 +56,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-4-0709",
 		CostPer1MIn:         3.0,
-		CostPer1MInCached:   0.75,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        15.0,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  0.75,
 		ContextWindow:       256_000,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -72,9 +72,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-3",
 		CostPer1MIn:         3.0,
-		CostPer1MInCached:   0.75,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        15.0,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  0.75,
 		ContextWindow:       131_072,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -86,9 +86,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-3-beta",
 		CostPer1MIn:         3.0,
-		CostPer1MInCached:   0.75,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        15.0,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  0.75,
 		ContextWindow:       131_072,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -102,9 +102,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-3-mini",
 		CostPer1MIn:         0.30,
-		CostPer1MInCached:   0.075,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        0.50,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  0.075,
 		ContextWindow:       131_072,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -116,9 +116,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-3-mini-beta",
 		CostPer1MIn:         0.30,
-		CostPer1MInCached:   0.075,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        0.50,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  0.075,
 		ContextWindow:       131_072,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -132,9 +132,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-3-fast",
 		CostPer1MIn:         5.0, // Higher cost for faster response
-		CostPer1MInCached:   1.25,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        25.0,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  1.25,
 		ContextWindow:       131_072,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -146,9 +146,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-3-fast-beta",
 		CostPer1MIn:         5.0,
-		CostPer1MInCached:   1.25,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        25.0,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  1.25,
 		ContextWindow:       131_072,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -160,9 +160,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-3-mini-fast",
 		CostPer1MIn:         0.60,
-		CostPer1MInCached:   0.15,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        4.0,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  0.15,
 		ContextWindow:       131_072,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
@@ -174,9 +174,9 @@ var XAI2Models = map[ModelID]Model{
 		Provider:            ProviderXAI2,
 		APIModel:            "grok-3-mini-fast-beta",
 		CostPer1MIn:         0.60,
-		CostPer1MInCached:   0.15,
+		CostPer1MInCached:   0,
 		CostPer1MOut:        4.0,
-		CostPer1MOutCached:  0,
+		CostPer1MOutCached:  0.15,
 		ContextWindow:       131_072,
 		DefaultMaxTokens:    20_000,
 		CanReason:           true,
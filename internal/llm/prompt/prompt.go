@@ -25,9 +25,12 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 		basePrompt = SummarizerPrompt(provider)
 	default:
 		basePrompt = "You are a helpful assistant"
+		if agentCfg, ok := config.Get().Agents[agentName]; ok && agentCfg.Prompt != "" {
+			basePrompt = agentCfg.Prompt
+		}
 	}
 
-	if agentName == config.AgentCoder || agentName == config.AgentTask {
+	if agentName == config.AgentCoder || agentName == config.AgentTask || config.IsCustomAgent(agentName) {
 		// Add context from project-specific instruction files if they exist
 		contextContent := getContextFromPaths()
 		logging.Debug("Context content", "Context", contextContent)
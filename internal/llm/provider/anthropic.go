@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,11 +24,28 @@ import (
 )
 
 type anthropicOptions struct {
-	useBedrock   bool
-	disableCache bool
-	shouldThink  func(userMessage string) bool
+	useBedrock      bool
+	disableCache    bool
+	failOnOverload  bool
+	shouldThink     func(userMessage string) bool
+	reasoningEffort string
 }
 
+// anthropicThinkingBudgets are the thinking budgets by reasoning effort.
+var anthropicThinkingBudgets = map[string]int64{
+	"low":    4096,
+	"medium": 16384,
+	"high":   32768,
+}
+
+const (
+	// anthropicMinThinkingBudget is the smallest budget Anthropic accepts.
+	anthropicMinThinkingBudget = 1024
+	// anthropicInterleavedThinking lets Claude 4 models think between tool
+	// calls.
+	anthropicInterleavedThinking = "interleaved-thinking-2025-05-14"
+)
+
 type AnthropicOption func(*anthropicOptions)
 
 type anthropicClient struct {
@@ -44,7 +62,8 @@ func newAnthropicClient(opts providerClientOptions) AnthropicClient {
 		o(&anthropicOpts)
 	}
 
-	anthropicClientOptions := []option.RequestOption{}
+	// Retries are left to the retry policy of the provider package
+	anthropicClientOptions := []option.RequestOption{option.WithMaxRetries(0)}
 	if opts.apiKey != "" {
 		anthropicClientOptions = append(anthropicClientOptions, option.WithAPIKey(opts.apiKey))
 	}
@@ -52,6 +71,10 @@ func newAnthropicClient(opts providerClientOptions) AnthropicClient {
 		anthropicClientOptions = append(anthropicClientOptions, bedrock.WithLoadDefaultConfig(context.Background()))
 	}
 
+	if httpClient := cassetteClient(); httpClient != nil {
+		anthropicClientOptions = append(anthropicClientOptions, option.WithHTTPClient(httpClient))
+	}
+
 	client := anthropic.NewClient(anthropicClientOptions...)
 	return &anthropicClient{
 		providerOptions: opts,
@@ -60,20 +83,17 @@ func newAnthropicClient(opts providerClientOptions) AnthropicClient {
 	}
 }
 
-func (a *anthropicClient) convertMessages(messages []message.Message) (anthropicMessages []anthropic.MessageParam) {
+// cachePlan plans the caching of a request, Bedrock requests are not cached.
+func (a *anthropicClient) cachePlan(messages []message.Message, tools []toolsPkg.BaseTool) cachePlan {
+	return newCachePlan(messages, len(tools), a.options.disableCache)
+}
+
+func (a *anthropicClient) convertMessages(messages []message.Message, plan cachePlan) (anthropicMessages []anthropic.MessageParam) {
 	for i, msg := range messages {
-		cache := false
-		if i > len(messages)-3 {
-			cache = true
-		}
+		converted := len(anthropicMessages)
 		switch msg.Role {
 		case message.User:
 			content := anthropic.NewTextBlock(msg.Content().String())
-			if cache && !a.options.disableCache {
-				content.OfText.CacheControl = anthropic.CacheControlEphemeralParam{
-					Type: "ephemeral",
-				}
-			}
 			var contentBlocks []anthropic.ContentBlockParamUnion
 			contentBlocks = append(contentBlocks, content)
 			for _, binaryContent := range msg.BinaryContent() {
@@ -84,15 +104,15 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(contentBlocks...))
 
 		case message.Assistant:
-			blocks := []anthropic.ContentBlockParamUnion{}
+			// Thinking blocks are signed for the model that wrote them
+			var thinking []message.ThinkingBlock
+			if state, ok := msg.ProviderState(); ok && msg.Model == a.providerOptions.model.ID {
+				thinking = state.Thinking
+			}
+			blocks := thinkingBlocks(thinking, "")
+			numThinking := len(blocks)
 			if msg.Content().String() != "" {
-				content := anthropic.NewTextBlock(msg.Content().String())
-				if cache && !a.options.disableCache {
-					content.OfText.CacheControl = anthropic.CacheControlEphemeralParam{
-						Type: "ephemeral",
-					}
-				}
-				blocks = append(blocks, content)
+				blocks = append(blocks, anthropic.NewTextBlock(msg.Content().String()))
 			}
 
 			for _, toolCall := range msg.ToolCalls() {
@@ -101,10 +121,11 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 				if err != nil {
 					continue
 				}
+				blocks = append(blocks, thinkingBlocks(thinking, toolCall.ID)...)
 				blocks = append(blocks, anthropic.NewToolUseBlock(toolCall.ID, inputMap, toolCall.Name))
 			}
 
-			if len(blocks) == 0 {
+			if len(blocks) == numThinking {
 				logging.Warn("There is a message without content, investigate, this should not happen")
 				continue
 			}
@@ -117,11 +138,36 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 			}
 			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(results...))
 		}
+
+		// A breakpoint caches the prefix up to the last block of the message
+		if plan.breakpoint(i) && len(anthropicMessages) > converted {
+			content := anthropicMessages[len(anthropicMessages)-1].Content
+			if cacheControl := content[len(content)-1].GetCacheControl(); cacheControl != nil {
+				*cacheControl = anthropic.CacheControlEphemeralParam{Type: "ephemeral"}
+			}
+		}
 	}
 	return
 }
 
-func (a *anthropicClient) convertTools(tools []toolsPkg.BaseTool) []anthropic.ToolUnionParam {
+// thinkingBlocks returns the thinking blocks that go before the tool call
+// with toolCallID, or before the text when empty.
+func thinkingBlocks(thinking []message.ThinkingBlock, toolCallID string) []anthropic.ContentBlockParamUnion {
+	blocks := []anthropic.ContentBlockParamUnion{}
+	for _, block := range thinking {
+		if block.ToolCallID != toolCallID {
+			continue
+		}
+		if block.RedactedData != "" {
+			blocks = append(blocks, anthropic.NewRedactedThinkingBlock(block.RedactedData))
+		} else {
+			blocks = append(blocks, anthropic.NewThinkingBlock(block.Signature, block.Thinking))
+		}
+	}
+	return blocks
+}
+
+func (a *anthropicClient) convertTools(tools []toolsPkg.BaseTool, plan cachePlan) []anthropic.ToolUnionParam {
 	anthropicTools := make([]anthropic.ToolUnionParam, len(tools))
 
 	for i, tool := range tools {
@@ -135,7 +181,7 @@ func (a *anthropicClient) convertTools(tools []toolsPkg.BaseTool) []anthropic.To
 			},
 		}
 
-		if i == len(tools)-1 && !a.options.disableCache {
+		if i == len(tools)-1 && plan.Tools {
 			toolParam.CacheControl = anthropic.CacheControlEphemeralParam{
 				Type: "ephemeral",
 			}
@@ -162,13 +208,58 @@ func (a *anthropicClient) finishReason(reason string) message.FinishReason {
 	}
 }
 
-func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, tools []anthropic.ToolUnionParam) anthropic.MessageNewParams {
+// interleavedThinking reports whether the model can think between tool
+// calls, which lets the thinking budget exceed the max tokens.
+func (a *anthropicClient) interleavedThinking() bool {
+	apiModel := a.providerOptions.model.APIModel
+	return !a.options.useBedrock && (strings.HasPrefix(apiModel, "claude-sonnet-4") || strings.HasPrefix(apiModel, "claude-opus-4"))
+}
+
+// thinkingBudget returns the thinking budget of the reasoning effort, zero
+// when thinking is off or does not fit in the max tokens.
+func (a *anthropicClient) thinkingBudget() int64 {
+	if !a.providerOptions.model.CanReason {
+		return 0
+	}
+	budget := anthropicThinkingBudgets[strings.ToLower(a.options.reasoningEffort)]
+	if !a.interleavedThinking() {
+		budget = min(budget, int64(float64(a.providerOptions.maxTokens)*0.8))
+	}
+	if budget < anthropicMinThinkingBudget {
+		return 0
+	}
+	return budget
+}
+
+// continuesWithoutThinking reports whether the request continues a tool use
+// that was made without thinking, which can not go on with thinking.
+func continuesWithoutThinking(messages []anthropic.MessageParam) bool {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role == anthropic.MessageParamRoleAssistant {
+			return len(msg.Content) > 0 && msg.Content[0].OfThinking == nil && msg.Content[0].OfRedactedThinking == nil
+		}
+		if !slices.ContainsFunc(msg.Content, func(block anthropic.ContentBlockParamUnion) bool {
+			return block.OfToolResult != nil
+		}) {
+			return false
+		}
+	}
+	return false
+}
+
+func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, tools []anthropic.ToolUnionParam, plan cachePlan) anthropic.MessageNewParams {
 	var thinkingParam anthropic.ThinkingConfigParamUnion
 	lastMessage := messages[len(messages)-1]
 	isUser := lastMessage.Role == anthropic.MessageParamRoleUser
 	messageContent := ""
 	temperature := anthropic.Float(0)
-	if isUser {
+	if budget := a.thinkingBudget(); budget > 0 {
+		if !continuesWithoutThinking(messages) {
+			thinkingParam = anthropic.ThinkingConfigParamOfEnabled(budget)
+			temperature = anthropic.Float(1)
+		}
+	} else if isUser {
 		for _, m := range lastMessage.Content {
 			if m.OfText != nil && m.OfText.Text != "" {
 				messageContent = m.OfText.Text
@@ -180,6 +271,10 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 		}
 	}
 
+	system := anthropic.TextBlockParam{Text: a.providerOptions.systemMessage}
+	if plan.System {
+		system.CacheControl = anthropic.CacheControlEphemeralParam{Type: "ephemeral"}
+	}
 	return anthropic.MessageNewParams{
 		Model:       anthropic.Model(a.providerOptions.model.APIModel),
 		MaxTokens:   a.providerOptions.maxTokens,
@@ -187,15 +282,16 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 		Messages:    messages,
 		Tools:       tools,
 		Thinking:    thinkingParam,
-		System: []anthropic.TextBlockParam{
-			{
-				Text: a.providerOptions.systemMessage,
-				CacheControl: anthropic.CacheControlEphemeralParam{
-					Type: "ephemeral",
-				},
-			},
-		},
+		System:      []anthropic.TextBlockParam{system},
+	}
+}
+
+// requestOptions returns the options of a request with the prepared params.
+func (a *anthropicClient) requestOptions(params anthropic.MessageNewParams) []option.RequestOption {
+	if params.Thinking.OfEnabled != nil && a.interleavedThinking() {
+		return []option.RequestOption{option.WithHeaderAdd("anthropic-beta", anthropicInterleavedThinking)}
 	}
+	return nil
 }
 
 func (a *anthropicClient) send(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool) (resposne *ProviderResponse, err error) {
@@ -206,7 +302,8 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 	}
 	request.SetCurrent(string(a.providerOptions.model.Provider), a.providerOptions.model.APIModel, baseURL)
 
-	preparedMessages := a.preparedMessages(a.convertMessages(messages), a.convertTools(tools))
+	plan := a.cachePlan(messages, tools)
+	preparedMessages := a.preparedMessages(a.convertMessages(messages, plan), a.convertTools(tools, plan), plan)
 	cfg := config.Get()
 	if cfg.Debug {
 		jsonData, _ := json.Marshal(preparedMessages)
@@ -217,11 +314,13 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 	}
 
 	attempts := 0
+	retries := newRetryWait()
 	for {
 		attempts++
 		anthropicResponse, err := a.client.Messages.New(
 			ctx,
 			preparedMessages,
+			a.requestOptions(preparedMessages)...,
 		)
 		// If there is an error we are going to see if we can retry the call
 		if err != nil {
@@ -232,14 +331,11 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 				return nil, retryErr
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
+				if err := retries.wait(ctx, attempts, after, err, logRetry); err != nil {
 					request.Clear() // Clear request info on context cancellation
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
+					return nil, err
 				}
+				continue
 			}
 			request.Clear() // Clear request info on error
 			return nil, retryErr
@@ -257,6 +353,7 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 			Content:   content,
 			ToolCalls: a.toolCalls(*anthropicResponse),
 			Usage:     a.usage(*anthropicResponse),
+			State:     a.thinkingState(*anthropicResponse),
 		}, nil
 	}
 }
@@ -269,7 +366,8 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 	}
 	request.SetCurrent(string(a.providerOptions.model.Provider), a.providerOptions.model.APIModel, baseURL)
 
-	preparedMessages := a.preparedMessages(a.convertMessages(messages), a.convertTools(tools))
+	plan := a.cachePlan(messages, tools)
+	preparedMessages := a.preparedMessages(a.convertMessages(messages, plan), a.convertTools(tools, plan), plan)
 	cfg := config.Get()
 
 	var sessionId string
@@ -292,6 +390,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 
 	}
 	attempts := 0
+	retries := newRetryWait()
 	eventChan := make(chan ProviderEvent)
 	go func() {
 		for {
@@ -299,6 +398,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 			anthropicStream := a.client.Messages.NewStreaming(
 				ctx,
 				preparedMessages,
+				a.requestOptions(preparedMessages)...,
 			)
 			accumulatedMessage := anthropic.Message{}
 
@@ -378,6 +478,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 							ToolCalls:    a.toolCalls(accumulatedMessage),
 							Usage:        a.usage(accumulatedMessage),
 							FinishReason: a.finishReason(string(accumulatedMessage.StopReason)),
+							State:        a.thinkingState(accumulatedMessage),
 						},
 					}
 				}
@@ -398,19 +499,13 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 				return
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					// context cancelled
+				if err := retries.wait(ctx, attempts, after, err, streamRetry(ctx, eventChan)); err != nil {
 					request.Clear() // Clear request info on context cancellation
-					if ctx.Err() != nil {
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-					}
+					eventChan <- ProviderEvent{Type: EventError, Error: err}
 					close(eventChan)
 					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
 				}
+				continue
 			}
 			request.Clear() // Clear request info on error
 			if ctx.Err() != nil {
@@ -425,31 +520,10 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 }
 
 func (a *anthropicClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	var apierr *anthropic.Error
-	if !errors.As(err, &apierr) {
+	if status, _ := errorStatus(err); status == statusOverloaded && a.options.failOnOverload {
 		return false, 0, err
 	}
-
-	if apierr.StatusCode != 429 && apierr.StatusCode != 529 {
-		return false, 0, err
-	}
-
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-
-	retryMs := 0
-	retryAfterValues := apierr.Response.Header.Values("Retry-After")
-
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs = backoffMs + jitterMs
-	if len(retryAfterValues) > 0 {
-		if _, err := fmt.Sscanf(retryAfterValues[0], "%d", &retryMs); err == nil {
-			retryMs = retryMs * 1000
-		}
-	}
-	return true, int64(retryMs), nil
+	return shouldRetry(attempts, err)
 }
 
 func (a *anthropicClient) toolCalls(msg anthropic.Message) []message.ToolCall {
@@ -472,6 +546,34 @@ func (a *anthropicClient) toolCalls(msg anthropic.Message) []message.ToolCall {
 	return toolCalls
 }
 
+// thinkingState keeps the thinking blocks of a response to send them back
+// during tool use. Each goes before the tool call that follows it.
+func (a *anthropicClient) thinkingState(msg anthropic.Message) *message.ProviderState {
+	var thinking, pending []message.ThinkingBlock
+	for _, block := range msg.Content {
+		switch variant := block.AsAny().(type) {
+		case anthropic.ThinkingBlock:
+			pending = append(pending, message.ThinkingBlock{Thinking: variant.Thinking, Signature: variant.Signature})
+		case anthropic.RedactedThinkingBlock:
+			pending = append(pending, message.ThinkingBlock{RedactedData: variant.Data})
+		case anthropic.TextBlock:
+			thinking = append(thinking, pending...)
+			pending = nil
+		case anthropic.ToolUseBlock:
+			for _, p := range pending {
+				p.ToolCallID = variant.ID
+				thinking = append(thinking, p)
+			}
+			pending = nil
+		}
+	}
+	thinking = append(thinking, pending...)
+	if len(thinking) == 0 {
+		return nil
+	}
+	return &message.ProviderState{Thinking: thinking}
+}
+
 func (a *anthropicClient) usage(msg anthropic.Message) TokenUsage {
 	return TokenUsage{
 		InputTokens:         msg.Usage.InputTokens,
@@ -487,12 +589,28 @@ func WithAnthropicBedrock(useBedrock bool) AnthropicOption {
 	}
 }
 
+// WithAnthropicReasoningEffort turns on thinking with the budget of the
+// effort, low, medium or high.
+func WithAnthropicReasoningEffort(effort string) AnthropicOption {
+	return func(options *anthropicOptions) {
+		options.reasoningEffort = effort
+	}
+}
+
 func WithAnthropicDisableCache() AnthropicOption {
 	return func(options *anthropicOptions) {
 		options.disableCache = true
 	}
 }
 
+// WithAnthropicFailOnOverload gives up on overloaded errors instead of
+// retrying them, so that the caller can fall back to another model.
+func WithAnthropicFailOnOverload() AnthropicOption {
+	return func(options *anthropicOptions) {
+		options.failOnOverload = true
+	}
+}
+
 func DefaultShouldThinkFn(s string) bool {
 	return strings.Contains(strings.ToLower(s), "think")
 }
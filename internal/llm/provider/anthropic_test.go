@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	toolsPkg "github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicThinkingBudget(t *testing.T) {
+	client := &anthropicClient{
+		providerOptions: providerClientOptions{model: models.AnthropicModels[models.Claude37Sonnet], maxTokens: 50000},
+		options:         anthropicOptions{reasoningEffort: "high"},
+	}
+	assert.Equal(t, int64(32768), client.thinkingBudget())
+
+	// Without interleaved thinking the budget has to fit in the max tokens
+	client.providerOptions.maxTokens = 5000
+	assert.Equal(t, int64(4000), client.thinkingBudget())
+	client.providerOptions.maxTokens = 1000
+	assert.Zero(t, client.thinkingBudget())
+
+	client.providerOptions.model = models.AnthropicModels[models.Claude4Sonnet]
+	assert.True(t, client.interleavedThinking())
+	assert.Equal(t, int64(32768), client.thinkingBudget())
+
+	client.options.reasoningEffort = ""
+	assert.Zero(t, client.thinkingBudget())
+
+	client.options.reasoningEffort = "low"
+	client.providerOptions.model = models.AnthropicModels[models.Claude35Haiku]
+	assert.Zero(t, client.thinkingBudget())
+}
+
+func TestAnthropicThinkingAcrossToolUse(t *testing.T) {
+	client := &anthropicClient{
+		providerOptions: providerClientOptions{model: models.AnthropicModels[models.Claude4Sonnet], maxTokens: 8000},
+		options:         anthropicOptions{reasoningEffort: "medium", disableCache: true},
+	}
+	var response anthropic.Message
+	require.NoError(t, response.UnmarshalJSON([]byte(`{"content":[
+		{"type":"thinking","thinking":"Which file?","signature":"sig-1"},
+		{"type":"text","text":"Let me look."},
+		{"type":"thinking","thinking":"main.go it is.","signature":"sig-2"},
+		{"type":"tool_use","id":"call_1","name":"view","input":{"file_path":"main.go"}},
+		{"type":"redacted_thinking","data":"encrypted"},
+		{"type":"tool_use","id":"call_2","name":"view","input":{"file_path":"go.mod"}}
+	]}`)))
+	state := client.thinkingState(response)
+	require.NotNil(t, state)
+	assert.Equal(t, []message.ThinkingBlock{
+		{Thinking: "Which file?", Signature: "sig-1"},
+		{ToolCallID: "call_1", Thinking: "main.go it is.", Signature: "sig-2"},
+		{ToolCallID: "call_2", RedactedData: "encrypted"},
+	}, state.Thinking)
+
+	assistant := message.Message{
+		Role:  message.Assistant,
+		Model: models.Claude4Sonnet,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me look."},
+			message.ToolCall{ID: "call_1", Name: "view", Input: `{"file_path":"main.go"}`, Finished: true},
+			message.ToolCall{ID: "call_2", Name: "view", Input: `{"file_path":"go.mod"}`, Finished: true},
+		},
+	}
+	assistant.SetProviderState(*state)
+	results := message.Message{
+		Role: message.Tool,
+		Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "call_1", Content: "package main"},
+			message.ToolResult{ToolCallID: "call_2", Content: "module example"},
+		},
+	}
+	messages := append(append([]message.Message{}, helloMessages...), assistant, results)
+
+	plan := client.cachePlan(messages, nil)
+	converted := client.convertMessages(messages, plan)
+	require.Len(t, converted, 3)
+	blocks := converted[1].Content
+	require.Len(t, blocks, 6)
+	assert.Equal(t, "sig-1", blocks[0].OfThinking.Signature)
+	assert.Equal(t, "Let me look.", blocks[1].OfText.Text)
+	assert.Equal(t, "sig-2", blocks[2].OfThinking.Signature)
+	assert.Equal(t, "call_1", blocks[3].OfToolUse.ID)
+	assert.Equal(t, "encrypted", blocks[4].OfRedactedThinking.Data)
+	assert.Equal(t, "call_2", blocks[5].OfToolUse.ID)
+
+	params := client.preparedMessages(converted, nil, plan)
+	require.NotNil(t, params.Thinking.OfEnabled)
+	assert.Equal(t, int64(16384), params.Thinking.OfEnabled.BudgetTokens)
+	assert.Len(t, client.requestOptions(params), 1)
+
+	// Another model can not use the thinking blocks, so the tool use goes
+	// on without thinking
+	messages[1].Model = models.Claude37Sonnet
+	converted = client.convertMessages(messages, plan)
+	assert.Len(t, converted[1].Content, 3)
+	params = client.preparedMessages(converted, nil, plan)
+	assert.Nil(t, params.Thinking.OfEnabled)
+}
+
+func TestAnthropicCacheBreakpoints(t *testing.T) {
+	client := &anthropicClient{
+		providerOptions: providerClientOptions{model: models.AnthropicModels[models.Claude4Sonnet], maxTokens: 8000, systemMessage: "be brief"},
+	}
+	messages := []message.Message{
+		{Role: message.User, Summary: true, Parts: []message.ContentPart{message.TextContent{Text: "summary"}}},
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "read main.go"}}},
+		{
+			Role:  message.Assistant,
+			Parts: []message.ContentPart{message.ToolCall{ID: "call_1", Name: "view", Input: `{"file_path":"main.go"}`, Finished: true}},
+		},
+		{
+			Role:  message.Tool,
+			Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call_1", Name: "view", Content: "package main"}},
+		},
+	}
+	tools := []toolsPkg.BaseTool{viewTool{}, viewTool{}}
+
+	cached := func(block anthropic.ContentBlockParamUnion) bool {
+		return block.GetCacheControl().Type == "ephemeral"
+	}
+	plan := client.cachePlan(messages, tools)
+	converted := client.convertMessages(messages, plan)
+	require.Len(t, converted, 4)
+	assert.True(t, cached(converted[0].Content[0]), "summary")
+	assert.False(t, cached(converted[1].Content[0]))
+	assert.False(t, cached(converted[2].Content[0]))
+	assert.True(t, cached(converted[3].Content[0]), "tool results")
+
+	convertedTools := client.convertTools(tools, plan)
+	assert.Empty(t, convertedTools[0].OfTool.CacheControl.Type)
+	assert.Equal(t, "ephemeral", string(convertedTools[1].OfTool.CacheControl.Type))
+
+	params := client.preparedMessages(converted, convertedTools, plan)
+	assert.Equal(t, "ephemeral", string(params.System[0].CacheControl.Type))
+
+	// Disabling the cache marks nothing
+	client.options.disableCache = true
+	plan = client.cachePlan(messages, tools)
+	for _, msg := range client.convertMessages(messages, plan) {
+		for _, block := range msg.Content {
+			assert.False(t, cached(block))
+		}
+	}
+	params = client.preparedMessages(client.convertMessages(messages, plan), client.convertTools(tools, plan), plan)
+	assert.Empty(t, params.System[0].CacheControl.Type)
+	assert.Empty(t, params.Tools[1].OfTool.CacheControl.Type)
+}
@@ -26,6 +26,7 @@ func newAzureClient(opts providerClientOptions) AzureClient {
 
 	reqOpts := []option.RequestOption{
 		azure.WithEndpoint(endpoint, apiVersion),
+		option.WithMaxRetries(0),
 	}
 
 	if opts.apiKey != "" || os.Getenv("AZURE_OPENAI_API_KEY") != "" {
@@ -38,6 +39,10 @@ func newAzureClient(opts providerClientOptions) AzureClient {
 		reqOpts = append(reqOpts, azure.WithTokenCredential(cred))
 	}
 
+	if httpClient := cassetteClient(); httpClient != nil {
+		reqOpts = append(reqOpts, option.WithHTTPClient(httpClient))
+	}
+
 	base := &openaiClient{
 		providerOptions: opts,
 		client:          openai.NewClient(reqOpts...),
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"slices"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/message"
+)
+
+// cachePlan marks the stable prefixes of a request that providers can cache,
+// each translating it to its own caching mechanism.
+type cachePlan struct {
+	// System and Tools cache the system prompt and the tool definitions.
+	System bool
+	Tools  bool
+	// Summary is the index of the summary a compacted session continues
+	// from, -1 without one. Its prefix only changes on the next compaction.
+	Summary int
+	// Rolling is the index of the latest message, whose prefix the next
+	// request of the conversation starts with. It is -1 when disabled.
+	Rolling int
+}
+
+// newCachePlan plans the caching of a request with messages and tools. A
+// disabled plan marks nothing.
+func newCachePlan(messages []message.Message, tools int, disabled bool) cachePlan {
+	plan := cachePlan{Summary: -1, Rolling: -1}
+	if disabled {
+		return plan
+	}
+	plan.System = true
+	plan.Tools = tools > 0
+	plan.Summary = slices.IndexFunc(messages, func(msg message.Message) bool {
+		return msg.Summary
+	})
+	plan.Rolling = len(messages) - 1
+	return plan
+}
+
+// breakpoint reports whether the prefix ending with message i is cached.
+func (p cachePlan) breakpoint(i int) bool {
+	return i >= 0 && (i == p.Summary || i == p.Rolling)
+}
+
+// stablePrefix returns how many messages the stable part of the plan covers,
+// the ones up to the summary.
+func (p cachePlan) stablePrefix() int {
+	return p.Summary + 1
+}
+
+// Cost returns the cost of the usage with the prices of model. Tokens
+// written to the cache are priced at CostPer1MInCached, those read from it
+// at CostPer1MOutCached.
+func (u TokenUsage) Cost(model models.Model) float64 {
+	return model.CostPer1MInCached/1e6*float64(u.CacheCreationTokens) +
+		model.CostPer1MOutCached/1e6*float64(u.CacheReadTokens) +
+		model.CostPer1MIn/1e6*float64(u.InputTokens) +
+		model.CostPer1MOut/1e6*float64(u.OutputTokens)
+}
+
+// CacheHitRate returns the share of the input tokens read from the cache,
+// and false when nothing was read from or written to it.
+func (u TokenUsage) CacheHitRate() (float64, bool) {
+	cached := u.CacheReadTokens + u.CacheCreationTokens
+	if cached == 0 {
+		return 0, false
+	}
+	return float64(u.CacheReadTokens) / float64(u.InputTokens+cached), true
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachePlan(t *testing.T) {
+	messages := []message.Message{
+		{Role: message.User, Summary: true},
+		{Role: message.Assistant},
+		{Role: message.User},
+	}
+
+	plan := newCachePlan(messages, 2, false)
+	assert.Equal(t, cachePlan{System: true, Tools: true, Summary: 0, Rolling: 2}, plan)
+	assert.True(t, plan.breakpoint(0))
+	assert.False(t, plan.breakpoint(1))
+	assert.True(t, plan.breakpoint(2))
+	assert.Equal(t, 1, plan.stablePrefix())
+
+	plan = newCachePlan(messages[1:], 0, false)
+	assert.Equal(t, cachePlan{System: true, Summary: -1, Rolling: 1}, plan)
+	assert.Zero(t, plan.stablePrefix())
+
+	plan = newCachePlan(messages, 2, true)
+	assert.Equal(t, cachePlan{Summary: -1, Rolling: -1}, plan)
+	assert.False(t, plan.breakpoint(-1))
+}
+
+func TestTokenUsageCost(t *testing.T) {
+	model := models.Model{CostPer1MIn: 3, CostPer1MOut: 15, CostPer1MInCached: 3.75, CostPer1MOutCached: 0.3}
+	usage := TokenUsage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheCreationTokens: 200_000, CacheReadTokens: 2_000_000}
+	assert.InDelta(t, 3+1.5+0.75+0.6, usage.Cost(model), 1e-9)
+}
+
+func TestTokenUsageCacheHitRate(t *testing.T) {
+	_, ok := TokenUsage{InputTokens: 100, OutputTokens: 10}.CacheHitRate()
+	assert.False(t, ok)
+
+	rate, ok := TokenUsage{InputTokens: 100, CacheCreationTokens: 100, CacheReadTokens: 800}.CacheHitRate()
+	assert.True(t, ok)
+	assert.InDelta(t, 0.8, rate, 1e-9)
+
+	// Writing the cache is a miss
+	rate, ok = TokenUsage{InputTokens: 10, CacheCreationTokens: 990}.CacheHitRate()
+	assert.True(t, ok)
+	assert.Zero(t, rate)
+}
@@ -0,0 +1,320 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// redacted replaces secrets in recorded traffic.
+const redacted = "REDACTED"
+
+// secretHeaders carry credentials and are never recorded.
+var secretHeaders = []string{
+	"Authorization",
+	"Api-Key",
+	"X-Api-Key",
+	"X-Goog-Api-Key",
+	"Cookie",
+	"Set-Cookie",
+	"X-Amz-Security-Token",
+}
+
+// secretParams are query parameters that carry credentials.
+var secretParams = []string{"key", "api_key", "api-key"}
+
+// cassetteInteraction is one provider call of a cassette. Streamed responses
+// keep their raw SSE body.
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+type cassetteRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+type cassetteResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// cassetteTransport records the provider calls into a cassette, one JSON
+// interaction per line, or serves them from a recorded cassette instead of
+// calling the provider.
+type cassetteTransport struct {
+	mode    config.CassetteMode
+	path    string
+	next    http.RoundTripper
+	secrets []string
+
+	mu           sync.Mutex
+	interactions []cassetteInteraction
+	used         []bool
+}
+
+var (
+	cassettesMu sync.Mutex
+	cassettes   = map[string]*cassetteTransport{}
+)
+
+// CassettePath returns the path of the named cassette.
+func CassettePath(name string) string {
+	if filepath.Ext(name) == "" {
+		name += ".jsonl"
+	}
+	return filepath.Join(config.Get().Data.Directory, "cassettes", name)
+}
+
+// replayingCassette reports whether provider calls are served from a cassette.
+func replayingCassette() bool {
+	cfg := config.Get()
+	return cfg != nil && cfg.Cassette.Mode == config.CassetteReplay
+}
+
+// cassetteClient returns the HTTP client providers use while a cassette is
+// recorded or replayed, or nil when they call the provider as usual.
+func cassetteClient() *http.Client {
+	cfg := config.Get()
+	if cfg == nil || cfg.Cassette.Mode == "" {
+		return nil
+	}
+	path := CassettePath(cfg.Cassette.Name)
+	key := string(cfg.Cassette.Mode) + ":" + path
+
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+	transport, ok := cassettes[key]
+	if !ok {
+		var err error
+		transport, err = newCassetteTransport(cfg.Cassette.Mode, path)
+		if err != nil {
+			logging.ErrorPersist(fmt.Sprintf("Failed to open cassette: %v", err))
+			return nil
+		}
+		cassettes[key] = transport
+	}
+	return &http.Client{Transport: transport}
+}
+
+func newCassetteTransport(mode config.CassetteMode, path string) (*cassetteTransport, error) {
+	t := &cassetteTransport{mode: mode, path: path, next: http.DefaultTransport}
+	if mode == config.CassetteRecord {
+		for _, provider := range config.Get().Providers {
+			if len(provider.APIKey) >= 8 {
+				t.secrets = append(t.secrets, provider.APIKey)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		logging.Info("Recording provider calls", "cassette", path)
+		return t, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var interaction cassetteInteraction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		t.interactions = append(t.interactions, interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	t.used = make([]bool, len(t.interactions))
+	logging.Info("Replaying provider calls", "cassette", path, "interactions", len(t.interactions))
+	return t, nil
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := cassetteRequest{
+		Method: req.Method,
+		URL:    t.redactURL(req.URL.String()),
+		Header: t.redactHeader(req.Header),
+		Body:   t.redact(string(body)),
+	}
+	if t.mode == config.CassetteReplay {
+		return t.replay(req, recorded)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		save: func(body []byte) {
+			t.save(cassetteInteraction{
+				Request: recorded,
+				Response: cassetteResponse{
+					Status: resp.StatusCode,
+					Header: t.redactHeader(resp.Header),
+					Body:   t.redact(string(body)),
+				},
+			})
+		},
+	}
+	return resp, nil
+}
+
+// replay serves the first unused interaction with the same request, or with
+// the same method and URL when the request body changed since recording.
+func (t *cassetteTransport) replay(req *http.Request, recorded cassetteRequest) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	match := -1
+	for i, interaction := range t.interactions {
+		if t.used[i] || interaction.Request.Method != recorded.Method || interaction.Request.URL != recorded.URL {
+			continue
+		}
+		if interaction.Request.Body == recorded.Body {
+			match = i
+			break
+		}
+		if match == -1 {
+			match = i
+		}
+	}
+	if match == -1 {
+		return nil, fmt.Errorf("cassette %s has no recorded call left for %s %s", t.path, recorded.Method, recorded.URL)
+	}
+	t.used[match] = true
+
+	recordedResp := t.interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recordedResp.Status, http.StatusText(recordedResp.Status)),
+		StatusCode:    recordedResp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recordedResp.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recordedResp.Body)),
+		ContentLength: int64(len(recordedResp.Body)),
+		Request:       req,
+	}, nil
+}
+
+// save appends an interaction to the recorded cassette.
+func (t *cassetteTransport) save(interaction cassetteInteraction) {
+	data, err := json.Marshal(interaction)
+	if err != nil {
+		logging.Error("Failed to encode cassette interaction", "error", err)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	file, err := os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		logging.Error("Failed to open cassette", "path", t.path, "error", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		logging.Error("Failed to write cassette", "path", t.path, "error", err)
+	}
+}
+
+// redact replaces the configured API keys in s.
+func (t *cassetteTransport) redact(s string) string {
+	for _, secret := range t.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+func (t *cassetteTransport) redactHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for key, values := range header {
+		for i := range values {
+			values[i] = t.redact(values[i])
+		}
+		header[key] = values
+	}
+	for _, key := range secretHeaders {
+		if header.Get(key) != "" {
+			header.Set(key, redacted)
+		}
+	}
+	return header
+}
+
+func (t *cassetteTransport) redactURL(rawURL string) string {
+	rawURL = t.redact(rawURL)
+	query := strings.Index(rawURL, "?")
+	if query == -1 {
+		return rawURL
+	}
+	params := strings.Split(rawURL[query+1:], "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		for _, secret := range secretParams {
+			if strings.EqualFold(name, secret) {
+				params[i] = name + "=" + redacted
+			}
+		}
+	}
+	return rawURL[:query+1] + strings.Join(params, "&")
+}
+
+// readRequestBody reads the body of req and puts it back for the transport.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// recordingBody keeps what is read from a response body and saves it once the
+// body is read to the end, fails or is closed, whichever comes first.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	save func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err != nil {
+		b.once.Do(func() { b.save(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.save(b.buf.Bytes()) })
+	return b.ReadCloser.Close()
+}
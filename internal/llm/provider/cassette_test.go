@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setCassetteConfig records into or replays the named cassette in a
+// temporary data directory for the test.
+func setCassetteConfig(t *testing.T, dataDir string, cassette config.CassetteConfig) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg := config.Get()
+	previousData, previousCassette := cfg.Data, cfg.Cassette
+	cfg.Data.Directory = dataDir
+	cfg.Cassette = cassette
+	t.Cleanup(func() {
+		cfg.Data, cfg.Cassette = previousData, previousCassette
+	})
+}
+
+func streamEvents(t *testing.T, p Provider) []ProviderEvent {
+	var events []ProviderEvent
+	for event := range p.StreamResponse(context.Background(), helloMessages, nil) {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestCassetteReplay(t *testing.T) {
+	const apiKey = "sk-test-0123456789"
+	ts := newTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ []byte, _ int) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Let me look."}}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"view","arguments":""}}]}}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"file_path\":\"main.go\"}"}}]}}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":5,"completion_tokens":7,"total_tokens":12}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	})
+
+	dataDir := t.TempDir()
+	newProvider := func() Provider {
+		return newTestProvider(t, models.OpenAIModels[models.GPT4o],
+			WithAPIKey(apiKey),
+			WithOpenAIOptions(WithOpenAIBaseURL(ts.URL)),
+		)
+	}
+
+	setCassetteConfig(t, dataDir, config.CassetteConfig{Mode: config.CassetteRecord, Name: "tool-call"})
+	providers := config.Get().Providers
+	previousProvider, hadProvider := providers[models.ProviderOpenAI]
+	providers[models.ProviderOpenAI] = config.Provider{APIKey: apiKey}
+	t.Cleanup(func() {
+		if hadProvider {
+			providers[models.ProviderOpenAI] = previousProvider
+		} else {
+			delete(providers, models.ProviderOpenAI)
+		}
+	})
+	recorded := streamEvents(t, newProvider())
+	require.Equal(t, 1, ts.Requests())
+	require.NotEmpty(t, recorded)
+	assert.Equal(t, EventComplete, recorded[len(recorded)-1].Type)
+
+	cassette, err := os.ReadFile(CassettePath("tool-call"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(cassette), apiKey)
+	assert.Contains(t, string(cassette), `"Authorization":["REDACTED"]`)
+	assert.Contains(t, string(cassette), `data: [DONE]`)
+
+	setCassetteConfig(t, dataDir, config.CassetteConfig{Mode: config.CassetteReplay, Name: "tool-call"})
+	replayed := streamEvents(t, newProvider())
+	assert.Equal(t, 1, ts.Requests())
+	assert.Equal(t, recorded, replayed)
+
+	// The only recorded call has been replayed
+	events := streamEvents(t, newProvider())
+	require.NotEmpty(t, events)
+	assert.Equal(t, EventError, events[len(events)-1].Type)
+	assert.Contains(t, events[len(events)-1].Error.Error(), "no recorded call left")
+}
+
+func TestCassetteReplayCopilot(t *testing.T) {
+	// No GitHub token is needed to replay, and none is exchanged
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dataDir := t.TempDir()
+	setCassetteConfig(t, dataDir, config.CassetteConfig{Mode: config.CassetteReplay, Name: "copilot"})
+	require.NoError(t, os.MkdirAll(filepath.Dir(CassettePath("copilot")), 0o755))
+	interaction := `{"request":{"method":"POST","url":"https://api.githubcopilot.com/chat/completions"},"response":{"status":200,"header":{"Content-Type":["application/json"]},"body":"{\"id\":\"c1\",\"object\":\"chat.completion\",\"created\":1,\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"message\":{\"role\":\"assistant\",\"content\":\"Hi.\"},\"finish_reason\":\"stop\"}]}"}}`
+	require.NoError(t, os.WriteFile(CassettePath("copilot"), []byte(interaction+"\n"), 0o600))
+
+	p := newTestProvider(t, models.CopilotModels[models.CopilotGPT4o])
+	res, err := p.SendMessages(context.Background(), helloMessages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi.", res.Content)
+}
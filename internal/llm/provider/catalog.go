@@ -0,0 +1,249 @@
+package provider
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+const (
+	openRouterModelsURL = "https://openrouter.ai/api/v1/models"
+	copilotModelsURL    = "https://api.githubcopilot.com/models"
+
+	catalogTimeout          = 30 * time.Second
+	catalogDefaultMaxTokens = 4096
+)
+
+// catalogFetchers fetch the model catalogs of the providers that publish one.
+var catalogFetchers = map[models.ModelProvider]func(ctx context.Context, client *http.Client, apiKey string) ([]models.Model, error){
+	models.ProviderOpenRouter: func(ctx context.Context, client *http.Client, _ string) ([]models.Model, error) {
+		return fetchOpenRouterModels(ctx, client, openRouterModelsURL)
+	},
+	models.ProviderCopilot: func(ctx context.Context, client *http.Client, apiKey string) ([]models.Model, error) {
+		githubToken := copilotGitHubToken(apiKey)
+		if githubToken == "" && !replayingCassette() {
+			return nil, errors.New("GitHub token not found")
+		}
+		// The token is never exchanged through a cassette
+		bearerToken, err := copilotBearerToken(&http.Client{Timeout: catalogTimeout}, githubToken)
+		if err != nil {
+			return nil, err
+		}
+		return fetchCopilotModels(ctx, client, copilotModelsURL, bearerToken)
+	},
+}
+
+// RefreshModelCatalog fetches the model catalogs of the configured providers,
+// caches them in the data directory and merges them into the supported
+// models. It returns the catalog and how many models it added. The cached
+// models of a provider whose catalog can not be fetched are kept.
+func RefreshModelCatalog(ctx context.Context) (models.Catalog, int, error) {
+	catalog, fetched, err := fetchModelCatalog(ctx)
+	if !fetched {
+		return catalog, 0, err
+	}
+	return catalog, models.MergeCatalog(catalog.Models), err
+}
+
+// CacheModelCatalog fetches the model catalogs of the configured providers
+// and caches them for the next start, leaving the supported models as they
+// are.
+func CacheModelCatalog(ctx context.Context) error {
+	_, _, err := fetchModelCatalog(ctx)
+	return err
+}
+
+// fetchModelCatalog fetches and caches the model catalogs, and reports
+// whether any of them could be fetched.
+func fetchModelCatalog(ctx context.Context) (models.Catalog, bool, error) {
+	cfg := config.Get()
+	path := config.ModelCatalogPath()
+	previous, err := models.ReadCatalog(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logging.Warn("failed to read the model catalog", "error", err)
+	}
+
+	client := cassetteClient()
+	if client == nil {
+		client = &http.Client{Timeout: catalogTimeout}
+	}
+
+	catalog := models.Catalog{UpdatedAt: time.Now().Unix()}
+	var errs []error
+	fetched := 0
+	for provider, fetch := range catalogFetchers {
+		providerCfg, ok := cfg.Providers[provider]
+		if !ok || providerCfg.Disabled || (providerCfg.APIKey == "" && provider != models.ProviderCopilot) {
+			continue
+		}
+		providerModels, err := fetch(ctx, client, providerCfg.APIKey)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+			for _, model := range previous.Models {
+				if model.Provider == provider {
+					catalog.Models = append(catalog.Models, model)
+				}
+			}
+			continue
+		}
+		logging.Debug("Fetched model catalog", "provider", provider, "models", len(providerModels))
+		catalog.Models = append(catalog.Models, providerModels...)
+		fetched++
+	}
+	if fetched == 0 {
+		if len(errs) == 0 {
+			return catalog, false, errors.New("no provider with a model catalog is configured")
+		}
+		return catalog, false, errors.Join(errs...)
+	}
+
+	slices.SortFunc(catalog.Models, func(a, b models.Model) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if err := models.WriteCatalog(path, catalog); err != nil {
+		errs = append(errs, fmt.Errorf("failed to cache the model catalog: %w", err))
+	}
+	return catalog, true, errors.Join(errs...)
+}
+
+type openRouterModelList struct {
+	Data []struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		ContextLength int64  `json:"context_length"`
+		Architecture  struct {
+			InputModalities []string `json:"input_modalities"`
+		} `json:"architecture"`
+		Pricing struct {
+			Prompt          string `json:"prompt"`
+			Completion      string `json:"completion"`
+			InputCacheRead  string `json:"input_cache_read"`
+			InputCacheWrite string `json:"input_cache_write"`
+		} `json:"pricing"`
+		TopProvider struct {
+			MaxCompletionTokens int64 `json:"max_completion_tokens"`
+		} `json:"top_provider"`
+		SupportedParameters []string `json:"supported_parameters"`
+	} `json:"data"`
+}
+
+// fetchOpenRouterModels returns the OpenRouter models that can call tools,
+// priced per million tokens.
+func fetchOpenRouterModels(ctx context.Context, client *http.Client, url string) ([]models.Model, error) {
+	var list openRouterModelList
+	if err := getCatalog(ctx, client, url, nil, &list); err != nil {
+		return nil, err
+	}
+
+	var result []models.Model
+	for _, m := range list.Data {
+		if !slices.Contains(m.SupportedParameters, "tools") || m.ContextLength <= 0 {
+			continue
+		}
+		result = append(result, models.Model{
+			ID:                  models.ModelID("openrouter." + m.ID),
+			Name:                "OpenRouter – " + cmp.Or(m.Name, m.ID),
+			Provider:            models.ProviderOpenRouter,
+			APIModel:            m.ID,
+			CostPer1MIn:         perMillion(m.Pricing.Prompt),
+			CostPer1MOut:        perMillion(m.Pricing.Completion),
+			CostPer1MInCached:   perMillion(m.Pricing.InputCacheWrite),
+			CostPer1MOutCached:  perMillion(m.Pricing.InputCacheRead),
+			ContextWindow:       m.ContextLength,
+			DefaultMaxTokens:    min(cmp.Or(m.TopProvider.MaxCompletionTokens, catalogDefaultMaxTokens), m.ContextLength/2),
+			CanReason:           slices.Contains(m.SupportedParameters, "reasoning"),
+			SupportsAttachments: slices.Contains(m.Architecture.InputModalities, "image"),
+		})
+	}
+	return result, nil
+}
+
+// perMillion converts a price per token to one per million tokens.
+func perMillion(price string) float64 {
+	perToken, err := strconv.ParseFloat(price, 64)
+	if err != nil || perToken < 0 {
+		return 0
+	}
+	return perToken * 1e6
+}
+
+type copilotModelList struct {
+	Data []struct {
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		Capabilities struct {
+			Type   string `json:"type"`
+			Limits struct {
+				MaxContextWindowTokens int64 `json:"max_context_window_tokens"`
+				MaxOutputTokens        int64 `json:"max_output_tokens"`
+			} `json:"limits"`
+			Supports struct {
+				ToolCalls bool `json:"tool_calls"`
+				Vision    bool `json:"vision"`
+			} `json:"supports"`
+		} `json:"capabilities"`
+	} `json:"data"`
+}
+
+// fetchCopilotModels returns the Copilot chat models that can call tools.
+// Copilot models are included in the subscription and have no price.
+func fetchCopilotModels(ctx context.Context, client *http.Client, url, bearerToken string) ([]models.Model, error) {
+	headers := map[string]string{
+		"Authorization":          "Bearer " + bearerToken,
+		"Editor-Version":         "OpenCode/1.0",
+		"Editor-Plugin-Version":  "OpenCode/1.0",
+		"Copilot-Integration-Id": "vscode-chat",
+	}
+	var list copilotModelList
+	if err := getCatalog(ctx, client, url, headers, &list); err != nil {
+		return nil, err
+	}
+
+	var result []models.Model
+	for _, m := range list.Data {
+		limits := m.Capabilities.Limits
+		if m.Capabilities.Type != "chat" || !m.Capabilities.Supports.ToolCalls || limits.MaxContextWindowTokens <= 0 {
+			continue
+		}
+		result = append(result, models.Model{
+			ID:                  models.ModelID("copilot." + m.ID),
+			Name:                "GitHub Copilot " + cmp.Or(m.Name, m.ID),
+			Provider:            models.ProviderCopilot,
+			APIModel:            m.ID,
+			ContextWindow:       limits.MaxContextWindowTokens,
+			DefaultMaxTokens:    min(cmp.Or(limits.MaxOutputTokens, catalogDefaultMaxTokens), limits.MaxContextWindowTokens/2),
+			SupportsAttachments: m.Capabilities.Supports.Vision,
+		})
+	}
+	return result, nil
+}
+
+func getCatalog(ctx context.Context, client *http.Client, url string, headers map[string]string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
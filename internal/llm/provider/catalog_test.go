@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchOpenRouterModels(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ []byte, _ int) {
+		fmt.Fprint(w, `{"data":[
+			{"id":"openai/gpt-5","name":"OpenAI: GPT-5","context_length":400000,
+			 "architecture":{"input_modalities":["text","image"]},
+			 "pricing":{"prompt":"0.00000125","completion":"0.00001","input_cache_read":"0.000000125"},
+			 "top_provider":{"max_completion_tokens":128000},
+			 "supported_parameters":["tools","reasoning","max_tokens"]},
+			{"id":"acme/small","name":"Acme: Small","context_length":8192,
+			 "pricing":{"prompt":"0","completion":"0"},
+			 "supported_parameters":["tools"]},
+			{"id":"acme/no-tools","context_length":8192,"supported_parameters":["max_tokens"]}
+		]}`)
+	})
+
+	result, err := fetchOpenRouterModels(context.Background(), ts.Client(), ts.URL)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	gpt5 := result[0]
+	assert.Equal(t, models.ModelID("openrouter.openai/gpt-5"), gpt5.ID)
+	assert.Equal(t, "OpenRouter – OpenAI: GPT-5", gpt5.Name)
+	assert.Equal(t, "openai/gpt-5", gpt5.APIModel)
+	assert.InDelta(t, 1.25, gpt5.CostPer1MIn, 1e-9)
+	assert.InDelta(t, 10, gpt5.CostPer1MOut, 1e-9)
+	assert.InDelta(t, 0.125, gpt5.CostPer1MOutCached, 1e-9)
+	assert.Equal(t, int64(400000), gpt5.ContextWindow)
+	assert.Equal(t, int64(128000), gpt5.DefaultMaxTokens)
+	assert.True(t, gpt5.CanReason)
+	assert.True(t, gpt5.SupportsAttachments)
+
+	small := result[1]
+	assert.Equal(t, int64(4096), small.DefaultMaxTokens)
+	assert.False(t, small.CanReason)
+	assert.Zero(t, small.CostPer1MIn)
+}
+
+func TestFetchCopilotModels(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, _ []byte, _ int) {
+		if r.Header.Get("Authorization") != "Bearer copilot-token" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "vscode-chat", r.Header.Get("Copilot-Integration-Id"))
+		fmt.Fprint(w, `{"data":[
+			{"id":"gpt-5","name":"GPT-5","capabilities":{"type":"chat",
+			 "limits":{"max_context_window_tokens":128000,"max_output_tokens":64000},
+			 "supports":{"tool_calls":true,"vision":true}}},
+			{"id":"text-embedding-3-small","name":"Embedding V3 small","capabilities":{"type":"embeddings"}},
+			{"id":"gpt-3.5-turbo","name":"GPT 3.5 Turbo","capabilities":{"type":"chat",
+			 "limits":{"max_context_window_tokens":16384},"supports":{"tool_calls":false}}}
+		]}`)
+	})
+
+	result, err := fetchCopilotModels(context.Background(), ts.Client(), ts.URL, "copilot-token")
+	require.NoError(t, err)
+	assert.Equal(t, []models.Model{{
+		ID:                  "copilot.gpt-5",
+		Name:                "GitHub Copilot GPT-5",
+		Provider:            models.ProviderCopilot,
+		APIModel:            "gpt-5",
+		ContextWindow:       128000,
+		DefaultMaxTokens:    64000,
+		SupportsAttachments: true,
+	}}, result)
+
+	_, err = fetchCopilotModels(context.Background(), ts.Client(), ts.URL, "expired-token")
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/openai/openai-go"
@@ -49,13 +50,39 @@ func (c *copilotClient) isAnthropicModel() bool {
 			return true
 		}
 	}
-	return false
+	// Models from the refreshed catalog
+	return strings.HasPrefix(c.providerOptions.model.APIModel, "claude-")
 }
 
-// loadGitHubToken loads the GitHub OAuth token from the standard GitHub CLI/Copilot locations
+// copilotGitHubToken returns the GitHub token to use for Copilot, from the
+// environment, the API key or the standard GitHub CLI/Copilot locations.
+func copilotGitHubToken(apiKey string) string {
+	if githubToken := os.Getenv("GITHUB_TOKEN"); githubToken != "" {
+		return githubToken
+	}
+	if apiKey != "" {
+		return apiKey
+	}
+	githubToken, err := config.LoadGitHubToken()
+	if err != nil {
+		logging.Debug("Failed to load GitHub token from standard locations", "error", err)
+	}
+	return githubToken
+}
+
+// copilotBearerToken returns a Copilot bearer token for the GitHub token.
+// Calls replayed from a cassette are served without one, so nothing is
+// exchanged then. httpClient must not record into a cassette, since the
+// answer is a credential.
+func copilotBearerToken(httpClient *http.Client, githubToken string) (string, error) {
+	if replayingCassette() {
+		return redacted, nil
+	}
+	return exchangeGitHubToken(httpClient, githubToken)
+}
 
 // exchangeGitHubToken exchanges a GitHub token for a Copilot bearer token
-func (c *copilotClient) exchangeGitHubToken(githubToken string) (string, error) {
+func exchangeGitHubToken(httpClient *http.Client, githubToken string) (string, error) {
 	req, err := http.NewRequest("GET", "https://api.github.com/copilot_internal/v2/token", nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create token exchange request: %w", err)
@@ -64,7 +91,7 @@ func (c *copilotClient) exchangeGitHubToken(githubToken string) (string, error)
 	req.Header.Set("Authorization", "Token "+githubToken)
 	req.Header.Set("User-Agent", "OpenCode/1.0")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to exchange GitHub token: %w", err)
 	}
@@ -103,27 +130,9 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 	if copilotOpts.bearerToken != "" {
 		bearerToken = copilotOpts.bearerToken
 	} else {
-		// Try to get GitHub token from multiple sources
-		var githubToken string
-
-		// 1. Environment variable
-		githubToken = os.Getenv("GITHUB_TOKEN")
+		githubToken := copilotGitHubToken(opts.apiKey)
 
-		// 2. API key from options
-		if githubToken == "" {
-			githubToken = opts.apiKey
-		}
-
-		// 3. Standard GitHub CLI/Copilot locations
-		if githubToken == "" {
-			var err error
-			githubToken, err = config.LoadGitHubToken()
-			if err != nil {
-				logging.Debug("Failed to load GitHub token from standard locations", "error", err)
-			}
-		}
-
-		if githubToken == "" {
+		if githubToken == "" && !replayingCassette() {
 			logging.Error("GitHub token is required for Copilot provider. Set GITHUB_TOKEN environment variable, configure it in opencode.json, or ensure GitHub CLI/Copilot is properly authenticated.")
 			return &copilotClient{
 				providerOptions: opts,
@@ -132,16 +141,9 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 			}
 		}
 
-		// Create a temporary client for token exchange
-		tempClient := &copilotClient{
-			providerOptions: opts,
-			options:         copilotOpts,
-			httpClient:      httpClient,
-		}
-
 		// Exchange GitHub token for bearer token
 		var err error
-		bearerToken, err = tempClient.exchangeGitHubToken(githubToken)
+		bearerToken, err = copilotBearerToken(httpClient, githubToken)
 		if err != nil {
 			logging.Error("Failed to exchange GitHub token for Copilot bearer token", "error", err)
 			return &copilotClient{
@@ -160,6 +162,7 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 	openaiClientOptions := []option.RequestOption{
 		option.WithBaseURL(baseURL),
 		option.WithAPIKey(bearerToken), // Use bearer token as API key
+		option.WithMaxRetries(0),
 	}
 
 	// Add GitHub Copilot specific headers
@@ -176,6 +179,10 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 		}
 	}
 
+	if httpClient := cassetteClient(); httpClient != nil {
+		openaiClientOptions = append(openaiClientOptions, option.WithHTTPClient(httpClient))
+	}
+
 	client := openai.NewClient(openaiClientOptions...)
 	// logging.Debug("Copilot client created", "opts", opts, "copilotOpts", copilotOpts, "model", opts.model)
 	return &copilotClient{
@@ -327,6 +334,7 @@ func (c *copilotClient) send(ctx context.Context, messages []message.Message, to
 	}
 
 	attempts := 0
+	retries := newRetryWait()
 	for {
 		attempts++
 		copilotResponse, err := c.client.Chat.Completions.New(
@@ -341,13 +349,10 @@ func (c *copilotClient) send(ctx context.Context, messages []message.Message, to
 				return nil, retryErr
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
+				if err := retries.wait(ctx, attempts, after, err, logRetry); err != nil {
+					return nil, err
 				}
+				continue
 			}
 			return nil, retryErr
 		}
@@ -397,6 +402,7 @@ func (c *copilotClient) stream(ctx context.Context, messages []message.Message,
 	}
 
 	attempts := 0
+	retries := newRetryWait()
 	eventChan := make(chan ProviderEvent)
 
 	go func() {
@@ -514,25 +520,13 @@ func (c *copilotClient) stream(ctx context.Context, messages []message.Message,
 				close(eventChan)
 				return
 			}
-			// shouldRetry is not catching the max retries...
-			// TODO: Figure out why
-			if attempts > maxRetries {
-				logging.Warn("Maximum retry attempts reached for rate limit", "attempts", attempts, "max_retries", maxRetries)
-				retry = false
-			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d (paused for %d ms)", attempts, maxRetries, after), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					// context cancelled
-					if ctx.Err() == nil {
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-					}
+				if err := retries.wait(ctx, attempts, after, err, streamRetry(ctx, eventChan)); err != nil {
+					eventChan <- ProviderEvent{Type: EventError, Error: err}
 					close(eventChan)
 					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
 				}
+				continue
 			}
 			eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
 			close(eventChan)
@@ -546,33 +540,15 @@ func (c *copilotClient) stream(ctx context.Context, messages []message.Message,
 func (c *copilotClient) shouldRetry(attempts int, err error) (bool, int64, error) {
 	var apierr *openai.Error
 	if !errors.As(err, &apierr) {
-		return false, 0, err
+		return shouldRetry(attempts, err)
 	}
 
 	// Check for token expiration (401 Unauthorized)
 	if apierr.StatusCode == 401 {
 		// Try to refresh the bearer token
-		var githubToken string
-
-		// 1. Environment variable
-		githubToken = os.Getenv("GITHUB_TOKEN")
-
-		// 2. API key from options
-		if githubToken == "" {
-			githubToken = c.providerOptions.apiKey
-		}
-
-		// 3. Standard GitHub CLI/Copilot locations
-		if githubToken == "" {
-			var err error
-			githubToken, err = config.LoadGitHubToken()
-			if err != nil {
-				logging.Debug("Failed to load GitHub token from standard locations during retry", "error", err)
-			}
-		}
-
+		githubToken := copilotGitHubToken(c.providerOptions.apiKey)
 		if githubToken != "" {
-			newBearerToken, tokenErr := c.exchangeGitHubToken(githubToken)
+			newBearerToken, tokenErr := copilotBearerToken(c.httpClient, githubToken)
 			if tokenErr == nil {
 				c.options.bearerToken = newBearerToken
 				// Update the client with the new token
@@ -587,30 +563,7 @@ func (c *copilotClient) shouldRetry(attempts int, err error) (bool, int64, error
 	}
 	logging.Debug("Copilot API Error", "status", apierr.StatusCode, "headers", apierr.Response.Header, "body", apierr.RawJSON())
 
-	if apierr.StatusCode != 429 && apierr.StatusCode != 500 {
-		return false, 0, err
-	}
-
-	if apierr.StatusCode == 500 {
-		logging.Warn("Copilot API returned 500 error, retrying", "error", err)
-	}
-
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-
-	retryMs := 0
-	retryAfterValues := apierr.Response.Header.Values("Retry-After")
-
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs = backoffMs + jitterMs
-	if len(retryAfterValues) > 0 {
-		if _, err := fmt.Sscanf(retryAfterValues[0], "%d", &retryMs); err == nil {
-			retryMs = retryMs * 1000
-		}
-	}
-	return true, int64(retryMs), nil
+	return shouldRetry(attempts, err)
 }
 
 func (c *copilotClient) toolCalls(completion openai.ChatCompletion) []message.ToolCall {
@@ -2,11 +2,11 @@ package provider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,8 +18,23 @@ import (
 	"google.golang.org/genai"
 )
 
+// geminiThinkingBudgets are the thinking budgets in tokens by reasoning effort.
+var geminiThinkingBudgets = map[string]int32{
+	"low":    1024,
+	"medium": 8192,
+	"high":   24576,
+}
+
+const (
+	// geminiCacheTTL is how long explicit caches live.
+	geminiCacheTTL = 15 * time.Minute
+	// geminiCacheMargin keeps requests from using a cache about to expire.
+	geminiCacheMargin = time.Minute
+)
+
 type geminiOptions struct {
-	disableCache bool
+	disableCache    bool
+	reasoningEffort string
 }
 
 type GeminiOption func(*geminiOptions)
@@ -28,6 +43,36 @@ type geminiClient struct {
 	providerOptions providerClientOptions
 	options         geminiOptions
 	client          *genai.Client
+
+	cacheMu sync.Mutex
+	// caches are the explicit caches by hash of their content. Those that
+	// could not be created have no name and are retried once expired.
+	caches map[string]*geminiCache
+	// sessionCaches are the hashes of the caches last used by each session.
+	sessionCaches map[string]string
+}
+
+// geminiCache is an explicit cache. Its fields are set once ready is closed,
+// so requests for the same content wait for a single creation.
+type geminiCache struct {
+	ready   chan struct{}
+	name    string
+	expires time.Time
+}
+
+// done reports whether the creation of the cache finished.
+func (c *geminiCache) done() bool {
+	select {
+	case <-c.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// expired reports whether the cache was created and is about to expire.
+func (c *geminiCache) expired(now time.Time) bool {
+	return c.done() && now.After(c.expires.Add(-geminiCacheMargin))
 }
 
 type GeminiClient ProviderClient
@@ -38,7 +83,11 @@ func newGeminiClient(opts providerClientOptions) GeminiClient {
 		o(&geminiOpts)
 	}
 
-	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{APIKey: opts.apiKey, Backend: genai.BackendGeminiAPI})
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:     opts.apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: cassetteClient(),
+	})
 	if err != nil {
 		logging.Error("Failed to create Gemini client", "error", err)
 		return nil
@@ -48,6 +97,8 @@ func newGeminiClient(opts providerClientOptions) GeminiClient {
 		providerOptions: opts,
 		options:         geminiOpts,
 		client:          client,
+		caches:          make(map[string]*geminiCache),
+		sessionCaches:   make(map[string]string),
 	}
 }
 
@@ -72,8 +123,17 @@ func (g *geminiClient) convertMessages(messages []message.Message) []*genai.Cont
 		case message.Assistant:
 			var assistantParts []*genai.Part
 
+			// Thought signatures only make sense to the model that produced them
+			var signatures map[string][]byte
+			if state, ok := msg.ProviderState(); ok && msg.Model == g.providerOptions.model.ID {
+				signatures = state.Signatures
+			}
+
 			if msg.Content().String() != "" {
-				assistantParts = append(assistantParts, &genai.Part{Text: msg.Content().String()})
+				assistantParts = append(assistantParts, &genai.Part{
+					Text:             msg.Content().String(),
+					ThoughtSignature: signatures[""],
+				})
 			}
 
 			if len(msg.ToolCalls()) > 0 {
@@ -84,6 +144,7 @@ func (g *geminiClient) convertMessages(messages []message.Message) []*genai.Cont
 							Name: call.Name,
 							Args: args,
 						},
+						ThoughtSignature: signatures[call.ID],
 					})
 				}
 			}
@@ -166,11 +227,26 @@ func (g *geminiClient) finishReason(reason genai.FinishReason) message.FinishRea
 	}
 }
 
-func (g *geminiClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
-	// Set current request info for display
-	request.SetCurrent(string(g.providerOptions.model.Provider), g.providerOptions.model.APIModel, "https://generativelanguage.googleapis.com")
+// thinkingConfig returns the thinking configuration of models that can reason,
+// with the budget of the reasoning effort. The budget leaves a fifth of the
+// max tokens to the answer.
+func (g *geminiClient) thinkingConfig() *genai.ThinkingConfig {
+	budget, ok := geminiThinkingBudgets[g.options.reasoningEffort]
+	if !g.providerOptions.model.CanReason || !ok {
+		return nil
+	}
+	budget = min(budget, int32(float64(g.providerOptions.maxTokens)*0.8))
+	return &genai.ThinkingConfig{
+		IncludeThoughts: true,
+		ThinkingBudget:  &budget,
+	}
+}
 
-	// Convert messages
+// preparedRequest returns the config of a request, the history to start the
+// chat with and the message to send. The system prompt, the tools and the
+// messages up to the summary are served from an explicit cache when the
+// cache plan allows it, along with the tokens written to create the cache.
+func (g *geminiClient) preparedRequest(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*genai.GenerateContentConfig, []*genai.Content, *genai.Content, int64) {
 	geminiMessages := g.convertMessages(messages)
 
 	cfg := config.Get()
@@ -186,13 +262,154 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 		SystemInstruction: &genai.Content{
 			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
 		},
+		ThinkingConfig: g.thinkingConfig(),
 	}
 	if len(tools) > 0 {
 		config.Tools = g.convertTools(tools)
 	}
+
+	plan := newCachePlan(messages, len(tools), g.options.disableCache)
+	if !plan.System {
+		return config, history, lastMsg, 0
+	}
+	// The last message is sent, never cached
+	cached := g.convertMessages(messages[:min(plan.stablePrefix(), len(messages)-1)])
+	name, created := g.cachedContent(ctx, config, cached)
+	if name == "" {
+		return config, history, lastMsg, 0
+	}
+	// Requests using a cache can not set what it holds
+	config.CachedContent = name
+	config.SystemInstruction = nil
+	config.Tools = nil
+	return config, history[len(cached):], lastMsg, created
+}
+
+// cachedContent returns the name of an explicit cache holding the system
+// instruction and the tools of config and contents, creating it if needed
+// along with the tokens written to it. It returns no name when the cache
+// can not be created, for example below the minimum size of the model.
+// Requests for the same content wait for a single creation, and the cache
+// the session used before is deleted once no session uses it anymore.
+func (g *geminiClient) cachedContent(ctx context.Context, config *genai.GenerateContentConfig, contents []*genai.Content) (string, int64) {
+	key, err := json.Marshal([]any{g.providerOptions.model.APIModel, config.SystemInstruction, config.Tools, contents})
+	if err != nil {
+		return "", 0
+	}
+	sum := sha256.Sum256(key)
+	id := hex.EncodeToString(sum[:])
+	sessionID, _ := ctx.Value(tools.SessionIDContextKey).(string)
+
+	g.cacheMu.Lock()
+	cache, ok := g.caches[id]
+	creating := !ok || cache.expired(time.Now())
+	if creating {
+		g.pruneCaches(time.Now())
+		cache = &geminiCache{ready: make(chan struct{})}
+		g.caches[id] = cache
+	}
+	g.cacheMu.Unlock()
+
+	var created int64
+	if creating {
+		created = g.createCache(ctx, cache, config, contents)
+	} else {
+		select {
+		case <-cache.ready:
+		case <-ctx.Done():
+			return "", 0
+		}
+	}
+
+	if superseded := g.useCache(sessionID, id); superseded != "" {
+		if _, err := g.client.Caches.Delete(ctx, superseded, nil); err != nil {
+			logging.Debug("Failed to delete Gemini cache", "error", err, "cache", superseded)
+		}
+	}
+	return cache.name, created
+}
+
+// createCache creates the explicit cache and marks it ready, returning the
+// tokens written. Caches that can not be created are not retried before
+// they would have expired.
+func (g *geminiClient) createCache(ctx context.Context, cache *geminiCache, config *genai.GenerateContentConfig, contents []*genai.Content) int64 {
+	defer close(cache.ready)
+	now := time.Now()
+	created, err := g.client.Caches.Create(ctx, g.providerOptions.model.APIModel, &genai.CreateCachedContentConfig{
+		TTL:               geminiCacheTTL,
+		Contents:          contents,
+		SystemInstruction: config.SystemInstruction,
+		Tools:             config.Tools,
+	})
+	if err != nil {
+		logging.Debug("Failed to create Gemini cache", "error", err)
+		cache.expires = now.Add(geminiCacheTTL)
+		return 0
+	}
+	cache.name = created.Name
+	cache.expires = created.ExpireTime
+	if cache.expires.IsZero() {
+		cache.expires = now.Add(geminiCacheTTL)
+	}
+	if created.UsageMetadata == nil {
+		return 0
+	}
+	return int64(created.UsageMetadata.TotalTokenCount)
+}
+
+// useCache records that the session now uses the cache with the given hash.
+// It returns the name of the cache the session used before if no other
+// session uses it, which is then forgotten and should be deleted.
+func (g *geminiClient) useCache(sessionID, id string) string {
+	if sessionID == "" {
+		return ""
+	}
+	g.cacheMu.Lock()
+	defer g.cacheMu.Unlock()
+	previous, ok := g.sessionCaches[sessionID]
+	g.sessionCaches[sessionID] = id
+	if !ok || previous == id {
+		return ""
+	}
+	for _, cacheID := range g.sessionCaches {
+		if cacheID == previous {
+			return ""
+		}
+	}
+	cache, ok := g.caches[previous]
+	if !ok || !cache.done() || cache.name == "" || cache.expired(time.Now()) {
+		return ""
+	}
+	delete(g.caches, previous)
+	return cache.name
+}
+
+// pruneCaches forgets the caches that expired. It is called with cacheMu held.
+func (g *geminiClient) pruneCaches(now time.Time) {
+	for cacheID, cache := range g.caches {
+		if cache.expired(now) && now.After(cache.expires) {
+			delete(g.caches, cacheID)
+		}
+	}
+}
+
+// providerState keeps the thought signatures of a response, if any.
+func providerState(signatures map[string][]byte) *message.ProviderState {
+	if len(signatures) == 0 {
+		return nil
+	}
+	return &message.ProviderState{Signatures: signatures}
+}
+
+func (g *geminiClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	// Set current request info for display
+	request.SetCurrent(string(g.providerOptions.model.Provider), g.providerOptions.model.APIModel, "https://generativelanguage.googleapis.com")
+
+	config, history, lastMsg, cacheCreated := g.preparedRequest(ctx, messages, tools)
 	chat, _ := g.client.Chats.Create(ctx, g.providerOptions.model.APIModel, config, history)
 
 	attempts := 0
+	retries := newRetryWait()
 	for {
 		attempts++
 		var toolCalls []message.ToolCall
@@ -210,26 +427,24 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 				return nil, retryErr
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
+				if err := retries.wait(ctx, attempts, after, err, logRetry); err != nil {
 					request.Clear() // Clear request info on context cancellation
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
+					return nil, err
 				}
+				continue
 			}
 			request.Clear() // Clear request info on error
 			return nil, retryErr
 		}
 
 		content := ""
+		signatures := map[string][]byte{}
 
 		if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
 			for _, part := range resp.Candidates[0].Content.Parts {
 				switch {
-				case part.Text != "":
-					content = string(part.Text)
+				case part.Thought:
+					// Thoughts are only shown while streaming
 				case part.FunctionCall != nil:
 					id := "call_" + uuid.New().String()
 					args, _ := json.Marshal(part.FunctionCall.Args)
@@ -240,6 +455,14 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 						Type:     "function",
 						Finished: true,
 					})
+					if len(part.ThoughtSignature) > 0 {
+						signatures[id] = part.ThoughtSignature
+					}
+				default:
+					content += part.Text
+					if len(part.ThoughtSignature) > 0 {
+						signatures[""] = part.ThoughtSignature
+					}
 				}
 			}
 		}
@@ -255,8 +478,9 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 		return &ProviderResponse{
 			Content:      content,
 			ToolCalls:    toolCalls,
-			Usage:        g.usage(resp),
+			Usage:        g.usage(resp, cacheCreated),
 			FinishReason: finishReason,
+			State:        providerState(signatures),
 		}, nil
 	}
 }
@@ -265,39 +489,23 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 	// Set current request info for display
 	request.SetCurrent(string(g.providerOptions.model.Provider), g.providerOptions.model.APIModel, "https://generativelanguage.googleapis.com")
 
-	// Convert messages
-	geminiMessages := g.convertMessages(messages)
-
-	cfg := config.Get()
-	if cfg.Debug {
-		jsonData, _ := json.Marshal(geminiMessages)
-		logging.Debug("Prepared messages", "messages", string(jsonData))
-	}
-
-	history := geminiMessages[:len(geminiMessages)-1] // All but last message
-	lastMsg := geminiMessages[len(geminiMessages)-1]
-	config := &genai.GenerateContentConfig{
-		MaxOutputTokens: int32(g.providerOptions.maxTokens),
-		SystemInstruction: &genai.Content{
-			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
-		},
-	}
-	if len(tools) > 0 {
-		config.Tools = g.convertTools(tools)
-	}
+	config, history, lastMsg, cacheCreated := g.preparedRequest(ctx, messages, tools)
 	chat, _ := g.client.Chats.Create(ctx, g.providerOptions.model.APIModel, config, history)
 
 	attempts := 0
+	retries := newRetryWait()
 	eventChan := make(chan ProviderEvent)
 
 	go func() {
 		defer close(eventChan)
 
+	attempt:
 		for {
 			attempts++
 
 			currentContent := ""
 			toolCalls := []message.ToolCall{}
+			signatures := map[string][]byte{}
 			var finalResp *genai.GenerateContentResponse
 
 			eventChan <- ProviderEvent{Type: EventContentStart}
@@ -315,24 +523,17 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 						eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
 						return
 					}
-					if retry {
-						logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-						select {
-						case <-ctx.Done():
-							request.Clear() // Clear request info on context cancellation
-							if ctx.Err() != nil {
-								eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-							}
-
-							return
-						case <-time.After(time.Duration(after) * time.Millisecond):
-							break
-						}
-					} else {
+					if !retry {
 						request.Clear() // Clear request info on error
 						eventChan <- ProviderEvent{Type: EventError, Error: err}
 						return
 					}
+					if err := retries.wait(ctx, attempts, after, err, streamRetry(ctx, eventChan)); err != nil {
+						request.Clear() // Clear request info on context cancellation
+						eventChan <- ProviderEvent{Type: EventError, Error: err}
+						return
+					}
+					continue attempt
 				}
 
 				finalResp = resp
@@ -340,14 +541,12 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 				if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
 					for _, part := range resp.Candidates[0].Content.Parts {
 						switch {
-						case part.Text != "":
-							delta := string(part.Text)
-							if delta != "" {
+						case part.Thought:
+							if part.Text != "" {
 								eventChan <- ProviderEvent{
-									Type:    EventContentDelta,
-									Content: delta,
+									Type:     EventThinkingDelta,
+									Thinking: part.Text,
 								}
-								currentContent += delta
 							}
 						case part.FunctionCall != nil:
 							id := "call_" + uuid.New().String()
@@ -370,6 +569,21 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 
 							if isNew {
 								toolCalls = append(toolCalls, newCall)
+								if len(part.ThoughtSignature) > 0 {
+									signatures[newCall.ID] = part.ThoughtSignature
+								}
+							}
+						default:
+							// The signature of the text can come with an empty last part
+							if len(part.ThoughtSignature) > 0 {
+								signatures[""] = part.ThoughtSignature
+							}
+							if part.Text != "" {
+								eventChan <- ProviderEvent{
+									Type:    EventContentDelta,
+									Content: part.Text,
+								}
+								currentContent += part.Text
 							}
 						}
 					}
@@ -392,8 +606,9 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 					Response: &ProviderResponse{
 						Content:      currentContent,
 						ToolCalls:    toolCalls,
-						Usage:        g.usage(finalResp),
+						Usage:        g.usage(finalResp, cacheCreated),
 						FinishReason: finishReason,
+						State:        providerState(signatures),
 					},
 				}
 				request.Clear() // Clear request info on successful completion
@@ -407,35 +622,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 }
 
 func (g *geminiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	// Check if error is a rate limit error
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-
-	// Gemini doesn't have a standard error type we can check against
-	// So we'll check the error message for rate limit indicators
-	if errors.Is(err, io.EOF) {
-		return false, 0, err
-	}
-
-	errMsg := err.Error()
-	isRateLimit := false
-
-	// Check for common rate limit error messages
-	if contains(errMsg, "rate limit", "quota exceeded", "too many requests") {
-		isRateLimit = true
-	}
-
-	if !isRateLimit {
-		return false, 0, err
-	}
-
-	// Calculate backoff with jitter
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs := backoffMs + jitterMs
-
-	return true, int64(retryMs), nil
+	return shouldRetry(attempts, err)
 }
 
 func (g *geminiClient) toolCalls(resp *genai.GenerateContentResponse) []message.ToolCall {
@@ -459,15 +646,18 @@ func (g *geminiClient) toolCalls(resp *genai.GenerateContentResponse) []message.
 	return toolCalls
 }
 
-func (g *geminiClient) usage(resp *genai.GenerateContentResponse) TokenUsage {
+// usage returns the usage of a response, with the tokens written to create
+// the explicit cache the request used.
+func (g *geminiClient) usage(resp *genai.GenerateContentResponse, cacheCreated int64) TokenUsage {
 	if resp == nil || resp.UsageMetadata == nil {
-		return TokenUsage{}
+		return TokenUsage{CacheCreationTokens: cacheCreated}
 	}
 
+	// The prompt tokens include the cached ones
 	return TokenUsage{
-		InputTokens:         int64(resp.UsageMetadata.PromptTokenCount),
-		OutputTokens:        int64(resp.UsageMetadata.CandidatesTokenCount),
-		CacheCreationTokens: 0, // Not directly provided by Gemini
+		InputTokens:         int64(resp.UsageMetadata.PromptTokenCount - resp.UsageMetadata.CachedContentTokenCount),
+		OutputTokens:        int64(resp.UsageMetadata.CandidatesTokenCount + resp.UsageMetadata.ThoughtsTokenCount),
+		CacheCreationTokens: cacheCreated,
 		CacheReadTokens:     int64(resp.UsageMetadata.CachedContentTokenCount),
 	}
 }
@@ -478,6 +668,19 @@ func WithGeminiDisableCache() GeminiOption {
 	}
 }
 
+func WithGeminiReasoningEffort(effort string) GeminiOption {
+	return func(options *geminiOptions) {
+		defaultReasoningEffort := "medium"
+		switch effort {
+		case "low", "medium", "high":
+			defaultReasoningEffort = effort
+		default:
+			logging.Warn("Invalid reasoning effort, using default: medium")
+		}
+		options.reasoningEffort = defaultReasoningEffort
+	}
+}
+
 // Helper functions
 func parseJsonToMap(jsonStr string) (map[string]interface{}, error) {
 	var result map[string]interface{}
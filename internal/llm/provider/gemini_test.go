@@ -0,0 +1,199 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	toolsPkg "github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestGeminiThinkingConfig(t *testing.T) {
+	client := &geminiClient{
+		providerOptions: providerClientOptions{model: models.GeminiModels[models.Gemini25], maxTokens: 50000},
+		options:         geminiOptions{reasoningEffort: "high"},
+	}
+	thinking := client.thinkingConfig()
+	require.NotNil(t, thinking)
+	assert.True(t, thinking.IncludeThoughts)
+	assert.Equal(t, int32(24576), *thinking.ThinkingBudget)
+
+	client.providerOptions.maxTokens = 5000
+	assert.Equal(t, int32(4000), *client.thinkingConfig().ThinkingBudget)
+
+	client.options.reasoningEffort = ""
+	assert.Nil(t, client.thinkingConfig())
+
+	client.options.reasoningEffort = "high"
+	client.providerOptions.model = models.GeminiModels[models.Gemini20Flash]
+	assert.Nil(t, client.thinkingConfig())
+}
+
+func TestGeminiThoughtSignatures(t *testing.T) {
+	client := &geminiClient{providerOptions: providerClientOptions{model: models.GeminiModels[models.Gemini25]}}
+	assistant := message.Message{
+		Role:  message.Assistant,
+		Model: models.Gemini25,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me look."},
+			message.ToolCall{ID: "call_1", Name: "view", Input: `{"file_path":"main.go"}`, Finished: true},
+		},
+	}
+	assistant.SetProviderState(message.ProviderState{Signatures: map[string][]byte{
+		"":       []byte("text-signature"),
+		"call_1": []byte("call-signature"),
+	}})
+	messages := append(append([]message.Message{}, helloMessages...), assistant)
+
+	history := client.convertMessages(messages)
+	require.Len(t, history, 2)
+	parts := history[1].Parts
+	require.Len(t, parts, 2)
+	assert.Equal(t, []byte("text-signature"), parts[0].ThoughtSignature)
+	assert.Equal(t, []byte("call-signature"), parts[1].ThoughtSignature)
+
+	// Another model can not use the signatures
+	messages[1].Model = models.Gemini25Flash
+	history = client.convertMessages(messages)
+	assert.Nil(t, history[1].Parts[0].ThoughtSignature)
+	assert.Nil(t, history[1].Parts[1].ThoughtSignature)
+}
+
+// newTestGeminiClient creates a Gemini client with the options that talks to
+// the test server.
+func newTestGeminiClient(t *testing.T, ts *testServer, options providerClientOptions) *geminiClient {
+	genaiClient, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: ts.URL},
+	})
+	require.NoError(t, err)
+	return &geminiClient{
+		providerOptions: options,
+		client:          genaiClient,
+		caches:          make(map[string]*geminiCache),
+		sessionCaches:   make(map[string]string),
+	}
+}
+
+func TestGeminiCachedContent(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	var creates []map[string]any
+	var deletes []string
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request, data []byte, _ int) {
+		if r.Method == http.MethodDelete {
+			deletes = append(deletes, r.URL.Path[strings.Index(r.URL.Path, "cachedContents/"):])
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		require.True(t, strings.HasSuffix(r.URL.Path, "/cachedContents"), r.URL.Path)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(data, &body))
+		creates = append(creates, body)
+		if len(creates) > 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"code":400,"message":"Cached content is too small"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"name":"cachedContents/c%d","usageMetadata":{"totalTokenCount":4096}}`, len(creates))
+	})
+
+	client := newTestGeminiClient(t, ts, providerClientOptions{model: models.GeminiModels[models.Gemini25], maxTokens: 8000, systemMessage: "be brief"})
+	text := func(role message.MessageRole, summary bool, content string) message.Message {
+		return message.Message{Role: role, Summary: summary, Parts: []message.ContentPart{message.TextContent{Text: content}}}
+	}
+	messages := []message.Message{
+		text(message.User, true, "summary"),
+		text(message.Assistant, false, "Go on."),
+		text(message.User, false, "hello"),
+	}
+	tools := []toolsPkg.BaseTool{viewTool{}}
+	ctx := context.WithValue(context.Background(), toolsPkg.SessionIDContextKey, "session")
+
+	// The system instruction, the tools and the summary are cached
+	config, history, lastMsg, created := client.preparedRequest(ctx, messages, tools)
+	assert.Equal(t, "cachedContents/c1", config.CachedContent)
+	assert.Nil(t, config.SystemInstruction)
+	assert.Nil(t, config.Tools)
+	assert.Equal(t, int64(4096), created)
+	require.Len(t, history, 1)
+	assert.Equal(t, "Go on.", history[0].Parts[0].Text)
+	assert.Equal(t, "hello", lastMsg.Parts[0].Text)
+	require.Len(t, creates, 1)
+	assert.Len(t, creates[0]["contents"], 1)
+
+	// The next turn reuses the cache
+	messages = append(messages, text(message.Assistant, false, "Hi."), text(message.User, false, "bye"))
+	config, history, _, created = client.preparedRequest(ctx, messages, tools)
+	assert.Equal(t, "cachedContents/c1", config.CachedContent)
+	assert.Zero(t, created)
+	assert.Len(t, history, 3)
+	assert.Len(t, creates, 1)
+
+	// Without a summary only the system instruction and the tools are cached,
+	// and the cache the session no longer uses is deleted
+	config, history, _, _ = client.preparedRequest(ctx, messages[1:], tools)
+	assert.Equal(t, "cachedContents/c2", config.CachedContent)
+	assert.Len(t, history, 3)
+	assert.Equal(t, []string{"cachedContents/c1"}, deletes)
+
+	// A cache another session still uses is kept
+	other := context.WithValue(context.Background(), toolsPkg.SessionIDContextKey, "other")
+	config, _, _, _ = client.preparedRequest(other, messages[1:], tools)
+	assert.Equal(t, "cachedContents/c2", config.CachedContent)
+
+	// A cache that can not be created is not retried before it would expire
+	for range 2 {
+		config, history, _, created = client.preparedRequest(ctx, messages[1:], nil)
+		assert.Empty(t, config.CachedContent)
+		assert.NotNil(t, config.SystemInstruction)
+		assert.Zero(t, created)
+		assert.Len(t, history, 3)
+	}
+	assert.Len(t, creates, 3)
+	assert.Len(t, deletes, 1)
+
+	// Disabling the cache sends everything
+	client.options.disableCache = true
+	config, history, _, _ = client.preparedRequest(context.Background(), messages, tools)
+	assert.Empty(t, config.CachedContent)
+	assert.Len(t, config.Tools, 1)
+	assert.Len(t, history, 4)
+	assert.Len(t, creates, 3)
+}
+
+func TestGeminiCachedContentConcurrent(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ []byte, _ int) {
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, `{"name":"cachedContents/c1"}`)
+	})
+
+	client := newTestGeminiClient(t, ts, providerClientOptions{model: models.GeminiModels[models.Gemini25]})
+	config := &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("be brief", genai.RoleUser)}
+
+	// Requests for the same content share one creation
+	var wg sync.WaitGroup
+	names := make([]string, 3)
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names[i], _ = client.cachedContent(context.Background(), config, nil)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"cachedContents/c1", "cachedContents/c1", "cachedContents/c1"}, names)
+	assert.Equal(t, 1, ts.Requests())
+}
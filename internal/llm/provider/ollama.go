@@ -0,0 +1,409 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/request"
+)
+
+type ollamaOptions struct {
+	baseURL string
+	think   bool
+}
+
+type OllamaOption func(*ollamaOptions)
+
+type ollamaClient struct {
+	providerOptions providerClientOptions
+	options         ollamaOptions
+	client          *http.Client
+}
+
+type OllamaClient ProviderClient
+
+func newOllamaClient(opts providerClientOptions) OllamaClient {
+	ollamaOpts := ollamaOptions{
+		baseURL: models.OllamaURL(cmp.Or(os.Getenv("OLLAMA_HOST"), "localhost")),
+	}
+	for _, o := range opts.ollamaOptions {
+		o(&ollamaOpts)
+	}
+
+	client := cassetteClient()
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &ollamaClient{
+		providerOptions: opts,
+		options:         ollamaOpts,
+		client:          client,
+	}
+}
+
+type ollamaChatRequest struct {
+	Model    string               `json:"model"`
+	Messages []ollamaMessage      `json:"messages"`
+	Tools    []ollamaTool         `json:"tools,omitempty"`
+	Stream   bool                 `json:"stream"`
+	Think    bool                 `json:"think,omitempty"`
+	Options  ollamaRequestOptions `json:"options"`
+}
+
+type ollamaRequestOptions struct {
+	NumCtx     int64 `json:"num_ctx"`
+	NumPredict int64 `json:"num_predict,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    [][]byte         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string         `json:"type"`
+	Function ollamaFunction `json:"function"`
+}
+
+type ollamaFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// ollamaChatResponse is a response of /api/chat, or a chunk of it when
+// streamed.
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int64         `json:"prompt_eval_count"`
+	EvalCount       int64         `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// ollamaError is an error answered by the Ollama server.
+type ollamaError struct {
+	StatusCode int
+	Header     http.Header
+	Message    string
+}
+
+func (e *ollamaError) Error() string {
+	return fmt.Sprintf("ollama: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (o *ollamaClient) convertMessages(messages []message.Message) []ollamaMessage {
+	ollamaMessages := []ollamaMessage{{Role: "system", Content: o.providerOptions.systemMessage}}
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			userMessage := ollamaMessage{Role: "user", Content: msg.Content().String()}
+			for _, binaryContent := range msg.BinaryContent() {
+				userMessage.Images = append(userMessage.Images, binaryContent.Data)
+			}
+			ollamaMessages = append(ollamaMessages, userMessage)
+
+		case message.Assistant:
+			assistantMessage := ollamaMessage{Role: "assistant", Content: msg.Content().String()}
+			for _, call := range msg.ToolCalls() {
+				var toolCall ollamaToolCall
+				toolCall.Function.Name = call.Name
+				toolCall.Function.Arguments, _ = parseJsonToMap(call.Input)
+				assistantMessage.ToolCalls = append(assistantMessage.ToolCalls, toolCall)
+			}
+			if assistantMessage.Content == "" && len(assistantMessage.ToolCalls) == 0 {
+				continue
+			}
+			ollamaMessages = append(ollamaMessages, assistantMessage)
+
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				ollamaMessages = append(ollamaMessages, ollamaMessage{
+					Role:     "tool",
+					Content:  result.Content,
+					ToolName: result.Name,
+				})
+			}
+		}
+	}
+	return ollamaMessages
+}
+
+func (o *ollamaClient) convertTools(tools []tools.BaseTool) []ollamaTool {
+	ollamaTools := make([]ollamaTool, len(tools))
+	for i, tool := range tools {
+		info := tool.Info()
+		ollamaTools[i] = ollamaTool{
+			Type: "function",
+			Function: ollamaFunction{
+				Name:        info.Name,
+				Description: info.Description,
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": info.Parameters,
+					"required":   info.Required,
+				},
+			},
+		}
+	}
+	return ollamaTools
+}
+
+func (o *ollamaClient) finishReason(reason string) message.FinishReason {
+	switch reason {
+	case "stop":
+		return message.FinishReasonEndTurn
+	case "length":
+		return message.FinishReasonMaxTokens
+	default:
+		return message.FinishReasonUnknown
+	}
+}
+
+// preparedRequest builds a chat request. The context length is set to the
+// one of the model, Ollama uses a much shorter one by default. Models that can
+// reason only think when the agent asked for it.
+func (o *ollamaClient) preparedRequest(messages []message.Message, tools []tools.BaseTool, stream bool) ollamaChatRequest {
+	model := o.providerOptions.model
+	chatRequest := ollamaChatRequest{
+		Model:    model.APIModel,
+		Messages: o.convertMessages(messages),
+		Stream:   stream,
+		Think:    model.CanReason && o.options.think,
+		Options: ollamaRequestOptions{
+			NumCtx:     model.ContextWindow,
+			NumPredict: o.providerOptions.maxTokens,
+		},
+	}
+	if models.OllamaSupportsTools(model.ID) {
+		chatRequest.Tools = o.convertTools(tools)
+	}
+	return chatRequest
+}
+
+// chat sends a chat request and returns the response once the server accepted
+// it.
+func (o *ollamaClient) chat(ctx context.Context, chatRequest ollamaChatRequest) (*http.Response, error) {
+	body, err := json.Marshal(chatRequest)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.options.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		var errorResponse ollamaChatResponse
+		data, _ := io.ReadAll(res.Body)
+		if json.Unmarshal(data, &errorResponse) != nil || errorResponse.Error == "" {
+			errorResponse.Error = string(data)
+		}
+		return nil, &ollamaError{StatusCode: res.StatusCode, Header: res.Header, Message: errorResponse.Error}
+	}
+	return res, nil
+}
+
+func (o *ollamaClient) logRequest(chatRequest ollamaChatRequest) {
+	request.SetCurrent(string(o.providerOptions.model.Provider), o.providerOptions.model.APIModel, o.options.baseURL)
+
+	if config.Get().Debug {
+		jsonData, _ := json.Marshal(chatRequest.Messages)
+		logging.Debug("Prepared messages", "messages", string(jsonData))
+	}
+}
+
+func (o *ollamaClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	chatRequest := o.preparedRequest(messages, tools, false)
+	o.logRequest(chatRequest)
+	defer request.Clear()
+
+	attempts := 0
+	retries := newRetryWait()
+	for {
+		attempts++
+		res, err := o.chat(ctx, chatRequest)
+		var chatResponse ollamaChatResponse
+		if err == nil {
+			err = json.NewDecoder(res.Body).Decode(&chatResponse)
+			res.Body.Close()
+		}
+		if err == nil && chatResponse.Error != "" {
+			err = errors.New(chatResponse.Error)
+		}
+		if err == nil {
+			toolCalls := o.toolCalls(chatResponse.Message)
+			finishReason := o.finishReason(chatResponse.DoneReason)
+			if len(toolCalls) > 0 {
+				finishReason = message.FinishReasonToolUse
+			}
+			return &ProviderResponse{
+				Content:      chatResponse.Message.Content,
+				ToolCalls:    toolCalls,
+				Usage:        o.usage(chatResponse),
+				FinishReason: finishReason,
+			}, nil
+		}
+
+		retry, after, retryErr := o.shouldRetry(attempts, err)
+		if retryErr != nil || !retry {
+			return nil, retryErr
+		}
+		if err := retries.wait(ctx, attempts, after, err, logRetry); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (o *ollamaClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	chatRequest := o.preparedRequest(messages, tools, true)
+	o.logRequest(chatRequest)
+
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+		defer request.Clear()
+
+		attempts := 0
+		retries := newRetryWait()
+		for {
+			attempts++
+			response, err := o.streamChat(ctx, chatRequest, eventChan)
+			if err == nil {
+				eventChan <- ProviderEvent{Type: EventComplete, Response: response}
+				return
+			}
+
+			retry, after, retryErr := o.shouldRetry(attempts, err)
+			if retryErr != nil || !retry {
+				eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
+				return
+			}
+			if err := retries.wait(ctx, attempts, after, err, streamRetry(ctx, eventChan)); err != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: err}
+				return
+			}
+		}
+	}()
+	return eventChan
+}
+
+// streamChat sends the deltas of a streamed chat as events and returns the
+// complete response.
+func (o *ollamaClient) streamChat(ctx context.Context, chatRequest ollamaChatRequest, eventChan chan<- ProviderEvent) (*ProviderResponse, error) {
+	res, err := o.chat(ctx, chatRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	content := ""
+	var toolCalls []message.ToolCall
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return nil, err
+		}
+		if chunk.Error != "" {
+			return nil, errors.New(chunk.Error)
+		}
+		if chunk.Message.Thinking != "" {
+			eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: chunk.Message.Thinking}
+		}
+		if chunk.Message.Content != "" {
+			eventChan <- ProviderEvent{Type: EventContentDelta, Content: chunk.Message.Content}
+			content += chunk.Message.Content
+		}
+		// Tool calls come whole
+		toolCalls = append(toolCalls, o.toolCalls(chunk.Message)...)
+
+		if chunk.Done {
+			finishReason := o.finishReason(chunk.DoneReason)
+			if len(toolCalls) > 0 {
+				finishReason = message.FinishReasonToolUse
+			}
+			return &ProviderResponse{
+				Content:      content,
+				ToolCalls:    toolCalls,
+				Usage:        o.usage(chunk),
+				FinishReason: finishReason,
+			}, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+func (o *ollamaClient) shouldRetry(attempts int, err error) (bool, int64, error) {
+	return shouldRetry(attempts, err)
+}
+
+func (o *ollamaClient) toolCalls(msg ollamaMessage) []message.ToolCall {
+	var toolCalls []message.ToolCall
+	for _, call := range msg.ToolCalls {
+		args, _ := json.Marshal(call.Function.Arguments)
+		toolCalls = append(toolCalls, message.ToolCall{
+			ID:       "call_" + uuid.New().String(),
+			Name:     call.Function.Name,
+			Input:    string(args),
+			Type:     "function",
+			Finished: true,
+		})
+	}
+	return toolCalls
+}
+
+func (o *ollamaClient) usage(response ollamaChatResponse) TokenUsage {
+	return TokenUsage{
+		InputTokens:  response.PromptEvalCount,
+		OutputTokens: response.EvalCount,
+	}
+}
+
+func WithOllamaBaseURL(baseURL string) OllamaOption {
+	return func(options *ollamaOptions) {
+		options.baseURL = baseURL
+	}
+}
+
+// WithOllamaThink lets models that can reason think before they answer.
+func WithOllamaThink() OllamaOption {
+	return func(options *ollamaOptions) {
+		options.think = true
+	}
+}
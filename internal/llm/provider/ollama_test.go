@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ollamaServer stands in for an Ollama server whose /api/chat answers with
+// the given lines, after failing the first failures requests.
+func ollamaServer(t *testing.T, failures int, lines ...string) *testServer {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request, _ []byte, n int) {
+		require.Equal(t, "/api/chat", r.URL.Path)
+		if n <= failures {
+			w.Header().Set("Retry-After-Ms", "10")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":"server busy, please try again"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+	})
+}
+
+func newTestOllamaProvider(t *testing.T, ts *testServer, opts ...OllamaOption) Provider {
+	model := models.Model{
+		ID:            "ollama.qwen3:32b",
+		Provider:      models.ProviderOllama,
+		APIModel:      "qwen3:32b",
+		ContextWindow: 40960,
+		CanReason:     true,
+	}
+	return newTestProvider(t, model,
+		WithMaxTokens(8192),
+		WithSystemMessage("be brief"),
+		WithOllamaOptions(append([]OllamaOption{WithOllamaBaseURL(ts.URL)}, opts...)...),
+	)
+}
+
+type viewTool struct{}
+
+func (viewTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{
+		Name:        "view",
+		Description: "View a file",
+		Parameters:  map[string]any{"file_path": map[string]any{"type": "string"}},
+		Required:    []string{"file_path"},
+	}
+}
+
+func (viewTool) Run(context.Context, tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.ToolResponse{}, nil
+}
+
+func TestOllamaStream(t *testing.T) {
+	setRetryConfig(t, config.RetryConfig{MaxRetries: 2, MaxWait: 10})
+	ts := ollamaServer(t, 1,
+		`{"message":{"role":"assistant","content":"","thinking":"Which file?"},"done":false}`,
+		`{"message":{"role":"assistant","content":"Let me look."},"done":false}`,
+		`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"view","arguments":{"file_path":"main.go"}}}]},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":5}`,
+	)
+
+	var thinking, content string
+	var response *ProviderResponse
+	for event := range newTestOllamaProvider(t, ts, WithOllamaThink()).StreamResponse(context.Background(), helloMessages, []tools.BaseTool{viewTool{}}) {
+		switch event.Type {
+		case EventThinkingDelta:
+			thinking += event.Thinking
+		case EventContentDelta:
+			content += event.Content
+		case EventError:
+			t.Fatal(event.Error)
+		case EventComplete:
+			response = event.Response
+		}
+	}
+
+	assert.Equal(t, "Which file?", thinking)
+	assert.Equal(t, "Let me look.", content)
+	require.NotNil(t, response)
+	assert.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+	assert.Equal(t, TokenUsage{InputTokens: 12, OutputTokens: 5}, response.Usage)
+	require.Len(t, response.ToolCalls, 1)
+	assert.Equal(t, "view", response.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"file_path":"main.go"}`, response.ToolCalls[0].Input)
+
+	// The busy server was retried
+	require.Equal(t, 2, ts.Requests())
+	chatRequest := requestBody[ollamaChatRequest](t, ts, 1)
+	assert.Equal(t, "qwen3:32b", chatRequest.Model)
+	assert.True(t, chatRequest.Stream)
+	assert.True(t, chatRequest.Think)
+	assert.Equal(t, ollamaRequestOptions{NumCtx: 40960, NumPredict: 8192}, chatRequest.Options)
+	require.Len(t, chatRequest.Tools, 1)
+	assert.Equal(t, "view", chatRequest.Tools[0].Function.Name)
+	assert.Equal(t, []ollamaMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "hello"}}, chatRequest.Messages)
+}
+
+func TestOllamaSend(t *testing.T) {
+	ts := ollamaServer(t, 0,
+		`{"message":{"role":"assistant","content":"It is empty."},"done":true,"done_reason":"length","prompt_eval_count":20,"eval_count":3}`,
+	)
+
+	messages := append(append([]message.Message{}, helloMessages...),
+		message.Message{
+			Role: message.Assistant,
+			Parts: []message.ContentPart{
+				message.ToolCall{ID: "call_1", Name: "view", Input: `{"file_path":"main.go"}`, Finished: true},
+			},
+		},
+		message.Message{
+			Role:  message.Tool,
+			Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call_1", Name: "view", Content: "package main"}},
+		},
+	)
+	res, err := newTestOllamaProvider(t, ts).SendMessages(context.Background(), messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "It is empty.", res.Content)
+	assert.Equal(t, message.FinishReasonMaxTokens, res.FinishReason)
+
+	require.Equal(t, 1, ts.Requests())
+	chatRequest := requestBody[ollamaChatRequest](t, ts, 0)
+	assert.False(t, chatRequest.Stream)
+	// Without an effort the model does not think
+	assert.False(t, chatRequest.Think)
+	require.Len(t, chatRequest.Messages, 4)
+	assert.Equal(t, "view", chatRequest.Messages[2].ToolCalls[0].Function.Name)
+	assert.Equal(t, map[string]any{"file_path": "main.go"}, chatRequest.Messages[2].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, ollamaMessage{Role: "tool", Content: "package main", ToolName: "view"}, chatRequest.Messages[3])
+}
+
+func TestOllamaError(t *testing.T) {
+	ts := newTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ []byte, _ int) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"qwen3:32b\" not found, try pulling it first"}`)
+	})
+
+	_, err := newTestOllamaProvider(t, ts).SendMessages(context.Background(), helloMessages, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "try pulling it first")
+}
@@ -46,7 +46,8 @@ func newOpenAIClient(opts providerClientOptions) OpenAIClient {
 		o(&openaiOpts)
 	}
 
-	openaiClientOptions := []option.RequestOption{}
+	// Retries are left to the retry policy of the provider package
+	openaiClientOptions := []option.RequestOption{option.WithMaxRetries(0)}
 	if opts.apiKey != "" {
 		openaiClientOptions = append(openaiClientOptions, option.WithAPIKey(opts.apiKey))
 	}
@@ -60,6 +61,10 @@ func newOpenAIClient(opts providerClientOptions) OpenAIClient {
 		}
 	}
 
+	if httpClient := cassetteClient(); httpClient != nil {
+		openaiClientOptions = append(openaiClientOptions, option.WithHTTPClient(httpClient))
+	}
+
 	client := openai.NewClient(openaiClientOptions...)
 	return &openaiClient{
 		providerOptions: opts,
@@ -195,6 +200,9 @@ func (o *openaiClient) preparedParams(messages []openai.ChatCompletionMessagePar
 }
 
 func (o *openaiClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (response *ProviderResponse, err error) {
+	if o.providerOptions.model.API == models.APIResponses {
+		return o.sendResponses(ctx, messages, tools)
+	}
 	params := o.preparedParams(o.convertMessages(messages), o.convertTools(tools))
 	return o.sendWithParams(ctx, params)
 }
@@ -216,11 +224,13 @@ func (o *openaiClient) sendWithParams(ctx context.Context, params openai.ChatCom
 		o.logRequest()
 	}
 	attempts := 0
+	retries := newRetryWait()
 	for {
 		attempts++
 		openaiResponse, err := o.client.Chat.Completions.New(
 			ctx,
 			params,
+			o.cacheOptions(ctx)...,
 		)
 		// If there is an error we are going to see if we can retry the call
 		if err != nil {
@@ -230,14 +240,11 @@ func (o *openaiClient) sendWithParams(ctx context.Context, params openai.ChatCom
 				return nil, retryErr
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
+				if err := retries.wait(ctx, attempts, after, err, logRetry); err != nil {
 					request.Clear() // Clear request info on context cancellation
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
+					return nil, err
 				}
+				continue
 			}
 			request.Clear() // Clear request info on error
 			return nil, retryErr
@@ -266,6 +273,9 @@ func (o *openaiClient) sendWithParams(ctx context.Context, params openai.ChatCom
 }
 
 func (o *openaiClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	if o.providerOptions.model.API == models.APIResponses {
+		return o.streamResponses(ctx, messages, tools)
+	}
 	params := o.preparedParams(o.convertMessages(messages), o.convertTools(tools))
 	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
 		IncludeUsage: openai.Bool(true),
@@ -291,6 +301,7 @@ func (o *openaiClient) streamWithParams(ctx context.Context, params openai.ChatC
 	}
 
 	attempts := 0
+	retries := newRetryWait()
 	eventChan := make(chan ProviderEvent)
 
 	go func() {
@@ -310,6 +321,7 @@ func (o *openaiClient) streamWithParams(ctx context.Context, params openai.ChatC
 			openaiStream := o.client.Chat.Completions.NewStreaming(
 				ctx,
 				params,
+				o.cacheOptions(ctx)...,
 			)
 
 			acc := openai.ChatCompletionAccumulator{}
@@ -398,19 +410,13 @@ func (o *openaiClient) streamWithParams(ctx context.Context, params openai.ChatC
 				return
 			}
 			if retry {
-				logging.WarnPersist(fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries), logging.PersistTimeArg, time.Millisecond*time.Duration(after+100))
-				select {
-				case <-ctx.Done():
-					// context cancelled
+				if err := retries.wait(ctx, attempts, after, err, streamRetry(ctx, eventChan)); err != nil {
 					request.Clear() // Clear request info on context cancellation
-					if ctx.Err() != nil {
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-					}
+					eventChan <- ProviderEvent{Type: EventError, Error: err}
 					close(eventChan)
 					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
 				}
+				continue
 			}
 			request.Clear() // Clear request info on error
 			eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
@@ -423,31 +429,21 @@ func (o *openaiClient) streamWithParams(ctx context.Context, params openai.ChatC
 }
 
 func (o *openaiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	var apierr *openai.Error
-	if !errors.As(err, &apierr) {
-		return false, 0, err
-	}
-
-	if apierr.StatusCode != 429 && apierr.StatusCode != 500 {
-		return false, 0, err
-	}
+	return shouldRetry(attempts, err)
+}
 
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
+// cacheOptions routes the requests of a session to the same prompt cache.
+// OpenAI caches prompt prefixes implicitly, the key only improves the hit
+// rate, so it is only sent to OpenAI itself.
+func (o *openaiClient) cacheOptions(ctx context.Context) []option.RequestOption {
+	if o.options.disableCache || o.providerOptions.model.Provider != models.ProviderOpenAI {
+		return nil
 	}
-
-	retryMs := 0
-	retryAfterValues := apierr.Response.Header.Values("Retry-After")
-
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs = backoffMs + jitterMs
-	if len(retryAfterValues) > 0 {
-		if _, err := fmt.Sscanf(retryAfterValues[0], "%d", &retryMs); err == nil {
-			retryMs = retryMs * 1000
-		}
+	sessionID, ok := ctx.Value(tools.SessionIDContextKey).(string)
+	if !ok || sessionID == "" {
+		return nil
 	}
-	return true, int64(retryMs), nil
+	return []option.RequestOption{option.WithJSONSet("prompt_cache_key", sessionID)}
 }
 
 func (o *openaiClient) toolCalls(completion openai.ChatCompletion) []message.ToolCall {
@@ -0,0 +1,427 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/responses"
+	"github.com/openai/openai-go/shared"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/opencode-ai/opencode/internal/request"
+)
+
+// includeEncryptedReasoning asks for the reasoning of responses that are not
+// stored, so it can be sent back with the following requests.
+const includeEncryptedReasoning responses.ResponseIncludable = "reasoning.encrypted_content"
+
+// responsesEvent is a streamed event of the Responses API. The events are
+// decoded here because the SDK does not know the reasoning summary events.
+type responsesEvent struct {
+	Type         string            `json:"type"`
+	Delta        string            `json:"delta"`
+	ItemID       string            `json:"item_id"`
+	SummaryIndex int               `json:"summary_index"`
+	Item         responsesItem     `json:"item"`
+	Response     responsesResponse `json:"response"`
+}
+
+type responsesResponse struct {
+	ID                string          `json:"id"`
+	Status            string          `json:"status"`
+	Output            []responsesItem `json:"output"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Usage struct {
+		InputTokens        int64 `json:"input_tokens"`
+		OutputTokens       int64 `json:"output_tokens"`
+		InputTokensDetails struct {
+			CachedTokens int64 `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+	} `json:"usage"`
+}
+
+type responsesItem struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Content   []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Summary []struct {
+		Text string `json:"text"`
+	} `json:"summary"`
+	EncryptedContent string `json:"encrypted_content"`
+}
+
+// responsesParams builds a Responses API request. When the model chains its
+// responses and continued is set, only the messages after the last response
+// are sent along with its ID.
+func (o *openaiClient) responsesParams(messages []message.Message, tools []tools.BaseTool, continued bool) responses.ResponseNewParams {
+	model := o.providerOptions.model
+	params := responses.ResponseNewParams{
+		Model:           model.APIModel,
+		Instructions:    openai.String(o.providerOptions.systemMessage),
+		MaxOutputTokens: openai.Int(o.providerOptions.maxTokens),
+		Tools:           o.convertResponsesTools(tools),
+	}
+	if model.CanReason {
+		params.Reasoning = shared.ReasoningParam{Effort: shared.ReasoningEffort(o.options.reasoningEffort)}
+		params.Reasoning.WithExtraFields(map[string]any{"summary": "auto"})
+	}
+
+	if model.ChainResponses {
+		if continued {
+			if id, last := o.previousResponse(messages); id != "" {
+				params.PreviousResponseID = openai.String(id)
+				messages = messages[last+1:]
+			}
+		}
+	} else {
+		// Nothing is kept by OpenAI, the reasoning goes back with the messages
+		params.Store = openai.Bool(false)
+		if model.CanReason {
+			params.Include = []responses.ResponseIncludable{includeEncryptedReasoning}
+		}
+	}
+	params.Input = responses.ResponseNewParamsInputUnion{OfInputItemList: o.convertResponsesInput(messages)}
+	return params
+}
+
+// previousResponse returns the response ID of the last assistant message and
+// its index, when it was answered by the current model.
+func (o *openaiClient) previousResponse(messages []message.Message) (string, int) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != message.Assistant {
+			continue
+		}
+		state, ok := messages[i].ProviderState()
+		if !ok || messages[i].Model != o.providerOptions.model.ID {
+			return "", -1
+		}
+		return state.ResponseID, i
+	}
+	return "", -1
+}
+
+func (o *openaiClient) convertResponsesInput(messages []message.Message) responses.ResponseInputParam {
+	var input responses.ResponseInputParam
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			content := responses.ResponseInputMessageContentListParam{
+				{OfInputText: &responses.ResponseInputTextParam{Text: msg.Content().String()}},
+			}
+			for _, binaryContent := range msg.BinaryContent() {
+				content = append(content, responses.ResponseInputContentUnionParam{
+					OfInputImage: &responses.ResponseInputImageParam{
+						Detail:   responses.ResponseInputImageDetailAuto,
+						ImageURL: openai.String(binaryContent.String(models.ProviderOpenAI)),
+					},
+				})
+			}
+			input = append(input, responses.ResponseInputItemUnionParam{
+				OfMessage: &responses.EasyInputMessageParam{
+					Role:    responses.EasyInputMessageRoleUser,
+					Content: responses.EasyInputMessageContentUnionParam{OfInputItemContentList: content},
+				},
+			})
+
+		case message.Assistant:
+			// Reasoning only makes sense to the model that produced it
+			if state, ok := msg.ProviderState(); ok && msg.Model == o.providerOptions.model.ID {
+				for _, reasoning := range state.Reasoning {
+					if reasoning.EncryptedContent == "" {
+						continue
+					}
+					item := &responses.ResponseReasoningItemParam{
+						ID:      reasoning.ID,
+						Summary: make([]responses.ResponseReasoningItemSummaryParam, 0, len(reasoning.Summary)),
+					}
+					for _, summary := range reasoning.Summary {
+						item.Summary = append(item.Summary, responses.ResponseReasoningItemSummaryParam{Text: summary})
+					}
+					item.WithExtraFields(map[string]any{"encrypted_content": reasoning.EncryptedContent})
+					input = append(input, responses.ResponseInputItemUnionParam{OfReasoning: item})
+				}
+			}
+			if text := msg.Content().String(); text != "" {
+				input = append(input, responses.ResponseInputItemUnionParam{
+					OfMessage: &responses.EasyInputMessageParam{
+						Role:    responses.EasyInputMessageRoleAssistant,
+						Content: responses.EasyInputMessageContentUnionParam{OfString: openai.String(text)},
+					},
+				})
+			}
+			for _, call := range msg.ToolCalls() {
+				arguments := call.Input
+				if arguments == "" {
+					arguments = "{}"
+				}
+				input = append(input, responses.ResponseInputItemUnionParam{
+					OfFunctionCall: &responses.ResponseFunctionToolCallParam{
+						CallID:    call.ID,
+						Name:      call.Name,
+						Arguments: arguments,
+					},
+				})
+			}
+
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				input = append(input, responses.ResponseInputItemUnionParam{
+					OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
+						CallID: result.ToolCallID,
+						Output: result.Content,
+					},
+				})
+			}
+		}
+	}
+	return input
+}
+
+func (o *openaiClient) convertResponsesTools(tools []tools.BaseTool) []responses.ToolUnionParam {
+	responsesTools := make([]responses.ToolUnionParam, len(tools))
+	for i, tool := range tools {
+		info := tool.Info()
+		responsesTools[i] = responses.ToolUnionParam{
+			OfFunction: &responses.FunctionToolParam{
+				Name:        info.Name,
+				Description: openai.String(info.Description),
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": info.Parameters,
+					"required":   info.Required,
+				},
+			},
+		}
+	}
+	return responsesTools
+}
+
+// responsesResult turns a finished response into the provider response.
+func (o *openaiClient) responsesResult(r responsesResponse) (*ProviderResponse, error) {
+	if r.Status == "failed" {
+		if r.Error != nil {
+			return nil, fmt.Errorf("response failed: %s: %s", r.Error.Code, r.Error.Message)
+		}
+		return nil, errors.New("response failed")
+	}
+
+	var content strings.Builder
+	var toolCalls []message.ToolCall
+	state := message.ProviderState{}
+	if o.providerOptions.model.ChainResponses {
+		state.ResponseID = r.ID
+	}
+	for _, item := range r.Output {
+		switch item.Type {
+		case "message":
+			for _, part := range item.Content {
+				if part.Type == "output_text" {
+					content.WriteString(part.Text)
+				}
+			}
+		case "function_call":
+			toolCalls = append(toolCalls, message.ToolCall{
+				ID:       item.CallID,
+				Name:     item.Name,
+				Input:    item.Arguments,
+				Type:     "function",
+				Finished: true,
+			})
+		case "reasoning":
+			reasoning := message.ReasoningItem{ID: item.ID, EncryptedContent: item.EncryptedContent}
+			for _, summary := range item.Summary {
+				reasoning.Summary = append(reasoning.Summary, summary.Text)
+			}
+			state.Reasoning = append(state.Reasoning, reasoning)
+		}
+	}
+
+	finishReason := message.FinishReasonEndTurn
+	switch {
+	case len(toolCalls) > 0:
+		finishReason = message.FinishReasonToolUse
+	case r.Status == "incomplete" && r.IncompleteDetails != nil && r.IncompleteDetails.Reason == "max_output_tokens":
+		finishReason = message.FinishReasonMaxTokens
+	case r.Status == "incomplete":
+		finishReason = message.FinishReasonUnknown
+	}
+
+	cachedTokens := r.Usage.InputTokensDetails.CachedTokens
+	return &ProviderResponse{
+		Content:   content.String(),
+		ToolCalls: toolCalls,
+		Usage: TokenUsage{
+			InputTokens:     r.Usage.InputTokens - cachedTokens,
+			OutputTokens:    r.Usage.OutputTokens,
+			CacheReadTokens: cachedTokens,
+		},
+		FinishReason: finishReason,
+		State:        &state,
+	}, nil
+}
+
+// isPreviousResponseError reports whether the previous response of a chained
+// request is gone, in which case the whole conversation has to be sent.
+func isPreviousResponseError(err error) bool {
+	status, _ := errorStatus(err)
+	if status != http.StatusBadRequest && status != http.StatusNotFound {
+		return false
+	}
+	return strings.Contains(err.Error(), "previous_response") || strings.Contains(err.Error(), "Previous response")
+}
+
+func (o *openaiClient) logResponsesParams(params responses.ResponseNewParams) {
+	baseURL := "https://api.openai.com/v1"
+	if o.options.baseURL != "" {
+		baseURL = o.options.baseURL
+	}
+	request.SetCurrent(string(o.providerOptions.model.Provider), o.providerOptions.model.APIModel, baseURL)
+
+	if config.Get().Debug {
+		jsonData, _ := json.Marshal(params)
+		logging.Debug("Prepared messages", "messages", string(jsonData))
+		o.logRequest()
+	}
+}
+
+func (o *openaiClient) sendResponses(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	params := o.responsesParams(messages, tools, true)
+	o.logResponsesParams(params)
+	defer request.Clear()
+
+	attempts := 0
+	retries := newRetryWait()
+	for {
+		attempts++
+		resp, err := o.client.Responses.New(ctx, params, o.cacheOptions(ctx)...)
+		if err == nil {
+			var r responsesResponse
+			if err := json.Unmarshal([]byte(resp.RawJSON()), &r); err != nil {
+				return nil, err
+			}
+			return o.responsesResult(r)
+		}
+
+		if params.PreviousResponseID.IsPresent() && isPreviousResponseError(err) {
+			logging.Warn("Previous response is not available, sending the whole conversation", "error", err)
+			params = o.responsesParams(messages, tools, false)
+			continue
+		}
+		retry, after, retryErr := o.shouldRetry(attempts, err)
+		if retryErr != nil || !retry {
+			return nil, retryErr
+		}
+		if err := retries.wait(ctx, attempts, after, err, logRetry); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (o *openaiClient) streamResponses(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	params := o.responsesParams(messages, tools, true)
+	o.logResponsesParams(params)
+
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+		defer request.Clear()
+
+		attempts := 0
+		retries := newRetryWait()
+		for {
+			attempts++
+			stream := o.client.Responses.NewStreaming(ctx, params, o.cacheOptions(ctx)...)
+
+			var final *responsesResponse
+			// Streamed events name the function call item, not the call
+			callIDs := map[string]string{}
+			for stream.Next() {
+				var event responsesEvent
+				if err := json.Unmarshal([]byte(stream.Current().RawJSON()), &event); err != nil {
+					logging.Debug("Failed to decode response event", "error", err)
+					continue
+				}
+				switch event.Type {
+				case "response.output_text.delta":
+					eventChan <- ProviderEvent{Type: EventContentDelta, Content: event.Delta}
+				case "response.reasoning_summary_part.added":
+					if event.SummaryIndex > 0 {
+						eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: "\n\n"}
+					}
+				case "response.reasoning_summary_text.delta":
+					eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: event.Delta}
+				case "response.output_item.added":
+					if event.Item.Type == "function_call" {
+						callIDs[event.Item.ID] = event.Item.CallID
+						eventChan <- ProviderEvent{
+							Type:     EventToolUseStart,
+							ToolCall: &message.ToolCall{ID: event.Item.CallID, Name: event.Item.Name},
+						}
+					}
+				case "response.function_call_arguments.delta":
+					eventChan <- ProviderEvent{
+						Type:     EventToolUseDelta,
+						ToolCall: &message.ToolCall{ID: callIDs[event.ItemID], Input: event.Delta},
+					}
+				case "response.output_item.done":
+					if event.Item.Type == "function_call" {
+						eventChan <- ProviderEvent{Type: EventToolUseStop, ToolCall: &message.ToolCall{ID: event.Item.CallID}}
+					}
+				case "response.completed", "response.incomplete", "response.failed":
+					final = &event.Response
+				}
+			}
+
+			err := stream.Err()
+			if err == nil || errors.Is(err, io.EOF) {
+				if final == nil {
+					err = errors.New("response stream ended without a response")
+				} else {
+					var response *ProviderResponse
+					response, err = o.responsesResult(*final)
+					if err == nil {
+						eventChan <- ProviderEvent{Type: EventComplete, Response: response}
+						return
+					}
+				}
+			}
+
+			if params.PreviousResponseID.IsPresent() && isPreviousResponseError(err) {
+				logging.Warn("Previous response is not available, sending the whole conversation", "error", err)
+				params = o.responsesParams(messages, tools, false)
+				continue
+			}
+			retry, after, retryErr := o.shouldRetry(attempts, err)
+			if retryErr != nil || !retry {
+				eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
+				return
+			}
+			if err := retries.wait(ctx, attempts, after, err, streamRetry(ctx, eventChan)); err != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: err}
+				return
+			}
+		}
+	}()
+	return eventChan
+}
@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// responsesServer answers Responses API calls with the given handler.
+func responsesServer(t *testing.T, handler func(w http.ResponseWriter, body map[string]any)) *testServer {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request, data []byte, _ int) {
+		require.Equal(t, "/responses", r.URL.Path)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(data, &body))
+		handler(w, body)
+	})
+}
+
+func writeResponseEvents(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, event := range events {
+		var typed struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal([]byte(event), &typed)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+	}
+}
+
+func newTestResponsesProvider(t *testing.T, ts *testServer, chain bool) Provider {
+	model := models.OpenAIModels[models.O4Mini]
+	model.API = models.APIResponses
+	model.ChainResponses = chain
+	return newTestProvider(t, model,
+		WithSystemMessage("be brief"),
+		WithOpenAIOptions(WithOpenAIBaseURL(ts.URL)),
+	)
+}
+
+const responsesCompleted = `{"type":"response.completed","response":{"id":"resp_1","status":"completed","output":[` +
+	`{"type":"reasoning","id":"rs_1","summary":[{"type":"summary_text","text":"Looking."},{"type":"summary_text","text":"Found it."}],"encrypted_content":"enc-1"},` +
+	`{"type":"message","id":"msg_1","role":"assistant","content":[{"type":"output_text","text":"Let me look."}]},` +
+	`{"type":"function_call","id":"fc_1","call_id":"call_1","name":"view","arguments":"{\"file_path\":\"main.go\"}"}],` +
+	`"usage":{"input_tokens":10,"output_tokens":7,"input_tokens_details":{"cached_tokens":4}}}}`
+
+func TestResponsesStream(t *testing.T) {
+	ts := responsesServer(t, func(w http.ResponseWriter, body map[string]any) {
+		writeResponseEvents(w,
+			`{"type":"response.created","response":{"id":"resp_1","status":"in_progress","output":[]}}`,
+			`{"type":"response.reasoning_summary_part.added","item_id":"rs_1","summary_index":0}`,
+			`{"type":"response.reasoning_summary_text.delta","item_id":"rs_1","summary_index":0,"delta":"Looking."}`,
+			`{"type":"response.reasoning_summary_part.added","item_id":"rs_1","summary_index":1}`,
+			`{"type":"response.reasoning_summary_text.delta","item_id":"rs_1","summary_index":1,"delta":"Found it."}`,
+			`{"type":"response.output_text.delta","item_id":"msg_1","delta":"Let me look."}`,
+			`{"type":"response.output_item.added","item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"view","arguments":""}}`,
+			`{"type":"response.function_call_arguments.delta","item_id":"fc_1","delta":"{\"file_path\":\"main.go\"}"}`,
+			`{"type":"response.output_item.done","item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"view","arguments":"{\"file_path\":\"main.go\"}"}}`,
+			responsesCompleted,
+		)
+	})
+
+	var thinking, content, arguments string
+	var started, stopped []string
+	var response *ProviderResponse
+	for event := range newTestResponsesProvider(t, ts, false).StreamResponse(context.Background(), helloMessages, nil) {
+		switch event.Type {
+		case EventThinkingDelta:
+			thinking += event.Thinking
+		case EventContentDelta:
+			content += event.Content
+		case EventToolUseStart:
+			started = append(started, event.ToolCall.ID)
+		case EventToolUseDelta:
+			assert.Equal(t, "call_1", event.ToolCall.ID)
+			arguments += event.ToolCall.Input
+		case EventToolUseStop:
+			stopped = append(stopped, event.ToolCall.ID)
+		case EventError:
+			t.Fatal(event.Error)
+		case EventComplete:
+			response = event.Response
+		}
+	}
+
+	assert.Equal(t, "Looking.\n\nFound it.", thinking)
+	assert.Equal(t, "Let me look.", content)
+	assert.Equal(t, `{"file_path":"main.go"}`, arguments)
+	assert.Equal(t, []string{"call_1"}, started)
+	assert.Equal(t, []string{"call_1"}, stopped)
+
+	require.NotNil(t, response)
+	assert.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+	assert.Equal(t, TokenUsage{InputTokens: 6, OutputTokens: 7, CacheReadTokens: 4}, response.Usage)
+	require.Len(t, response.ToolCalls, 1)
+	assert.Equal(t, "view", response.ToolCalls[0].Name)
+	require.NotNil(t, response.State)
+	assert.Empty(t, response.State.ResponseID)
+	assert.Equal(t, []message.ReasoningItem{{ID: "rs_1", Summary: []string{"Looking.", "Found it."}, EncryptedContent: "enc-1"}}, response.State.Reasoning)
+
+	require.Equal(t, 1, ts.Requests())
+	body := requestBody[map[string]any](t, ts, 0)
+	assert.Equal(t, "be brief", body["instructions"])
+	assert.Equal(t, false, body["store"])
+	assert.Equal(t, []any{"reasoning.encrypted_content"}, body["include"])
+	assert.Equal(t, map[string]any{"effort": "medium", "summary": "auto"}, body["reasoning"])
+}
+
+// toolTurn is the conversation after the model asked for a tool and got its
+// result.
+func toolTurn(state message.ProviderState) []message.Message {
+	assistant := message.Message{
+		Role:  message.Assistant,
+		Model: models.O4Mini,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me look."},
+			message.ToolCall{ID: "call_1", Name: "view", Input: `{"file_path":"main.go"}`, Finished: true},
+		},
+	}
+	assistant.SetProviderState(state)
+	return append(append([]message.Message{}, helloMessages...),
+		assistant,
+		message.Message{
+			Role:  message.Tool,
+			Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call_1", Content: "package main"}},
+		},
+	)
+}
+
+const responseDone = `{"id":"resp_2","object":"response","status":"completed","output":[` +
+	`{"type":"message","id":"msg_2","role":"assistant","content":[{"type":"output_text","text":"It is empty."}]}],` +
+	`"usage":{"input_tokens":20,"output_tokens":3}}`
+
+func TestResponsesCarryReasoning(t *testing.T) {
+	ts := responsesServer(t, func(w http.ResponseWriter, body map[string]any) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, responseDone)
+	})
+
+	messages := toolTurn(message.ProviderState{
+		Reasoning: []message.ReasoningItem{{ID: "rs_1", Summary: []string{"Looking."}, EncryptedContent: "enc-1"}},
+	})
+	res, err := newTestResponsesProvider(t, ts, false).SendMessages(context.Background(), messages, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "It is empty.", res.Content)
+	assert.Equal(t, message.FinishReasonEndTurn, res.FinishReason)
+
+	require.Equal(t, 1, ts.Requests())
+	body := requestBody[map[string]any](t, ts, 0)
+	input := body["input"].([]any)
+	require.Len(t, input, 5)
+	assert.Equal(t, map[string]any{
+		"type":              "reasoning",
+		"id":                "rs_1",
+		"summary":           []any{map[string]any{"type": "summary_text", "text": "Looking."}},
+		"encrypted_content": "enc-1",
+	}, input[1])
+	assert.Equal(t, "function_call", input[3].(map[string]any)["type"])
+	assert.Equal(t, map[string]any{"type": "function_call_output", "call_id": "call_1", "output": "package main"}, input[4])
+	assert.NotContains(t, body, "previous_response_id")
+}
+
+func TestResponsesChain(t *testing.T) {
+	t.Run("sends the messages after the previous response", func(t *testing.T) {
+		ts := responsesServer(t, func(w http.ResponseWriter, body map[string]any) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, responseDone)
+		})
+
+		messages := toolTurn(message.ProviderState{ResponseID: "resp_1"})
+		res, err := newTestResponsesProvider(t, ts, true).SendMessages(context.Background(), messages, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "resp_2", res.State.ResponseID)
+
+		require.Equal(t, 1, ts.Requests())
+		body := requestBody[map[string]any](t, ts, 0)
+		assert.Equal(t, "resp_1", body["previous_response_id"])
+		assert.NotContains(t, body, "store")
+		input := body["input"].([]any)
+		require.Len(t, input, 1)
+		assert.Equal(t, "function_call_output", input[0].(map[string]any)["type"])
+	})
+
+	t.Run("falls back to the whole conversation", func(t *testing.T) {
+		ts := responsesServer(t, func(w http.ResponseWriter, body map[string]any) {
+			w.Header().Set("Content-Type", "application/json")
+			if _, ok := body["previous_response_id"]; ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error":{"message":"Previous response with id 'resp_1' not found.","type":"invalid_request_error","param":"previous_response_id","code":"previous_response_not_found"}}`)
+				return
+			}
+			fmt.Fprint(w, responseDone)
+		})
+
+		messages := toolTurn(message.ProviderState{ResponseID: "resp_1"})
+		_, err := newTestResponsesProvider(t, ts, true).SendMessages(context.Background(), messages, nil)
+		require.NoError(t, err)
+
+		require.Equal(t, 2, ts.Requests())
+		body := requestBody[map[string]any](t, ts, 1)
+		assert.NotContains(t, body, "previous_response_id")
+		assert.Len(t, body["input"], 4)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/llm/models"
 	"github.com/opencode-ai/opencode/internal/llm/tools"
 	"github.com/opencode-ai/opencode/internal/message"
@@ -12,8 +13,6 @@ import (
 
 type EventType string
 
-const maxRetries = 8
-
 const (
 	EventContentStart  EventType = "content_start"
 	EventToolUseStart  EventType = "tool_use_start"
@@ -39,6 +38,8 @@ type ProviderResponse struct {
 	ToolCalls    []message.ToolCall
 	Usage        TokenUsage
 	FinishReason message.FinishReason
+	// State is kept with the message for the following requests
+	State *message.ProviderState
 }
 
 type ProviderEvent struct {
@@ -69,6 +70,7 @@ type providerClientOptions struct {
 	geminiOptions    []GeminiOption
 	bedrockOptions   []BedrockOption
 	copilotOptions   []CopilotOption
+	ollamaOptions    []OllamaOption
 }
 
 type ProviderClientOption func(*providerClientOptions)
@@ -167,10 +169,34 @@ func NewProvider(providerName models.ModelProvider, opts ...ProviderClientOption
 			options: clientOptions,
 			client:  newOpenAIClient(clientOptions),
 		}, nil
+	case models.ProviderOllama:
+		// A custom provider may only take the name when discovery found no
+		// Ollama server
+		if _, custom := config.GetCustomProvider(providerName); custom {
+			break
+		}
+		return &baseProvider[OllamaClient]{
+			options: clientOptions,
+			client:  newOllamaClient(clientOptions),
+		}, nil
 	case models.ProviderMock:
 		// TODO: implement mock client for test
 		panic("not implemented")
 	}
+	if custom, ok := config.GetCustomProvider(providerName); ok {
+		clientOptions.openaiOptions = append(clientOptions.openaiOptions,
+			WithOpenAIBaseURL(custom.BaseURL),
+		)
+		if len(custom.Headers) > 0 {
+			clientOptions.openaiOptions = append(clientOptions.openaiOptions,
+				WithOpenAIExtraHeaders(custom.Headers),
+			)
+		}
+		return &baseProvider[OpenAIClient]{
+			options: clientOptions,
+			client:  newOpenAIClient(clientOptions),
+		}, nil
+	}
 	return nil, fmt.Errorf("provider not supported: %s", providerName)
 }
 
@@ -252,3 +278,9 @@ func WithCopilotOptions(copilotOptions ...CopilotOption) ProviderClientOption {
 		options.copilotOptions = copilotOptions
 	}
 }
+
+func WithOllamaOptions(ollamaOptions ...OllamaOption) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.ollamaOptions = ollamaOptions
+	}
+}
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+var helloMessages = []message.Message{{
+	Role:  message.User,
+	Parts: []message.ContentPart{message.TextContent{Text: "hello"}},
+}}
+
+// testServer stands in for the API of a provider. It keeps the body of every
+// request and answers with its handler, which is told the number of the
+// request, starting at 1.
+type testServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	bodies [][]byte
+}
+
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, body []byte, n int)) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, body)
+		n := len(s.bodies)
+		s.mu.Unlock()
+		handler(w, r, body, n)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns how many requests the server received.
+func (s *testServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+// requestBody decodes the JSON body of the i-th request the server received.
+func requestBody[T any](t *testing.T, s *testServer, i int) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.Less(t, i, len(s.bodies))
+	var body T
+	require.NoError(t, json.Unmarshal(s.bodies[i], &body))
+	return body
+}
+
+// newTestProvider loads the default configuration and creates a provider of
+// the model with the options, which point it at a test server.
+func newTestProvider(t *testing.T, model models.Model, opts ...ProviderClientOption) Provider {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	p, err := NewProvider(model.Provider, append([]ProviderClientOption{WithModel(model)}, opts...)...)
+	require.NoError(t, err)
+	return p
+}
@@ -0,0 +1,247 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/logging"
+	"google.golang.org/genai"
+)
+
+const (
+	// maxRetries and maxRetryWait apply when no configuration is loaded.
+	maxRetries   = 8
+	maxRetryWait = 5 * time.Minute
+
+	// retryBaseDelay is the wait before the first retry. It doubles with
+	// every further attempt up to retryMaxDelay.
+	retryBaseDelay = 2 * time.Second
+	retryMaxDelay  = time.Minute
+
+	// statusOverloaded is what Anthropic answers when its API is overloaded.
+	statusOverloaded = 529
+)
+
+// retryPolicy is shared by all providers: rate limits, server errors and
+// overloads are retried with a jittered exponential backoff, unless the
+// response says how long to wait.
+type retryPolicy struct {
+	maxRetries int
+	maxWait    time.Duration
+}
+
+func newRetryPolicy() retryPolicy {
+	cfg := config.Get()
+	if cfg == nil {
+		return retryPolicy{maxRetries: maxRetries, maxWait: maxRetryWait}
+	}
+	return retryPolicy{
+		maxRetries: cfg.Retry.MaxRetries,
+		maxWait:    time.Duration(cfg.Retry.MaxWait) * time.Second,
+	}
+}
+
+// shouldRetry reports whether a request that failed with err should be
+// retried and after how many milliseconds.
+func shouldRetry(attempts int, err error) (bool, int64, error) {
+	status, header := errorStatus(err)
+	if !retryableStatus(status) {
+		return false, 0, err
+	}
+	return newRetryPolicy().retry(attempts, header, err)
+}
+
+// retry returns the delay before the next attempt of a request that failed
+// with a retryable error, or an error once the attempts are used up.
+func (p retryPolicy) retry(attempts int, header http.Header, err error) (bool, int64, error) {
+	if attempts > p.maxRetries {
+		return false, 0, fmt.Errorf("maximum retry attempts reached: %d retries: %w", p.maxRetries, err)
+	}
+	delay, ok := retryAfter(header)
+	if !ok {
+		delay = backoff(attempts)
+	}
+	return true, delay.Milliseconds(), nil
+}
+
+// errorStatus returns the HTTP status and headers of a failed request. Errors
+// sent in the middle of a stream carry no status, so it is guessed from the
+// message.
+func errorStatus(err error) (int, http.Header) {
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode, responseHeader(openaiErr.Response)
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode, responseHeader(anthropicErr.Response)
+	}
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		return genaiErr.Code, nil
+	}
+	var ollamaErr *ollamaError
+	if errors.As(err, &ollamaErr) {
+		return ollamaErr.StatusCode, ollamaErr.Header
+	}
+
+	msg := err.Error()
+	switch {
+	case contains(msg, "overloaded"):
+		return statusOverloaded, nil
+	case contains(msg, "rate limit", "rate_limit", "quota exceeded", "too many requests"):
+		return http.StatusTooManyRequests, nil
+	}
+	return 0, nil
+}
+
+func responseHeader(resp *http.Response) http.Header {
+	if resp == nil {
+		return nil
+	}
+	return resp.Header
+}
+
+// retryableStatus reports whether a request that failed with status may
+// succeed when sent again.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return status >= 500
+}
+
+// Unavailable reports whether a request failed because the provider can not
+// answer for now: it is overloaded, failing or keeps rate limiting after the
+// retries. Other errors, such as invalid requests, would fail with any model.
+func Unavailable(err error) bool {
+	status, _ := errorStatus(err)
+	return retryableStatus(status)
+}
+
+// backoff returns the exponential delay before the retry following the
+// given attempt, with up to 20% jitter so that clients hitting the same limit
+// spread out.
+func backoff(attempts int) time.Duration {
+	delay := retryMaxDelay
+	if shift := attempts - 1; shift < 16 {
+		delay = min(retryBaseDelay<<max(shift, 0), retryMaxDelay)
+	}
+	return delay + rand.N(delay/5+1)
+}
+
+// retryAfter returns how long the response asks to wait before retrying:
+// Retry-After and retry-after-ms, or the x-ratelimit-reset-* header of a rate
+// limit that is used up.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	if header == nil {
+		return 0, false
+	}
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+	if after := header.Get("Retry-After"); after != "" {
+		if seconds, err := strconv.Atoi(after); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(after); err == nil {
+			return max(time.Until(at), 0), true
+		}
+	}
+
+	var wait time.Duration
+	found := false
+	for _, limit := range []string{"requests", "tokens"} {
+		if remaining := header.Get("X-Ratelimit-Remaining-" + limit); remaining != "" && remaining != "0" {
+			continue
+		}
+		if reset, ok := parseReset(header.Get("X-Ratelimit-Reset-" + limit)); ok {
+			wait = max(wait, reset)
+			found = true
+		}
+	}
+	return wait, found
+}
+
+// parseReset parses the time until a rate limit resets, given either as a
+// duration such as "6m0s" or as a number of seconds.
+func parseReset(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, true
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// retryWait waits out the delays between the attempts of one request and
+// keeps the total within the wait budget of the policy.
+type retryWait struct {
+	policy retryPolicy
+	waited time.Duration
+}
+
+func newRetryWait() *retryWait {
+	return &retryWait{policy: newRetryPolicy()}
+}
+
+// wait sleeps for delay milliseconds before the next attempt, passing a
+// countdown to notify every second. It fails when the context is done or when
+// the delay would exceed the wait budget, returning cause in that case.
+func (w *retryWait) wait(ctx context.Context, attempts int, delay int64, cause error, notify func(string)) error {
+	d := time.Duration(delay) * time.Millisecond
+	if w.waited+d > w.policy.maxWait {
+		return fmt.Errorf("retry wait of %s exceeds the budget of %s: %w", w.waited+d, w.policy.maxWait, cause)
+	}
+	w.waited += d
+
+	deadline := time.Now().Add(d)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+		seconds := int(math.Ceil(remaining.Seconds()))
+		notify(fmt.Sprintf("Provider unavailable, retrying in %ds (retry %d of %d)", seconds, attempts, w.policy.maxRetries))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(remaining, time.Second)):
+		}
+	}
+}
+
+// retryNoticeTime keeps a countdown notice on screen until the next one.
+const retryNoticeTime = 1500 * time.Millisecond
+
+// logRetry shows the countdown of a request that has no event stream in the
+// status bar.
+func logRetry(msg string) {
+	logging.WarnPersist(msg, logging.PersistTimeArg, retryNoticeTime)
+}
+
+// streamRetry sends the countdown of a streamed request as warning events.
+func streamRetry(ctx context.Context, events chan<- ProviderEvent) func(string) {
+	return func(msg string) {
+		select {
+		case events <- ProviderEvent{Type: EventWarning, Content: msg}:
+		case <-ctx.Done():
+		}
+	}
+}
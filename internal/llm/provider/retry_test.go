@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{"none", http.Header{}, 0, false},
+		{"seconds", http.Header{"Retry-After": {"3"}}, 3 * time.Second, true},
+		{"milliseconds", http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"1"}}, 250 * time.Millisecond, true},
+		{"used up requests", http.Header{"X-Ratelimit-Remaining-Requests": {"0"}, "X-Ratelimit-Reset-Requests": {"1m30s"}}, 90 * time.Second, true},
+		{"longest reset", http.Header{"X-Ratelimit-Reset-Requests": {"2s"}, "X-Ratelimit-Reset-Tokens": {"7.5"}}, 7500 * time.Millisecond, true},
+		{"limit left", http.Header{"X-Ratelimit-Remaining-Tokens": {"100"}, "X-Ratelimit-Reset-Tokens": {"10s"}}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retryAfter(tt.header)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, base := range map[int]time.Duration{1: 2 * time.Second, 3: 8 * time.Second, 10: time.Minute, 100: time.Minute} {
+		delay := backoff(attempts)
+		assert.GreaterOrEqual(t, delay, base, attempts)
+		assert.LessOrEqual(t, delay, base+base/5, attempts)
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	for _, status := range []int{408, 429, 500, 502, 503, 529} {
+		assert.True(t, retryableStatus(status), status)
+	}
+	for _, status := range []int{0, 400, 401, 404, 501} {
+		assert.False(t, retryableStatus(status), status)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	assert.True(t, Unavailable(&anthropic.Error{StatusCode: statusOverloaded}))
+	assert.True(t, Unavailable(fmt.Errorf("maximum retry attempts reached: 8 retries: %w", &ollamaError{StatusCode: http.StatusTooManyRequests})))
+	assert.True(t, Unavailable(fmt.Errorf("stream error: Overloaded")))
+	assert.False(t, Unavailable(&anthropic.Error{StatusCode: http.StatusBadRequest}))
+	assert.False(t, Unavailable(fmt.Errorf("prompt is too long")))
+}
+
+func TestAnthropicFailOnOverload(t *testing.T) {
+	overloaded := &anthropic.Error{StatusCode: statusOverloaded, Response: &http.Response{Header: http.Header{"Retry-After": {"1"}}}}
+
+	retry, after, err := (&anthropicClient{}).shouldRetry(1, overloaded)
+	assert.True(t, retry)
+	assert.Equal(t, int64(1000), after)
+	assert.NoError(t, err)
+
+	client := &anthropicClient{options: anthropicOptions{failOnOverload: true}}
+	retry, _, err = client.shouldRetry(1, overloaded)
+	assert.False(t, retry)
+	assert.Equal(t, overloaded, err)
+
+	retry, _, err = client.shouldRetry(1, fmt.Errorf(`received error while streaming: {"type":"overloaded_error"}`))
+	assert.False(t, retry)
+	assert.Error(t, err)
+}
+
+// setRetryConfig replaces the retry configuration for the test.
+func setRetryConfig(t *testing.T, retry config.RetryConfig) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	previous := config.Get().Retry
+	config.Get().Retry = retry
+	t.Cleanup(func() { config.Get().Retry = previous })
+}
+
+// flakyServer answers the first failures requests with status and the
+// headers, and the rest with a completion, streamed when asked to.
+func flakyServer(t *testing.T, failures int, status int, header http.Header) *testServer {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request, body []byte, n int) {
+		if n <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":{"message":"slow down","type":"rate_limit_error"}}`)
+			return
+		}
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`+"\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","created":1,"model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"hello"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	})
+}
+
+func newTestOpenAIProvider(t *testing.T, ts *testServer) Provider {
+	return newTestProvider(t, models.OpenAIModels[models.GPT4o], WithOpenAIOptions(WithOpenAIBaseURL(ts.URL)))
+}
+
+func TestRetrySend(t *testing.T) {
+	t.Run("retries rate limits", func(t *testing.T) {
+		setRetryConfig(t, config.RetryConfig{MaxRetries: 3, MaxWait: 10})
+		ts := flakyServer(t, 2, http.StatusTooManyRequests, http.Header{"Retry-After-Ms": {"10"}})
+
+		res, err := newTestOpenAIProvider(t, ts).SendMessages(context.Background(), helloMessages, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", res.Content)
+		assert.Equal(t, 3, ts.Requests())
+	})
+
+	t.Run("retries server errors", func(t *testing.T) {
+		setRetryConfig(t, config.RetryConfig{MaxRetries: 3, MaxWait: 10})
+		ts := flakyServer(t, 1, http.StatusServiceUnavailable, http.Header{
+			"X-Ratelimit-Remaining-Requests": {"0"},
+			"X-Ratelimit-Reset-Requests":     {"10ms"},
+		})
+
+		_, err := newTestOpenAIProvider(t, ts).SendMessages(context.Background(), helloMessages, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 2, ts.Requests())
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		setRetryConfig(t, config.RetryConfig{MaxRetries: 1, MaxWait: 10})
+		ts := flakyServer(t, 5, http.StatusTooManyRequests, http.Header{"Retry-After-Ms": {"10"}})
+
+		_, err := newTestOpenAIProvider(t, ts).SendMessages(context.Background(), helloMessages, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "maximum retry attempts reached")
+		assert.Equal(t, 2, ts.Requests())
+	})
+
+	t.Run("gives up when the wait exceeds the budget", func(t *testing.T) {
+		setRetryConfig(t, config.RetryConfig{MaxRetries: 3, MaxWait: 1})
+		ts := flakyServer(t, 5, http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}})
+
+		_, err := newTestOpenAIProvider(t, ts).SendMessages(context.Background(), helloMessages, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds the budget")
+		assert.Equal(t, 1, ts.Requests())
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		setRetryConfig(t, config.RetryConfig{MaxRetries: 3, MaxWait: 10})
+		ts := flakyServer(t, 5, http.StatusBadRequest, nil)
+
+		_, err := newTestOpenAIProvider(t, ts).SendMessages(context.Background(), helloMessages, nil)
+		require.Error(t, err)
+		assert.Equal(t, 1, ts.Requests())
+	})
+}
+
+func TestRetryStream(t *testing.T) {
+	setRetryConfig(t, config.RetryConfig{MaxRetries: 3, MaxWait: 10})
+	ts := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	var warnings []string
+	var response *ProviderResponse
+	for event := range newTestOpenAIProvider(t, ts).StreamResponse(context.Background(), helloMessages, nil) {
+		switch event.Type {
+		case EventWarning:
+			warnings = append(warnings, event.Content)
+		case EventError:
+			t.Fatal(event.Error)
+		case EventComplete:
+			response = event.Response
+		}
+	}
+	require.NotNil(t, response)
+	assert.Equal(t, "hello", response.Content)
+	assert.Equal(t, 2, ts.Requests())
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "retrying in 1s (retry 1 of 3)")
+}
@@ -30,5 +30,7 @@ func newVertexAIClient(opts providerClientOptions) VertexAIClient {
 		providerOptions: opts,
 		options:         geminiOpts,
 		client:          client,
+		caches:          make(map[string]*geminiCache),
+		sessionCaches:   make(map[string]string),
 	}
 }
@@ -61,11 +61,16 @@ func newXAIClient(opts providerClientOptions) XAIClient {
 	// Build OpenAI client options
 	clientOptions := []option.RequestOption{
 		option.WithBaseURL("https://api.x.ai/v1"),
+		option.WithMaxRetries(0),
 	}
 	if opts.apiKey != "" {
 		clientOptions = append(clientOptions, option.WithAPIKey(opts.apiKey))
 	}
 
+	if httpClient := cassetteClient(); httpClient != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(httpClient))
+	}
+
 	base := &openaiClient{
 		providerOptions: opts,
 		options:         openaiOpts,
@@ -351,24 +356,8 @@ func (x *xaiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
 	// Check for xAI content errors
 	var xaiErr *xaiContentError
 	if errors.As(err, &xaiErr) {
-		if attempts > maxRetries {
-			return false, 0, fmt.Errorf("maximum retry attempts reached for xAI rate limit: %d retries", maxRetries)
-		}
-
-		// Use exponential backoff with jitter
-		backoffMs := 2000 * (1 << (attempts - 1))
-		jitterMs := int(float64(backoffMs) * 0.2)
-		retryMs := backoffMs + jitterMs
-
-		// Log the retry attempt
-		logging.WarnPersist(
-			fmt.Sprintf("xAI content error detected: %s. Retrying... attempt %d of %d",
-				strings.TrimSpace(xaiErr.Content), attempts, maxRetries),
-			logging.PersistTimeArg,
-			time.Millisecond*time.Duration(retryMs+100),
-		)
-
-		return true, int64(retryMs), nil
+		logging.Warn("xAI content error detected", "content", strings.TrimSpace(xaiErr.Content), "attempt", attempts)
+		return newRetryPolicy().retry(attempts, nil, err)
 	}
 
 	// Check for OpenAI API errors
@@ -393,6 +382,7 @@ func (x *xaiClient) streamWithParams(ctx context.Context, params openai.ChatComp
 	go func() {
 		defer close(eventChan)
 		attempts := 0
+		retries := newRetryWait()
 
 		for {
 			attempts++
@@ -459,26 +449,17 @@ func (x *xaiClient) streamWithParams(ctx context.Context, params openai.ChatComp
 				}
 
 				if retry {
-					// Show retry message
-					logging.WarnPersist(
-						fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, maxRetries),
-						logging.PersistTimeArg,
-						time.Millisecond*time.Duration(after+100),
-					)
-
 					// Clear request info during retry
 					request.Clear()
 
 					// Wait before retrying
-					select {
-					case <-ctx.Done():
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
+					if err := retries.wait(ctx, attempts, after, streamErr, streamRetry(ctx, eventChan)); err != nil {
+						eventChan <- ProviderEvent{Type: EventError, Error: err}
 						return
-					case <-time.After(time.Duration(after) * time.Millisecond):
-						// Re-set request info for next attempt
-						request.SetCurrent(string(x.providerOptions.model.Provider), x.providerOptions.model.APIModel, "https://api.x.ai/v1")
-						continue // Try again
 					}
+					// Re-set request info for next attempt
+					request.SetCurrent(string(x.providerOptions.model.Provider), x.providerOptions.model.APIModel, "https://api.x.ai/v1")
+					continue // Try again
 				}
 
 				// No retry - send error
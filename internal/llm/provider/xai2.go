@@ -22,7 +22,6 @@ import (
 )
 
 const (
-	xai2MaxRetries = 8
 	// xAI specific error patterns
 	quotaExceededPattern = "credits or reached its monthly spending limit"
 )
@@ -62,6 +61,7 @@ func NewXAI2Client(apiKey string, providerOptions providerClientOptions) (*xai2C
 
 	opts := []option.RequestOption{
 		option.WithHeader("HTTP-Referer", "https://api.x.ai"),
+		option.WithMaxRetries(0),
 	}
 
 	if apiKey != "" {
@@ -71,6 +71,10 @@ func NewXAI2Client(apiKey string, providerOptions providerClientOptions) (*xai2C
 		opts = append(opts, option.WithBaseURL(options.baseURL))
 	}
 
+	if httpClient := cassetteClient(); httpClient != nil {
+		opts = append(opts, option.WithHTTPClient(httpClient))
+	}
+
 	client := openai.NewClient(opts...)
 
 	return &xai2Client{
@@ -129,6 +133,7 @@ func (x *xai2Client) streamWithParams(ctx context.Context, params openai.ChatCom
 	go func() {
 		defer close(eventChan)
 		attempts := 0
+		retries := newRetryWait()
 
 		for {
 			attempts++
@@ -204,26 +209,17 @@ func (x *xai2Client) streamWithParams(ctx context.Context, params openai.ChatCom
 				}
 
 				if retry {
-					// Show retry message
-					logging.WarnPersist(
-						fmt.Sprintf("Retrying due to rate limit... attempt %d of %d", attempts, xai2MaxRetries),
-						logging.PersistTimeArg,
-						time.Millisecond*time.Duration(after+100),
-					)
-
 					// Clear request info during retry
 					request.Clear()
 
 					// Wait before retrying
-					select {
-					case <-ctx.Done():
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
+					if err := retries.wait(ctx, attempts, after, streamErr, streamRetry(ctx, eventChan)); err != nil {
+						eventChan <- ProviderEvent{Type: EventError, Error: err}
 						return
-					case <-time.After(time.Duration(after) * time.Millisecond):
-						// Re-set request info for next attempt
-						request.SetCurrent(string(x.providerOptions.model.Provider), x.providerOptions.model.APIModel, x.options.baseURL)
-						continue // Try again
 					}
+					// Re-set request info for next attempt
+					request.SetCurrent(string(x.providerOptions.model.Provider), x.providerOptions.model.APIModel, x.options.baseURL)
+					continue // Try again
 				}
 
 				// No retry - send error
@@ -325,52 +321,21 @@ func (x *xai2Client) Model() models.Model {
 
 // shouldRetry handles xAI-specific retry logic
 func (x *xai2Client) shouldRetry(attempts int, err error) (bool, int64, error) {
-	// Check if we've exceeded max retries
-	if attempts > xai2MaxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached: %d retries", xai2MaxRetries)
-	}
-
 	// Check for xAI content errors
 	var xaiErr *xai2ContentError
 	if errors.As(err, &xaiErr) {
-		// Use exponential backoff with jitter
-		backoffMs := 2000 * (1 << (attempts - 1))
-		jitterMs := int(float64(backoffMs) * 0.2)
-		retryMs := backoffMs + jitterMs
-
-		logging.WarnPersist(
-			fmt.Sprintf("xAI content error detected: %s. Retrying... attempt %d of %d",
-				strings.TrimSpace(xaiErr.Content), attempts, xai2MaxRetries),
-			logging.PersistTimeArg,
-			time.Millisecond*time.Duration(retryMs+100),
-		)
-
-		return true, int64(retryMs), nil
+		logging.Warn("xAI content error detected", "content", strings.TrimSpace(xaiErr.Content), "attempt", attempts)
+		return newRetryPolicy().retry(attempts, nil, err)
 	}
 
-	// Check for OpenAI API errors
+	// Check if this is a quota/billing error from xAI
 	var apierr *openai.Error
-	if errors.As(err, &apierr) {
-		// Check if this is a quota/billing error from xAI
-		if apierr.StatusCode == 429 && strings.Contains(err.Error(), quotaExceededPattern) {
-			// This is a permanent error, don't retry
-			return false, 0, fmt.Errorf("xAI quota exceeded: %s", err.Error())
-		}
-
-		// Handle standard rate limits
-		if apierr.StatusCode == 429 || apierr.StatusCode == 500 {
-			// Use exponential backoff
-			backoffMs := 2000 * (1 << (attempts - 1))
-			retryAfter := int64(backoffMs)
-
-			// xAI doesn't provide retry-after headers in the error object
-
-			return true, retryAfter, nil
-		}
+	if errors.As(err, &apierr) && apierr.StatusCode == 429 && strings.Contains(err.Error(), quotaExceededPattern) {
+		// This is a permanent error, don't retry
+		return false, 0, fmt.Errorf("xAI quota exceeded: %s", err.Error())
 	}
 
-	// Don't retry other errors
-	return false, 0, err
+	return shouldRetry(attempts, err)
 }
 
 // isErrorContent checks if the response content matches known error patterns
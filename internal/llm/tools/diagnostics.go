@@ -65,6 +65,10 @@ func (b *diagnosticsTool) Info() ToolInfo {
 	}
 }
 
+func (b *diagnosticsTool) Concurrent(ToolCall) bool {
+	return true
+}
+
 func (b *diagnosticsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params DiagnosticsParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
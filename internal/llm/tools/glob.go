@@ -10,7 +10,6 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/fileutil"
 	"github.com/opencode-ai/opencode/internal/logging"
 )
@@ -87,6 +86,10 @@ func (g *globTool) Info() ToolInfo {
 	}
 }
 
+func (g *globTool) Concurrent(ToolCall) bool {
+	return true
+}
+
 func (g *globTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params GlobParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
@@ -99,7 +102,7 @@ func (g *globTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 
 	searchPath := params.Path
 	if searchPath == "" {
-		searchPath = config.WorkingDirectory()
+		searchPath = WorkingDirectory(ctx)
 	}
 
 	files, truncated, err := globFiles(params.Pattern, searchPath, 100)
@@ -14,8 +14,8 @@ import (
 	"strings"
 	"time"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/fileutil"
+	"github.com/opencode-ai/opencode/internal/logging"
 )
 
 type GrepParams struct {
@@ -40,6 +40,7 @@ type GrepResponseMetadata struct {
 type grepTool struct{}
 
 const (
+	maxGrepMatches  = 100
 	GrepToolName    = "grep"
 	grepDescription = `Fast content search tool that finds files containing specific text or patterns, returning matching file paths sorted by modification time (newest first).
 
@@ -67,7 +68,7 @@ COMMON INCLUDE PATTERN EXAMPLES:
 - '*.go' - Only search Go files
 
 LIMITATIONS:
-- Results are limited to 100 files (newest first)
+- Results are limited to 100 matches (newest first), all matches can be read with the read_output tool
 - Performance depends on the number of files being searched
 - Very large binary files may be skipped
 - Hidden files (starting with '.') are skipped
@@ -109,6 +110,10 @@ func (g *grepTool) Info() ToolInfo {
 	}
 }
 
+func (g *grepTool) Concurrent(ToolCall) bool {
+	return true
+}
+
 // escapeRegexPattern escapes special regex characters so they're treated as literal characters
 func escapeRegexPattern(pattern string) string {
 	specialChars := []string{"\\", ".", "+", "*", "?", "(", ")", "[", "]", "{", "}", "^", "$", "|"}
@@ -139,39 +144,36 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 
 	searchPath := params.Path
 	if searchPath == "" {
-		searchPath = config.WorkingDirectory()
+		searchPath = WorkingDirectory(ctx)
 	}
 
-	matches, truncated, err := searchFiles(searchPattern, searchPath, params.Include, 100)
+	matches, err := searchFiles(searchPattern, searchPath, params.Include)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error searching files: %w", err)
 	}
+	allMatches := matches
+	truncated := len(matches) > maxGrepMatches
+	if truncated {
+		matches = matches[:maxGrepMatches]
+	}
 
 	var output string
 	if len(matches) == 0 {
 		output = "No files found"
 	} else {
 		output = fmt.Sprintf("Found %d matches\n", len(matches))
+		output += formatGrepMatches(matches)
 
-		currentFile := ""
-		for _, match := range matches {
-			if currentFile != match.path {
-				if currentFile != "" {
-					output += "\n"
-				}
-				currentFile = match.path
-				output += fmt.Sprintf("%s:\n", match.path)
-			}
-			if match.lineNum > 0 {
-				output += fmt.Sprintf("  Line %d: %s\n", match.lineNum, match.lineText)
+		if truncated {
+			sessionID, _ := GetContextValues(ctx)
+			handle := call.ID + "-matches"
+			if err := writeSpill(sessionID, handle, formatGrepMatches(allMatches)); err != nil {
+				logging.Warn("failed to spill grep matches", "handle", handle, "error", err)
+				output += "\n(Results are truncated. Consider using a more specific path or pattern.)"
 			} else {
-				output += fmt.Sprintf("  %s\n", match.path)
+				output += fmt.Sprintf("\n(Results are truncated. All %d matches are saved as output handle %q, read them with the %s tool or use a more specific path or pattern.)", len(allMatches), handle, ReadOutputToolName)
 			}
 		}
-
-		if truncated {
-			output += "\n(Results are truncated. Consider using a more specific path or pattern.)"
-		}
 	}
 
 	return WithResponseMetadata(
@@ -183,12 +185,34 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	), nil
 }
 
-func searchFiles(pattern, rootPath, include string, limit int) ([]grepMatch, bool, error) {
+// formatGrepMatches lists matches grouped by file.
+func formatGrepMatches(matches []grepMatch) string {
+	var output string
+	currentFile := ""
+	for _, match := range matches {
+		if currentFile != match.path {
+			if currentFile != "" {
+				output += "\n"
+			}
+			currentFile = match.path
+			output += fmt.Sprintf("%s:\n", match.path)
+		}
+		if match.lineNum > 0 {
+			output += fmt.Sprintf("  Line %d: %s\n", match.lineNum, match.lineText)
+		} else {
+			output += fmt.Sprintf("  %s\n", match.path)
+		}
+	}
+	return output
+}
+
+// searchFiles returns all matches, the most recently modified files first.
+func searchFiles(pattern, rootPath, include string) ([]grepMatch, error) {
 	matches, err := searchWithRipgrep(pattern, rootPath, include)
 	if err != nil {
 		matches, err = searchFilesWithRegex(pattern, rootPath, include)
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
 	}
 
@@ -196,12 +220,7 @@ func searchFiles(pattern, rootPath, include string, limit int) ([]grepMatch, boo
 		return matches[i].modTime.After(matches[j].modTime)
 	})
 
-	truncated := len(matches) > limit
-	if truncated {
-		matches = matches[:limit]
-	}
-
-	return matches, truncated, nil
+	return matches, nil
 }
 
 func searchWithRipgrep(pattern, path, include string) ([]grepMatch, error) {
@@ -7,8 +7,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/opencode-ai/opencode/internal/config"
 )
 
 type LSParams struct {
@@ -88,6 +86,10 @@ func (l *lsTool) Info() ToolInfo {
 	}
 }
 
+func (l *lsTool) Concurrent(ToolCall) bool {
+	return true
+}
+
 func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params LSParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
@@ -96,11 +98,11 @@ func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 
 	searchPath := params.Path
 	if searchPath == "" {
-		searchPath = config.WorkingDirectory()
+		searchPath = WorkingDirectory(ctx)
 	}
 
 	if !filepath.IsAbs(searchPath) {
-		searchPath = filepath.Join(config.WorkingDirectory(), searchPath)
+		searchPath = filepath.Join(WorkingDirectory(ctx), searchPath)
 	}
 
 	if _, err := os.Stat(searchPath); os.IsNotExist(err) {
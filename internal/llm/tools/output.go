@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type ReadOutputParams struct {
+	Handle string `json:"handle"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+type readOutputTool struct{}
+
+const (
+	ReadOutputToolName    = "read_output"
+	readOutputDescription = `Reads the full output of an earlier tool call that was too long to return at once.
+
+WHEN TO USE THIS TOOL:
+- Use when a tool result says lines were left out and names an output handle
+- Use to look at the part of a long command output, search result or page that was left out
+
+HOW TO USE:
+- Provide the output handle from the tool result
+- Optionally specify an offset to start reading from a specific line
+- Optionally specify a limit to control how many lines are read
+
+LIMITATIONS:
+- Default reading limit is 2000 lines
+- Lines longer than 2000 characters are truncated
+- Output is cut short when it gets too long, continue with a larger offset
+- Handles only refer to outputs of the current session`
+)
+
+func NewReadOutputTool() BaseTool {
+	return &readOutputTool{}
+}
+
+func (r *readOutputTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ReadOutputToolName,
+		Description: readOutputDescription,
+		Parameters: map[string]any{
+			"handle": map[string]any{
+				"type":        "string",
+				"description": "The output handle given in the tool result",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "The line number to start reading from (0-based)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "The number of lines to read (defaults to 2000)",
+			},
+		},
+		Required: []string{"handle"},
+	}
+}
+
+func (r *readOutputTool) Concurrent(ToolCall) bool {
+	return true
+}
+
+func (r *readOutputTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ReadOutputParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.Handle == "" {
+		return NewTextErrorResponse("handle is required"), nil
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+	if params.Limit <= 0 {
+		params.Limit = DefaultReadLimit
+	}
+
+	sessionID, _ := GetContextValues(ctx)
+	path, err := SpillPath(sessionID, params.Handle)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return NewTextErrorResponse(fmt.Sprintf("Output not found: %s", params.Handle)), nil
+		}
+		return ToolResponse{}, fmt.Errorf("error accessing output: %w", err)
+	}
+
+	content, lineCount, err := readTextFile(path, params.Offset, params.Limit)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading output: %w", err)
+	}
+	lines := strings.Split(content, "\n")
+	size := 0
+	for i, line := range lines {
+		size += len(line) + 1
+		if size > MaxOutputLength && i > 0 {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	output := "<output>\n" + addLineNumbers(strings.Join(lines, "\n"), params.Offset+1)
+	if end := params.Offset + len(lines); lineCount > end {
+		output += fmt.Sprintf("\n\n(Output has %d lines. Use 'offset' parameter to read beyond line %d)", lineCount, end)
+	}
+	output += "\n</output>"
+	return NewTextResponse(output), nil
+}
@@ -8,7 +8,6 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/diff"
 	"github.com/opencode-ai/opencode/internal/history"
 	"github.com/opencode-ai/opencode/internal/logging"
@@ -99,10 +98,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	// Identify all files needed for the patch and verify they've been read
 	filesToRead := diff.IdentifyFilesNeeded(params.PatchText)
 	for _, filePath := range filesToRead {
-		absPath := filePath
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := resolvePath(ctx, filePath)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
 		}
 
 		if getLastReadTime(absPath).IsZero() {
@@ -134,13 +132,12 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	// Check for new files to ensure they don't already exist
 	filesToAdd := diff.IdentifyFilesAdded(params.PatchText)
 	for _, filePath := range filesToAdd {
-		absPath := filePath
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := resolvePath(ctx, filePath)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
 		}
 
-		_, err := os.Stat(absPath)
+		_, err = os.Stat(absPath)
 		if err == nil {
 			return NewTextErrorResponse(fmt.Sprintf("file already exists and cannot be added: %s", absPath)), nil
 		} else if !os.IsNotExist(err) {
@@ -151,10 +148,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	// Load all required files
 	currentFiles := make(map[string]string)
 	for _, filePath := range filesToRead {
-		absPath := filePath
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := resolvePath(ctx, filePath)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
 		}
 
 		content, err := os.ReadFile(absPath)
@@ -179,6 +175,14 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	if err != nil {
 		return NewTextErrorResponse(fmt.Sprintf("failed to create commit from patch: %s", err)), nil
 	}
+	for _, change := range commit.Changes {
+		if change.MovePath == nil {
+			continue
+		}
+		if _, err := resolvePath(ctx, *change.MovePath); err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+	}
 
 	// Get session ID and message ID
 	sessionID, messageID := GetContextValues(ctx)
@@ -259,10 +263,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 	// Apply the changes to the filesystem
 	err = diff.ApplyCommit(commit, func(path string, content string) error {
-		absPath := path
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := resolvePath(ctx, path)
+		if err != nil {
+			return err
 		}
 
 		// Create parent directories if needed
@@ -273,10 +276,9 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 		return os.WriteFile(absPath, []byte(content), 0o644)
 	}, func(path string) error {
-		absPath := path
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
+		absPath, err := resolvePath(ctx, path)
+		if err != nil {
+			return err
 		}
 		return os.Remove(absPath)
 	})
@@ -290,11 +292,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	totalRemovals := 0
 
 	for path, change := range commit.Changes {
-		absPath := path
-		if !filepath.IsAbs(absPath) {
-			wd := config.WorkingDirectory()
-			absPath = filepath.Join(wd, absPath)
-		}
+		absPath, _ := resolvePath(ctx, path)
 		changedFiles = append(changedFiles, absPath)
 
 		oldContent := ""
@@ -314,7 +312,13 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 		// Update history
 		file, err := p.files.GetByPathAndSession(ctx, absPath, sessionID)
-		if err != nil && change.Type != diff.ActionAdd {
+		if err != nil && change.Type == diff.ActionAdd {
+			// Record that the file did not exist before the patch
+			_, err = p.files.CreateNew(ctx, sessionID, absPath)
+			if err != nil {
+				logging.Debug("Error creating file history", "error", err)
+			}
+		} else if err != nil {
 			// If not adding a file, create history entry for existing file
 			_, err = p.files.Create(ctx, sessionID, absPath, oldContent)
 			if err != nil {
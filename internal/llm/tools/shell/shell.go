@@ -58,6 +58,13 @@ func GetPersistentShell(workingDir string) *PersistentShell {
 	return shellInstance
 }
 
+// NewPersistentShell starts a shell in cwd that is independent from the shared
+// one returned by GetPersistentShell. The caller must Close it. It returns nil
+// if the shell can not be started.
+func NewPersistentShell(cwd string) *PersistentShell {
+	return newPersistentShell(cwd)
+}
+
 func newPersistentShell(cwd string) *PersistentShell {
 	// Get shell configuration from config
 	cfg := config.Get()
@@ -159,6 +159,10 @@ func (t *sourcegraphTool) Info() ToolInfo {
 	}
 }
 
+func (t *sourcegraphTool) Concurrent(ToolCall) bool {
+	return true
+}
+
 func (t *sourcegraphTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params SourcegraphParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
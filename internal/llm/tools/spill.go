@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/logging"
+)
+
+// Tool outputs longer than MaxOutputLength are spilled to a file in the
+// session's spill directory. The model gets the head and the tail of the
+// output and a handle to read the rest with the read_output tool.
+
+// SpillDir returns the directory the spilled outputs of a session are kept in.
+func SpillDir(sessionID string) string {
+	return filepath.Join(config.Get().Data.Directory, "spill", sessionID)
+}
+
+// SpillPath returns the file of a spilled output.
+func SpillPath(sessionID, handle string) (string, error) {
+	if handle == "" || handle != filepath.Base(handle) || strings.HasPrefix(handle, ".") {
+		return "", fmt.Errorf("invalid output handle: %s", handle)
+	}
+	return filepath.Join(SpillDir(sessionID), handle+".txt"), nil
+}
+
+// SpilledOutputs returns the handles of the outputs spilled for a tool call.
+func SpilledOutputs(sessionID, toolCallID string) []string {
+	entries, err := os.ReadDir(SpillDir(sessionID))
+	if err != nil {
+		return nil
+	}
+	var handles []string
+	for _, entry := range entries {
+		handle, ok := strings.CutSuffix(entry.Name(), ".txt")
+		if ok && (handle == toolCallID || strings.HasPrefix(handle, toolCallID+"-")) {
+			handles = append(handles, handle)
+		}
+	}
+	sort.Strings(handles)
+	return handles
+}
+
+func writeSpill(sessionID, handle, content string) error {
+	path, err := SpillPath(sessionID, handle)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0o644)
+}
+
+// SpillOutput returns content unchanged when it fits in MaxOutputLength.
+// Longer content is saved under handle and replaced with its head and tail
+// and a note on how to read the rest. When saving fails the middle is dropped.
+func SpillOutput(sessionID, handle, content string) string {
+	if len(content) <= MaxOutputLength {
+		return content
+	}
+
+	halfLength := MaxOutputLength / 2
+	start := content[:halfLength]
+	end := content[len(content)-halfLength:]
+	firstLine := countLines(start)
+	lastLine := firstLine + countLines(content[halfLength:len(content)-halfLength]) - 1
+	totalLines := countLines(content)
+
+	if err := writeSpill(sessionID, handle, content); err != nil {
+		logging.Warn("failed to spill tool output", "handle", handle, "error", err)
+		truncatedLinesCount := countLines(content[halfLength : len(content)-halfLength])
+		return fmt.Sprintf("%s\n\n... [%d lines truncated] ...\n\n%s", start, truncatedLinesCount, end)
+	}
+	return fmt.Sprintf(
+		"%s\n\n... [lines %d to %d of %d left out, the full output is saved as output handle %q: use the %s tool with an offset of %d to read them] ...\n\n%s",
+		start, firstLine, lastLine, totalLines, handle, ReadOutputToolName, firstLine-1, end,
+	)
+}
+
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	return len(strings.Split(s, "\n"))
+}
@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillOutput(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Data.Directory = t.TempDir()
+
+	var lines []string
+	for i := 1; i <= 5000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	content := strings.Join(lines, "\n")
+
+	assert.Equal(t, "short", SpillOutput("s1", "call1", "short"))
+	assert.Empty(t, SpilledOutputs("s1", "call1"))
+
+	output := SpillOutput("s1", "call1", content)
+	assert.Less(t, len(output), MaxOutputLength+500)
+	assert.True(t, strings.HasPrefix(output, "line 1\n"))
+	assert.True(t, strings.HasSuffix(output, "line 5000"))
+	assert.Contains(t, output, `output handle "call1"`)
+	assert.Equal(t, []string{"call1"}, SpilledOutputs("s1", "call1"))
+
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "s1")
+	input, _ := json.Marshal(ReadOutputParams{Handle: "call1", Offset: 2500, Limit: 2})
+	response, err := NewReadOutputTool().Run(ctx, ToolCall{Input: string(input)})
+	require.NoError(t, err)
+	assert.False(t, response.IsError)
+	assert.Contains(t, response.Content, "  2501|line 2501\n  2502|line 2502")
+	assert.Contains(t, response.Content, "Output has 5000 lines")
+
+	for _, handle := range []string{"../s2/call1", "..", ""} {
+		input, _ := json.Marshal(ReadOutputParams{Handle: handle})
+		response, err := NewReadOutputTool().Run(ctx, ToolCall{Input: string(input)})
+		require.NoError(t, err)
+		assert.True(t, response.IsError, handle)
+	}
+}
@@ -71,6 +71,20 @@ type BaseTool interface {
 	Run(ctx context.Context, params ToolCall) (ToolResponse, error)
 }
 
+// ConcurrentTool is implemented by tools whose calls can run at the same time
+// as other concurrent calls from the same message. Concurrent calls must not
+// modify files or ask for permissions.
+type ConcurrentTool interface {
+	BaseTool
+	Concurrent(call ToolCall) bool
+}
+
+// IsConcurrent reports whether the call to the tool may run in parallel.
+func IsConcurrent(tool BaseTool, call ToolCall) bool {
+	c, ok := tool.(ConcurrentTool)
+	return ok && c.Concurrent(call)
+}
+
 func GetContextValues(ctx context.Context) (string, string) {
 	sessionID := ctx.Value(SessionIDContextKey)
 	messageID := ctx.Value(MessageIDContextKey)
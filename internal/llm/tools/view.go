@@ -10,7 +10,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/opencode-ai/opencode/internal/config"
 	"github.com/opencode-ai/opencode/internal/logging"
 	"github.com/opencode-ai/opencode/internal/lsp"
 )
@@ -95,6 +94,10 @@ func (v *viewTool) Info() ToolInfo {
 	}
 }
 
+func (v *viewTool) Concurrent(ToolCall) bool {
+	return true
+}
+
 // Run implements Tool.
 func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params ViewParams
@@ -110,7 +113,7 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	// Handle relative paths
 	filePath := params.FilePath
 	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(config.WorkingDirectory(), filePath)
+		filePath = filepath.Join(WorkingDirectory(ctx), filePath)
 	}
 
 	// Check if file exists
@@ -250,7 +253,7 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 	var lines []string
 	lineCount = offset
 
-	for scanner.Scan() && len(lines) < limit {
+	for len(lines) < limit && scanner.Scan() {
 		lineCount++
 		lineText := scanner.Text()
 		if len(lineText) > MaxLineLength {
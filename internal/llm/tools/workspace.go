@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/tools/shell"
+)
+
+type workspaceContextKey struct{}
+
+// workspace is a working directory other than the project one, with the shell
+// that runs commands in it.
+type workspace struct {
+	dir   string
+	shell *shell.PersistentShell
+}
+
+// WithWorkspace makes the tools run with ctx resolve paths against dir and
+// execute commands in sh instead of the project working directory.
+func WithWorkspace(ctx context.Context, dir string, sh *shell.PersistentShell) context.Context {
+	return context.WithValue(ctx, workspaceContextKey{}, workspace{dir: dir, shell: sh})
+}
+
+// WorkingDirectory returns the directory the tools run with ctx work in.
+func WorkingDirectory(ctx context.Context) string {
+	if ws, ok := ctx.Value(workspaceContextKey{}).(workspace); ok {
+		return ws.dir
+	}
+	return config.WorkingDirectory()
+}
+
+// resolvePath makes path absolute against the working directory of ctx. When
+// ctx has a workspace, paths outside of it are rejected so an agent working
+// in a worktree cannot change the project behind it.
+func resolvePath(ctx context.Context, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(WorkingDirectory(ctx), path)
+	}
+	path = filepath.Clean(path)
+	ws, ok := ctx.Value(workspaceContextKey{}).(workspace)
+	if !ok {
+		return path, nil
+	}
+	rel, err := filepath.Rel(ws.dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside of the workspace %s", path, ws.dir)
+	}
+	return path, nil
+}
+
+func workspaceShell(ctx context.Context) *shell.PersistentShell {
+	if ws, ok := ctx.Value(workspaceContextKey{}).(workspace); ok && ws.shell != nil {
+		return ws.shell
+	}
+	return shell.GetPersistentShell(config.WorkingDirectory())
+}
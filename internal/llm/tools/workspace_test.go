@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePath(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	project := config.WorkingDirectory()
+	worktree := t.TempDir()
+	ws := WithWorkspace(context.Background(), worktree, nil)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		path string
+		want string
+	}{
+		{"relative in project", context.Background(), "a.go", filepath.Join(project, "a.go")},
+		{"absolute in project", context.Background(), filepath.Join(worktree, "a.go"), filepath.Join(worktree, "a.go")},
+		{"relative in workspace", ws, "dir/a.go", filepath.Join(worktree, "dir", "a.go")},
+		{"absolute in workspace", ws, filepath.Join(worktree, "a.go"), filepath.Join(worktree, "a.go")},
+		{"absolute outside workspace", ws, filepath.Join(project, "a.go"), ""},
+		{"relative escaping workspace", ws, "../a.go", ""},
+		{"workspace itself", ws, worktree, worktree},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePath(tt.ctx, tt.path)
+			if tt.want == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWriteOutsideWorkspace(t *testing.T) {
+	project := t.TempDir()
+	ctx := WithWorkspace(context.Background(), t.TempDir(), nil)
+	target := filepath.Join(project, "main.go")
+
+	input, err := json.Marshal(WriteParams{FilePath: target, Content: "package main"})
+	require.NoError(t, err)
+	response, err := NewWriteTool(nil, nil, nil).Run(ctx, ToolCall{Name: WriteToolName, Input: string(input)})
+	require.NoError(t, err)
+
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "outside of the workspace")
+	_, err = os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -26,6 +26,7 @@ const (
 	FinishReasonCanceled         FinishReason = "canceled"
 	FinishReasonError            FinishReason = "error"
 	FinishReasonPermissionDenied FinishReason = "permission_denied"
+	FinishReasonLimitReached     FinishReason = "limit_reached"
 
 	// Should never happen
 	FinishReasonUnknown FinishReason = "unknown"
@@ -44,6 +45,38 @@ func (tc ReasoningContent) String() string {
 }
 func (ReasoningContent) isPart() {}
 
+// ProviderState is what a provider returned with a message to receive back in
+// later requests to the same model: the ID of the response, the reasoning
+// items, whose content can be encrypted, the thought signatures and the
+// signed thinking blocks.
+type ProviderState struct {
+	ResponseID string          `json:"response_id,omitempty"`
+	Reasoning  []ReasoningItem `json:"reasoning,omitempty"`
+	// Signatures are the Gemini thought signatures by tool call ID, the one
+	// of the text under an empty ID.
+	Signatures map[string][]byte `json:"signatures,omitempty"`
+	Thinking   []ThinkingBlock   `json:"thinking,omitempty"`
+}
+
+func (ProviderState) isPart() {}
+
+// ReasoningItem is a reasoning step of the OpenAI Responses API.
+type ReasoningItem struct {
+	ID               string   `json:"id"`
+	Summary          []string `json:"summary,omitempty"`
+	EncryptedContent string   `json:"encrypted_content,omitempty"`
+}
+
+// ThinkingBlock is an Anthropic thinking block with its signature. It goes
+// before the tool call with ToolCallID, or before the text when empty.
+// Redacted blocks only carry their encrypted data.
+type ThinkingBlock struct {
+	ToolCallID   string `json:"tool_call_id,omitempty"`
+	Thinking     string `json:"thinking,omitempty"`
+	Signature    string `json:"signature,omitempty"`
+	RedactedData string `json:"redacted_data,omitempty"`
+}
+
 type TextContent struct {
 	Text string `json:"text"`
 }
@@ -116,6 +149,12 @@ type Message struct {
 	Model     models.ModelID
 	CreatedAt int64
 	UpdatedAt int64
+	// Seq orders the message among the messages and file versions of all
+	// sessions, even those created within the same second.
+	Seq int64
+	// Summary marks the summary a compacted session continues from in the
+	// history sent to providers. It is not stored.
+	Summary bool
 }
 
 func (m *Message) Content() TextContent {
@@ -136,6 +175,15 @@ func (m *Message) ReasoningContent() ReasoningContent {
 	return ReasoningContent{}
 }
 
+func (m *Message) ProviderState() (ProviderState, bool) {
+	for _, part := range m.Parts {
+		if c, ok := part.(ProviderState); ok {
+			return c, true
+		}
+	}
+	return ProviderState{}, false
+}
+
 func (m *Message) ImageURLContent() []ImageURLContent {
 	imageURLContents := make([]ImageURLContent, 0)
 	for _, part := range m.Parts {
@@ -236,6 +284,17 @@ func (m *Message) AppendReasoningContent(delta string) {
 	}
 }
 
+// SetProviderState replaces the provider state of the message.
+func (m *Message) SetProviderState(state ProviderState) {
+	for i, part := range m.Parts {
+		if _, ok := part.(ProviderState); ok {
+			m.Parts[i] = state
+			return
+		}
+	}
+	m.Parts = append(m.Parts, state)
+}
+
 func (m *Message) FinishToolCall(toolCallID string) {
 	for i, part := range m.Parts {
 		if c, ok := part.(ToolCall); ok {
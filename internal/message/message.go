@@ -27,6 +27,7 @@ type Service interface {
 	List(ctx context.Context, sessionID string) ([]Message, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	DeleteFrom(ctx context.Context, sessionID, messageID string) error
 }
 
 type service struct {
@@ -98,6 +99,37 @@ func (s *service) DeleteSessionMessages(ctx context.Context, sessionID string) e
 	return nil
 }
 
+// DeleteFrom deletes messageID and every message created after it in the
+// session. The messages are deleted by a single statement, so a failure never
+// leaves part of them behind.
+func (s *service) DeleteFrom(ctx context.Context, sessionID, messageID string) error {
+	messages, err := s.List(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	start := -1
+	for i, message := range messages {
+		if message.ID == messageID {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return fmt.Errorf("message %s not found in session %s", messageID, sessionID)
+	}
+	err = s.q.DeleteMessagesFrom(ctx, db.DeleteMessagesFromParams{
+		SessionID: sessionID,
+		Seq:       messages[start].Seq,
+	})
+	if err != nil {
+		return err
+	}
+	for _, message := range messages[start:] {
+		s.Publish(pubsub.DeletedEvent, message)
+	}
+	return nil
+}
+
 func (s *service) Update(ctx context.Context, message Message) error {
 	parts, err := marshallParts(message.Parts)
 	if err != nil {
@@ -157,19 +189,21 @@ func (s *service) fromDBItem(item db.Message) (Message, error) {
 		Model:     models.ModelID(item.Model.String),
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
+		Seq:       item.Seq,
 	}, nil
 }
 
 type partType string
 
 const (
-	reasoningType  partType = "reasoning"
-	textType       partType = "text"
-	imageURLType   partType = "image_url"
-	binaryType     partType = "binary"
-	toolCallType   partType = "tool_call"
-	toolResultType partType = "tool_result"
-	finishType     partType = "finish"
+	reasoningType     partType = "reasoning"
+	providerStateType partType = "provider_state"
+	textType          partType = "text"
+	imageURLType      partType = "image_url"
+	binaryType        partType = "binary"
+	toolCallType      partType = "tool_call"
+	toolResultType    partType = "tool_result"
+	finishType        partType = "finish"
 )
 
 type partWrapper struct {
@@ -186,6 +220,8 @@ func marshallParts(parts []ContentPart) ([]byte, error) {
 		switch part.(type) {
 		case ReasoningContent:
 			typ = reasoningType
+		case ProviderState:
+			typ = providerStateType
 		case TextContent:
 			typ = textType
 		case ImageURLContent:
@@ -236,6 +272,12 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 				return nil, err
 			}
 			parts = append(parts, part)
+		case providerStateType:
+			part := ProviderState{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		case textType:
 			part := TextContent{}
 			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
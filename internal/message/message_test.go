@@ -0,0 +1,53 @@
+package message
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteFrom(t *testing.T) {
+	ctx := context.Background()
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Data.Directory = t.TempDir()
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	defer conn.Close()
+	q := db.New(conn)
+	_, err = q.CreateSession(ctx, db.CreateSessionParams{ID: "session", Title: "session"})
+	require.NoError(t, err)
+	messages := NewService(q)
+
+	var ids []string
+	for _, text := range []string{"first", "second", "third"} {
+		msg, err := messages.Create(ctx, "session", CreateMessageParams{
+			Role:  User,
+			Parts: []ContentPart{TextContent{Text: text}},
+		})
+		require.NoError(t, err)
+		ids = append(ids, msg.ID)
+	}
+	// Everything happened within the same second
+	_, err = conn.Exec("UPDATE messages SET created_at = 1")
+	require.NoError(t, err)
+
+	events := messages.Subscribe(ctx)
+	require.NoError(t, messages.DeleteFrom(ctx, "session", ids[1]))
+	left, err := messages.List(ctx, "session")
+	require.NoError(t, err)
+	require.Len(t, left, 1)
+	assert.Equal(t, ids[0], left[0].ID)
+	for _, id := range ids[1:] {
+		event := <-events
+		assert.Equal(t, pubsub.DeletedEvent, event.Type)
+		assert.Equal(t, id, event.Payload.ID)
+	}
+
+	assert.Error(t, messages.DeleteFrom(ctx, "session", ids[1]))
+}
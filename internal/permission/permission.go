@@ -44,6 +44,9 @@ type Service interface {
 type permissionService struct {
 	*pubsub.Broker[PermissionRequest]
 
+	// mu guards sessionPermissions and autoApproveSessions, which concurrent
+	// agents read and update.
+	mu                  sync.Mutex
 	sessionPermissions  []PermissionRequest
 	pendingRequests     sync.Map
 	autoApproveSessions []string
@@ -54,6 +57,8 @@ func (s *permissionService) GrantPersistant(permission PermissionRequest) {
 	if ok {
 		respCh.(chan bool) <- true
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.sessionPermissions = append(s.sessionPermissions, permission)
 }
 
@@ -72,9 +77,6 @@ func (s *permissionService) Deny(permission PermissionRequest) {
 }
 
 func (s *permissionService) Request(opts CreatePermissionRequest) bool {
-	if slices.Contains(s.autoApproveSessions, opts.SessionID) {
-		return true
-	}
 	dir := filepath.Dir(opts.Path)
 	if dir == "." {
 		dir = config.WorkingDirectory()
@@ -89,10 +91,8 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 		Params:      opts.Params,
 	}
 
-	for _, p := range s.sessionPermissions {
-		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
-			return true
-		}
+	if s.granted(permission) {
+		return true
 	}
 
 	respCh := make(chan bool, 1)
@@ -107,7 +107,22 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	return resp
 }
 
+// granted reports whether the requests of the session are approved on their
+// own, or the permission was granted for the whole session.
+func (s *permissionService) granted(permission PermissionRequest) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.Contains(s.autoApproveSessions, permission.SessionID) {
+		return true
+	}
+	return slices.ContainsFunc(s.sessionPermissions, func(p PermissionRequest) bool {
+		return p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path
+	})
+}
+
 func (s *permissionService) AutoApproveSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.autoApproveSessions = append(s.autoApproveSessions, sessionID)
 }
 
@@ -117,3 +132,21 @@ func NewPermissionService() Service {
 		sessionPermissions: make([]PermissionRequest, 0),
 	}
 }
+
+type modeService struct {
+	Service
+	mode config.PermissionMode
+}
+
+func (s *modeService) Request(opts CreatePermissionRequest) bool {
+	return s.mode == config.PermissionAuto
+}
+
+// WithMode returns a Service that answers every request on its own when mode
+// is auto or deny and asks the user through s otherwise.
+func WithMode(s Service, mode config.PermissionMode) Service {
+	if mode == config.PermissionAuto || mode == config.PermissionDeny {
+		return &modeService{Service: s, mode: mode}
+	}
+	return s
+}
@@ -3,23 +3,27 @@ package session
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/message"
 	"github.com/opencode-ai/opencode/internal/pubsub"
 )
 
 type Session struct {
-	ID               string
-	ParentSessionID  string
-	Title            string
-	MessageCount     int64
-	PromptTokens     int64
-	CompletionTokens int64
-	SummaryMessageID string
-	Cost             float64
-	CreatedAt        int64
-	UpdatedAt        int64
+	ID                  string
+	ParentSessionID     string
+	Title               string
+	MessageCount        int64
+	PromptTokens        int64
+	CompletionTokens    int64
+	SummaryMessageID    string
+	ForkedFromMessageID string
+	Cost                float64
+	CreatedAt           int64
+	UpdatedAt           int64
 }
 
 type Service interface {
@@ -27,15 +31,20 @@ type Service interface {
 	Create(ctx context.Context, title string) (Session, error)
 	CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error)
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
+	Fork(ctx context.Context, sessionID, messageID string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
+	ListAgentSessions(ctx context.Context) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
 	Delete(ctx context.Context, id string) error
+	AddCost(ctx context.Context, at time.Time, cost float64) error
+	DailyCost(ctx context.Context, day time.Time) (float64, error)
 }
 
 type service struct {
 	*pubsub.Broker[Session]
-	q db.Querier
+	db *sql.DB
+	q  *db.Queries
 }
 
 func (s *service) Create(ctx context.Context, title string) (Session, error) {
@@ -79,6 +88,136 @@ func (s *service) CreateTitleSession(ctx context.Context, parentSessionID string
 	return session, nil
 }
 
+// Fork creates a child session of sessionID holding a copy of its messages up
+// to and including messageID, together with the file history recorded until
+// then. Tool results that answer the fork message are copied along with it so
+// the new session can be continued directly.
+func (s *service) Fork(ctx context.Context, sessionID, messageID string) (Session, error) {
+	parent, err := s.q.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return Session{}, err
+	}
+	messages, err := s.q.ListMessagesBySession(ctx, sessionID)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	end := -1
+	for i, msg := range messages {
+		if msg.ID == messageID {
+			end = i + 1
+			break
+		}
+	}
+	if end == -1 {
+		return Session{}, fmt.Errorf("message %s not found in session %s", messageID, sessionID)
+	}
+	for end < len(messages) && messages[end].Role == string(message.Tool) {
+		end++
+	}
+	messages = messages[:end]
+
+	// The fork is created in a single transaction so a failed copy never
+	// leaves a partial session behind.
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.q.WithTx(tx)
+
+	dbSession, err := qtx.CreateSession(ctx, db.CreateSessionParams{
+		ID:                  uuid.New().String(),
+		ParentSessionID:     sql.NullString{String: parent.ID, Valid: true},
+		Title:               parent.Title + " (fork)",
+		ForkedFromMessageID: sql.NullString{String: messageID, Valid: true},
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	if err := copyHistory(ctx, qtx, parent, dbSession, messages); err != nil {
+		return Session{}, err
+	}
+	dbSession, err = qtx.GetSessionByID(ctx, dbSession.ID)
+	if err != nil {
+		return Session{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Session{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
+
+// copyHistory copies messages and the parent's file versions created up to the
+// last of them into fork.
+func copyHistory(ctx context.Context, q db.Querier, parent, fork db.Session, messages []db.Message) error {
+	files, err := q.ListFilesBySession(ctx, parent.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	cutoff := messages[len(messages)-1].Seq
+	for i, file := range files {
+		if file.Seq > cutoff {
+			files = files[:i]
+			break
+		}
+	}
+
+	// Both are copied in their original order so that the copies are given
+	// sequence numbers in the same order.
+	ids := make(map[string]string, len(messages))
+	for len(messages) > 0 || len(files) > 0 {
+		if len(files) == 0 || (len(messages) > 0 && messages[0].Seq < files[0].Seq) {
+			msg := messages[0]
+			messages = messages[1:]
+			copied, err := q.CopyMessage(ctx, db.CopyMessageParams{
+				ID:         uuid.New().String(),
+				SessionID:  fork.ID,
+				Role:       msg.Role,
+				Parts:      msg.Parts,
+				Model:      msg.Model,
+				CreatedAt:  msg.CreatedAt,
+				UpdatedAt:  msg.UpdatedAt,
+				FinishedAt: msg.FinishedAt,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to copy message: %w", err)
+			}
+			ids[msg.ID] = copied.ID
+			continue
+		}
+		file := files[0]
+		files = files[1:]
+		_, err := q.CopyFile(ctx, db.CopyFileParams{
+			ID:        uuid.New().String(),
+			SessionID: fork.ID,
+			Path:      file.Path,
+			Content:   file.Content,
+			Version:   file.Version,
+			IsNew:     file.IsNew,
+			CreatedAt: file.CreatedAt,
+			UpdatedAt: file.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy file history: %w", err)
+		}
+	}
+
+	// Keep the summary if it is part of the copied history.
+	if summaryID, ok := ids[parent.SummaryMessageID.String]; ok {
+		_, err := q.UpdateSession(ctx, db.UpdateSessionParams{
+			ID:               fork.ID,
+			Title:            fork.Title,
+			SummaryMessageID: sql.NullString{String: summaryID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy summary: %w", err)
+		}
+	}
+	return nil
+}
+
 func (s *service) Delete(ctx context.Context, id string) error {
 	session, err := s.Get(ctx, id)
 	if err != nil {
@@ -132,25 +271,56 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 	return sessions, nil
 }
 
+// ListAgentSessions returns the sessions created by the agent tool, oldest
+// first.
+func (s *service) ListAgentSessions(ctx context.Context) ([]Session, error) {
+	dbSessions, err := s.q.ListAgentSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
+// AddCost records cost spent at the given time in the daily spend totals.
+func (s *service) AddCost(ctx context.Context, at time.Time, cost float64) error {
+	if cost <= 0 {
+		return nil
+	}
+	return s.q.AddDailyCost(ctx, db.AddDailyCostParams{
+		Day:  at.Local().Format(time.DateOnly),
+		Cost: cost,
+	})
+}
+
+// DailyCost returns the cost recorded on the local day of the given time.
+func (s *service) DailyCost(ctx context.Context, day time.Time) (float64, error) {
+	return s.q.GetDailyCost(ctx, day.Local().Format(time.DateOnly))
+}
+
 func (s service) fromDBItem(item db.Session) Session {
 	return Session{
-		ID:               item.ID,
-		ParentSessionID:  item.ParentSessionID.String,
-		Title:            item.Title,
-		MessageCount:     item.MessageCount,
-		PromptTokens:     item.PromptTokens,
-		CompletionTokens: item.CompletionTokens,
-		SummaryMessageID: item.SummaryMessageID.String,
-		Cost:             item.Cost,
-		CreatedAt:        item.CreatedAt,
-		UpdatedAt:        item.UpdatedAt,
+		ID:                  item.ID,
+		ParentSessionID:     item.ParentSessionID.String,
+		Title:               item.Title,
+		MessageCount:        item.MessageCount,
+		PromptTokens:        item.PromptTokens,
+		CompletionTokens:    item.CompletionTokens,
+		SummaryMessageID:    item.SummaryMessageID.String,
+		ForkedFromMessageID: item.ForkedFromMessageID.String,
+		Cost:                item.Cost,
+		CreatedAt:           item.CreatedAt,
+		UpdatedAt:           item.UpdatedAt,
 	}
 }
 
-func NewService(q db.Querier) Service {
-	broker := pubsub.NewBroker[Session]()
+func NewService(q *db.Queries, db *sql.DB) Service {
 	return &service{
-		broker,
-		q,
+		Broker: pubsub.NewBroker[Session](),
+		db:     db,
+		q:      q,
 	}
 }
@@ -0,0 +1,90 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/db"
+	"github.com/opencode-ai/opencode/internal/history"
+	"github.com/opencode-ai/opencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFork(t *testing.T) {
+	ctx := context.Background()
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Data.Directory = t.TempDir()
+	conn, err := db.Connect()
+	require.NoError(t, err)
+	defer conn.Close()
+	q := db.New(conn)
+	sessions := NewService(q, conn)
+	messages := message.NewService(q)
+	files := history.NewService(q, conn)
+
+	parent, err := sessions.Create(ctx, "parent")
+	require.NoError(t, err)
+	var ids []string
+	for _, role := range []message.MessageRole{message.User, message.Assistant, message.Tool, message.User} {
+		msg, err := messages.Create(ctx, parent.ID, message.CreateMessageParams{
+			Role:  role,
+			Parts: []message.ContentPart{message.TextContent{Text: string(role)}},
+		})
+		require.NoError(t, err)
+		ids = append(ids, msg.ID)
+		switch role {
+		case message.Assistant:
+			// The tool the assistant called wrote a file
+			_, err = files.CreateNew(ctx, parent.ID, "main.go")
+			require.NoError(t, err)
+		case message.User:
+			if len(ids) > 1 {
+				_, err = files.CreateVersion(ctx, parent.ID, "main.go", "package main")
+				require.NoError(t, err)
+			}
+		}
+	}
+	// Everything happened within the same second
+	_, err = conn.Exec("UPDATE messages SET created_at = 1")
+	require.NoError(t, err)
+	_, err = conn.Exec("UPDATE files SET created_at = 1")
+	require.NoError(t, err)
+
+	fork, err := sessions.Fork(ctx, parent.ID, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, "parent (fork)", fork.Title)
+	assert.Equal(t, parent.ID, fork.ParentSessionID)
+	copied, err := messages.List(ctx, fork.ID)
+	require.NoError(t, err)
+	require.Len(t, copied, 3)
+	assert.Equal(t, message.User, copied[0].Role)
+	assert.Equal(t, message.Assistant, copied[1].Role)
+	assert.Equal(t, message.Tool, copied[2].Role)
+
+	// Only the version written during the copied turn is copied, in its
+	// place among the messages
+	copiedFiles, err := files.ListBySession(ctx, fork.ID)
+	require.NoError(t, err)
+	require.Len(t, copiedFiles, 1)
+	assert.True(t, copiedFiles[0].IsNew)
+	assert.Greater(t, copiedFiles[0].Seq, copied[1].Seq)
+	assert.Less(t, copiedFiles[0].Seq, copied[2].Seq)
+
+	// A fork that fails part way leaves nothing behind
+	_, err = conn.Exec(`CREATE TRIGGER fail_file_copy BEFORE INSERT ON files
+BEGIN SELECT RAISE(ABORT, 'disk full'); END`)
+	require.NoError(t, err)
+	_, err = sessions.Fork(ctx, parent.ID, ids[3])
+	require.ErrorContains(t, err, "disk full")
+	all, err := sessions.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	var messageCount, fileCount int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM messages").Scan(&messageCount))
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM files").Scan(&fileCount))
+	assert.Equal(t, 4+3, messageCount)
+	assert.Equal(t, 2+1, fileCount)
+}
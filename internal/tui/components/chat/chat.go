@@ -18,10 +18,39 @@ import (
 type SendMsg struct {
 	Text        string
 	Attachments []message.Attachment
+	Edit        *MessageEdit
 }
 
+// SteerMsg submits a note to the agent run in progress without cancelling it.
+type SteerMsg struct {
+	Text string
+}
+
+// MessageEdit describes how an edited user message replaces the original one.
+type MessageEdit struct {
+	Message message.Message
+	// Fork keeps the original conversation and resends in a new session
+	// branched from the message before the edited one.
+	Fork bool
+	// RevertFiles restores the files changed after the edited message.
+	RevertFiles bool
+	// Queued marks a prompt that is still waiting in the agent queue, it is
+	// updated in place instead of resent.
+	Queued bool
+}
+
+// EditMessageMsg loads a user message into the editor to be edited and resent.
+type EditMessageMsg MessageEdit
+
 type SessionSelectedMsg = session.Session
 
+// MessageActionsMsg asks for the actions available on a selected message.
+type MessageActionsMsg struct {
+	Message message.Message
+	// Queued is set when the message is a prompt waiting in the agent queue.
+	Queued bool
+}
+
 type SessionClearedMsg struct{}
 
 type EditorFocusMsg bool
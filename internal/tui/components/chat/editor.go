@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
 	"strings"
 	"unicode"
@@ -31,10 +32,12 @@ type editorCmp struct {
 	textarea    textarea.Model
 	attachments []message.Attachment
 	deleteMode  bool
+	edit        *MessageEdit
 }
 
 type EditorKeyMaps struct {
 	Send       key.Binding
+	Steer      key.Binding
 	OpenEditor key.Binding
 }
 
@@ -54,6 +57,10 @@ var editorMaps = EditorKeyMaps{
 		key.WithKeys("enter", "ctrl+s"),
 		key.WithHelp("enter", "send message"),
 	),
+	Steer: key.NewBinding(
+		key.WithKeys("alt+enter"),
+		key.WithHelp("alt+enter", "steer the running agent"),
+	),
 	OpenEditor: key.NewBinding(
 		key.WithKeys("ctrl+e"),
 		key.WithHelp("ctrl+e", "open editor"),
@@ -89,6 +96,9 @@ func (m *editorCmp) openEditor() tea.Cmd {
 	if err != nil {
 		return util.ReportError(err)
 	}
+	if m.edit != nil {
+		tmpfile.WriteString(m.textarea.Value())
+	}
 	tmpfile.Close()
 	c := exec.Command(editor, tmpfile.Name()) //nolint:gosec
 	c.Stdin = os.Stdin
@@ -108,9 +118,13 @@ func (m *editorCmp) openEditor() tea.Cmd {
 		os.Remove(tmpfile.Name())
 		attachments := m.attachments
 		m.attachments = nil
+		edit := m.edit
+		m.edit = nil
+		m.textarea.Reset()
 		return SendMsg{
 			Text:        string(content),
 			Attachments: attachments,
+			Edit:        edit,
 		}
 	})
 }
@@ -119,16 +133,25 @@ func (m *editorCmp) Init() tea.Cmd {
 	return textarea.Blink
 }
 
+// editBlocked reports whether the pending edit can not be sent yet. New
+// prompts and edits of queued prompts are accepted while the agent works,
+// rewriting the history is not.
+func (m *editorCmp) editBlocked() bool {
+	return m.edit != nil && !m.edit.Queued && m.app.CoderAgent.IsSessionBusy(m.session.ID)
+}
+
 func (m *editorCmp) send() tea.Cmd {
-	if m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+	if m.editBlocked() {
 		return util.ReportWarn("Agent is working, please wait...")
 	}
 
 	value := m.textarea.Value()
 	m.textarea.Reset()
 	attachments := m.attachments
+	edit := m.edit
 
 	m.attachments = nil
+	m.edit = nil
 	if value == "" {
 		return nil
 	}
@@ -136,10 +159,30 @@ func (m *editorCmp) send() tea.Cmd {
 		util.CmdHandler(SendMsg{
 			Text:        value,
 			Attachments: attachments,
+			Edit:        edit,
 		}),
 	)
 }
 
+// steer sends the editor content as a note to the running agent.
+func (m *editorCmp) steer() tea.Cmd {
+	if m.edit != nil {
+		return util.ReportWarn("Finish or cancel the edit first")
+	}
+	if !m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+		return util.ReportWarn("Agent is not working, press enter to send the message")
+	}
+	if len(m.attachments) > 0 {
+		return util.ReportWarn("Attachments can not be sent as a steering note")
+	}
+	value := strings.TrimSpace(m.textarea.Value())
+	if value == "" {
+		return nil
+	}
+	m.textarea.Reset()
+	return util.CmdHandler(SteerMsg{Text: value})
+}
+
 func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch msg := msg.(type) {
@@ -154,8 +197,26 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case SessionSelectedMsg:
 		if msg.ID != m.session.ID {
 			m.session = msg
+			m.edit = nil
 		}
 		return m, nil
+	case EditMessageMsg:
+		edit := MessageEdit(msg)
+		m.edit = &edit
+		m.textarea.SetValue(msg.Message.Content().String())
+		m.attachments = nil
+		for _, content := range msg.Message.BinaryContent() {
+			m.attachments = append(m.attachments, message.Attachment{
+				FilePath: content.Path,
+				FileName: filepath.Base(content.Path),
+				MimeType: content.MIMEType,
+				Content:  content.Data,
+			})
+		}
+		if edit.Queued {
+			return m, util.ReportInfo("Editing queued prompt, press enter to save or esc to cancel")
+		}
+		return m, util.ReportInfo("Editing message, press enter to resend or esc to cancel")
 	case dialog.AttachmentAddedMsg:
 		if len(m.attachments) >= maxAttachments {
 			logging.ErrorPersist(fmt.Sprintf("cannot add more than %d images", maxAttachments))
@@ -189,15 +250,23 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		if key.Matches(msg, editorMaps.OpenEditor) {
-			if m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+			if m.editBlocked() {
 				return m, util.ReportWarn("Agent is working, please wait...")
 			}
 			return m, m.openEditor()
 		}
 		if key.Matches(msg, DeleteKeyMaps.Escape) {
 			m.deleteMode = false
+			if m.edit != nil {
+				m.edit = nil
+				m.attachments = nil
+				m.textarea.Reset()
+			}
 			return m, nil
 		}
+		if m.textarea.Focused() && key.Matches(msg, editorMaps.Steer) {
+			return m, m.steer()
+		}
 		// Hanlde Enter key
 		if m.textarea.Focused() && key.Matches(msg, editorMaps.Send) {
 			value := m.textarea.Value()
@@ -225,13 +294,20 @@ func (m *editorCmp) View() string {
 		Bold(true).
 		Foreground(t.Primary())
 
+	// Mark the prompt while an earlier message is being edited
+	prompt := ">"
+	if m.edit != nil {
+		prompt = "✎"
+		style = style.Foreground(t.Warning())
+	}
+
 	if len(m.attachments) == 0 {
-		return lipgloss.JoinHorizontal(lipgloss.Top, style.Render(">"), m.textarea.View())
+		return lipgloss.JoinHorizontal(lipgloss.Top, style.Render(prompt), m.textarea.View())
 	}
 	m.textarea.SetHeight(m.height - 1)
 	return lipgloss.JoinVertical(lipgloss.Top,
 		m.attachmentsContent(),
-		lipgloss.JoinHorizontal(lipgloss.Top, style.Render(">"),
+		lipgloss.JoinHorizontal(lipgloss.Top, style.Render(prompt),
 			m.textarea.View()),
 	)
 }
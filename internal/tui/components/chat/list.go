@@ -11,6 +11,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/opencode-ai/opencode/internal/app"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
 	"github.com/opencode-ai/opencode/internal/message"
 	"github.com/opencode-ai/opencode/internal/pubsub"
 	"github.com/opencode-ai/opencode/internal/session"
@@ -30,8 +31,10 @@ type messagesCmp struct {
 	viewport      viewport.Model
 	session       session.Session
 	messages      []message.Message
+	queued        []message.Message
 	uiMessages    []uiMessage
 	currentMsgID  string
+	selectedMsgID string
 	cachedContent map[string]cacheItem
 	spinner       spinner.Model
 	rendering     bool
@@ -44,6 +47,9 @@ type MessageKeys struct {
 	PageUp       key.Binding
 	HalfPageUp   key.Binding
 	HalfPageDown key.Binding
+	SelectPrev   key.Binding
+	SelectNext   key.Binding
+	Actions      key.Binding
 }
 
 var messageKeys = MessageKeys{
@@ -63,6 +69,18 @@ var messageKeys = MessageKeys{
 		key.WithKeys("ctrl+d", "ctrl+d"),
 		key.WithHelp("ctrl+d", "½ page down"),
 	),
+	SelectPrev: key.NewBinding(
+		key.WithKeys("ctrl+up", "alt+up"),
+		key.WithHelp("ctrl+↑", "select previous message"),
+	),
+	SelectNext: key.NewBinding(
+		key.WithKeys("ctrl+down", "alt+down"),
+		key.WithHelp("ctrl+↓", "select next message"),
+	),
+	Actions: key.NewBinding(
+		key.WithKeys("ctrl+g"),
+		key.WithHelp("ctrl+g", "message actions"),
+	),
 }
 
 func (m *messagesCmp) Init() tea.Cmd {
@@ -84,11 +102,33 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case SessionClearedMsg:
 		m.session = session.Session{}
 		m.messages = make([]message.Message, 0)
+		m.queued = nil
 		m.currentMsgID = ""
+		m.selectedMsgID = ""
 		m.rendering = false
 		return m, nil
 
 	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, messageKeys.SelectPrev):
+			m.moveSelection(-1)
+			return m, nil
+		case key.Matches(msg, messageKeys.SelectNext):
+			m.moveSelection(1)
+			return m, nil
+		case key.Matches(msg, messageKeys.Actions):
+			for _, v := range m.messages {
+				if v.ID == m.selectedMsgID {
+					return m, util.CmdHandler(MessageActionsMsg{Message: v})
+				}
+			}
+			for _, v := range m.queued {
+				if v.ID == m.selectedMsgID {
+					return m, util.CmdHandler(MessageActionsMsg{Message: v, Queued: true})
+				}
+			}
+			return m, util.ReportWarn("Select a message first with ctrl+↑")
+		}
 		// Handle half-page scrolling with custom smaller amount
 		if key.Matches(msg, messageKeys.HalfPageUp) {
 			// Scroll up by 3 lines instead of half page
@@ -123,6 +163,12 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case renderFinishedMsg:
 		m.rendering = false
 		m.viewport.GotoBottom()
+	case pubsub.Event[agent.AgentEvent]:
+		if msg.Payload.Type == agent.AgentEventTypeQueue && msg.Payload.SessionID == m.session.ID {
+			m.setQueue(msg.Payload.Queue)
+			m.renderView()
+			m.viewport.GotoBottom()
+		}
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent && msg.Payload.ID == m.session.ID {
 			m.session = msg.Payload
@@ -165,6 +211,21 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 			}
+		} else if msg.Type == pubsub.DeletedEvent && msg.Payload.SessionID == m.session.ID {
+			for i, v := range m.messages {
+				if v.ID == msg.Payload.ID {
+					m.messages = append(m.messages[:i], m.messages[i+1:]...)
+					delete(m.cachedContent, msg.Payload.ID)
+					if m.selectedMsgID == msg.Payload.ID {
+						m.selectedMsgID = ""
+					}
+					if m.currentMsgID == msg.Payload.ID && len(m.messages) > 0 {
+						m.currentMsgID = m.messages[len(m.messages)-1].ID
+					}
+					needsRerender = true
+					break
+				}
+			}
 		} else if msg.Type == pubsub.UpdatedEvent && msg.Payload.SessionID == m.session.ID {
 			for i, v := range m.messages {
 				if v.ID == msg.Payload.ID {
@@ -192,6 +253,124 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// moveSelection moves the selected message up (delta < 0) or down (delta > 0)
+// through the user and assistant messages. Moving past the last message clears
+// the selection.
+func (m *messagesCmp) moveSelection(delta int) {
+	var selectable []string
+	current := -1
+	for _, v := range append(m.messages[:len(m.messages):len(m.messages)], m.queued...) {
+		if v.Role != message.User && v.Role != message.Assistant {
+			continue
+		}
+		if v.ID == m.selectedMsgID {
+			current = len(selectable)
+		}
+		selectable = append(selectable, v.ID)
+	}
+	if len(selectable) == 0 {
+		return
+	}
+
+	next := current + delta
+	if current == -1 {
+		if delta > 0 {
+			return
+		}
+		next = len(selectable) - 1
+	}
+	if next < 0 {
+		next = 0
+	}
+
+	delete(m.cachedContent, m.selectedMsgID)
+	if next >= len(selectable) {
+		m.selectedMsgID = ""
+		m.renderView()
+		m.viewport.GotoBottom()
+		return
+	}
+	m.selectedMsgID = selectable[next]
+	delete(m.cachedContent, m.selectedMsgID)
+	m.renderView()
+
+	offset := 0
+	for _, v := range m.uiMessages {
+		if v.ID == m.selectedMsgID {
+			break
+		}
+		offset += v.height + 1 // + 1 for spacing
+	}
+	m.viewport.SetYOffset(offset)
+}
+
+// setQueue replaces the pending prompts shown after the messages. They are
+// rendered as user messages that have not been sent yet.
+func (m *messagesCmp) setQueue(prompts []agent.QueuedPrompt) {
+	m.queued = nil
+	selected := m.selectedMsgID == ""
+	for _, v := range m.messages {
+		if v.ID == m.selectedMsgID {
+			selected = true
+		}
+	}
+	for _, prompt := range prompts {
+		parts := []message.ContentPart{message.TextContent{Text: prompt.Content}}
+		for _, attachment := range prompt.Attachments {
+			parts = append(parts, message.BinaryContent{
+				Path:     attachment.FilePath,
+				MIMEType: attachment.MimeType,
+				Data:     attachment.Content,
+			})
+		}
+		if prompt.ID == m.selectedMsgID {
+			selected = true
+		}
+		m.queued = append(m.queued, message.Message{
+			ID:        prompt.ID,
+			Role:      message.User,
+			SessionID: prompt.SessionID,
+			Parts:     parts,
+			CreatedAt: prompt.CreatedAt,
+			UpdatedAt: prompt.CreatedAt,
+		})
+	}
+	if !selected {
+		m.selectedMsgID = ""
+	}
+}
+
+// markQueued prefixes a rendered queued prompt with its position in the queue.
+func (m *messagesCmp) markQueued(content uiMessage, position int) uiMessage {
+	t := theme.CurrentTheme()
+	marker := styles.BaseStyle().
+		Width(m.width).
+		Foreground(t.TextMuted()).
+		Bold(true).
+		Render(fmt.Sprintf("⋯ queued #%d · sent when the agent finishes", position))
+	content.content = lipgloss.JoinVertical(lipgloss.Left, marker, content.content)
+	content.height = lipgloss.Height(content.content)
+	return content
+}
+
+// markSelected prefixes the first rendered part of the selected message with a
+// marker line.
+func (m *messagesCmp) markSelected(content []uiMessage) []uiMessage {
+	if len(content) == 0 {
+		return content
+	}
+	t := theme.CurrentTheme()
+	marker := styles.BaseStyle().
+		Width(m.width).
+		Foreground(t.Accent()).
+		Bold(true).
+		Render("▸ selected · ctrl+g for actions")
+	marked := append([]uiMessage{}, content...)
+	marked[0].content = lipgloss.JoinVertical(lipgloss.Left, marker, marked[0].content)
+	marked[0].height = lipgloss.Height(marked[0].content)
+	return marked
+}
+
 func (m *messagesCmp) IsAgentWorking() bool {
 	return m.app.CoderAgent.IsSessionBusy(m.session.ID)
 }
@@ -229,12 +408,16 @@ func (m *messagesCmp) renderView() {
 				m.width,
 				pos,
 			)
-			m.uiMessages = append(m.uiMessages, userMsg)
+			userMessages := []uiMessage{userMsg}
+			if msg.ID == m.selectedMsgID {
+				userMessages = m.markSelected(userMessages)
+			}
+			m.uiMessages = append(m.uiMessages, userMessages...)
 			m.cachedContent[msg.ID] = cacheItem{
 				width:   m.width,
-				content: []uiMessage{userMsg},
+				content: userMessages,
 			}
-			pos += userMsg.height + 1 // + 1 for spacing
+			pos += userMessages[0].height + 1 // + 1 for spacing
 		case message.Assistant:
 			if cache, ok := m.cachedContent[msg.ID]; ok && cache.width == m.width {
 				m.uiMessages = append(m.uiMessages, cache.content...)
@@ -252,6 +435,9 @@ func (m *messagesCmp) renderView() {
 				m.width,
 				pos,
 			)
+			if msg.ID == m.selectedMsgID {
+				assistantMessages = m.markSelected(assistantMessages)
+			}
 			for _, msg := range assistantMessages {
 				m.uiMessages = append(m.uiMessages, msg)
 				pos += msg.height + 1 // + 1 for spacing
@@ -263,6 +449,16 @@ func (m *messagesCmp) renderView() {
 		}
 	}
 
+	// Queued prompts change while they wait, so they are not cached
+	for i, msg := range m.queued {
+		queued := []uiMessage{m.markQueued(renderUserMessage(msg, false, m.width, pos), i+1)}
+		if msg.ID == m.selectedMsgID {
+			queued = m.markSelected(queued)
+		}
+		m.uiMessages = append(m.uiMessages, queued...)
+		pos += queued[0].height + 1 // + 1 for spacing
+	}
+
 	messages := make([]string, 0)
 	for _, v := range m.uiMessages {
 		messages = append(messages, lipgloss.JoinVertical(lipgloss.Left, v.content),
@@ -476,11 +672,13 @@ func (m *messagesCmp) SetSession(session session.Session) tea.Cmd {
 		return nil
 	}
 	m.session = session
+	m.selectedMsgID = ""
 	messages, err := m.app.Messages.List(context.Background(), session.ID)
 	if err != nil {
 		return util.ReportError(err)
 	}
 	m.messages = messages
+	m.setQueue(m.app.CoderAgent.Queue(session.ID))
 	if len(m.messages) > 0 {
 		m.currentMsgID = m.messages[len(m.messages)-1].ID
 	}
@@ -498,6 +696,9 @@ func (m *messagesCmp) BindingKeys() []key.Binding {
 		m.viewport.KeyMap.PageUp,
 		m.viewport.KeyMap.HalfPageUp,
 		m.viewport.KeyMap.HalfPageDown,
+		messageKeys.SelectPrev,
+		messageKeys.SelectNext,
+		messageKeys.Actions,
 	}
 }
 
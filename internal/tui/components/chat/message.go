@@ -180,6 +180,12 @@ func renderAssistantMessage(
 			if content == "" {
 				content = "*Permission denied - check your API key or access rights*"
 			}
+		case message.FinishReasonLimitReached:
+			info = append(info, baseStyle.
+				Width(width-1).
+				Foreground(t.Warning()).
+				Render(fmt.Sprintf(" %s (limit reached)", models.SupportedModels[msg.Model].Name)),
+			)
 		}
 	}
 	if content != "" || (finished && finishData.Reason == message.FinishReasonEndTurn) {
@@ -264,6 +270,8 @@ func toolName(name string) string {
 		return "Sourcegraph"
 	case tools.ViewToolName:
 		return "View"
+	case tools.ReadOutputToolName:
+		return "Output"
 	case tools.WriteToolName:
 		return "Write"
 	case tools.PatchToolName:
@@ -292,6 +300,8 @@ func getToolAction(name string) string {
 		return "Searching code..."
 	case tools.ViewToolName:
 		return "Reading file..."
+	case tools.ReadOutputToolName:
+		return "Reading output..."
 	case tools.WriteToolName:
 		return "Preparing write..."
 	case tools.PatchToolName:
@@ -450,6 +460,19 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 			toolParams = append(toolParams, "offset", fmt.Sprintf("%d", params.Offset))
 		}
 		return renderParams(paramWidth, toolParams...)
+	case tools.ReadOutputToolName:
+		var params tools.ReadOutputParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		toolParams := []string{
+			params.Handle,
+		}
+		if params.Limit != 0 {
+			toolParams = append(toolParams, "limit", fmt.Sprintf("%d", params.Limit))
+		}
+		if params.Offset != 0 {
+			toolParams = append(toolParams, "offset", fmt.Sprintf("%d", params.Offset))
+		}
+		return renderParams(paramWidth, toolParams...)
 	case tools.WriteToolName:
 		var params tools.WriteParams
 		json.Unmarshal([]byte(toolCall.Input), &params)
@@ -669,9 +692,8 @@ func renderToolMessage(
 	}
 
 	if toolCall.Name == agent.AgentToolName {
-		taskMessages, _ := messagesService.List(context.Background(), toolCall.ID)
 		toolCalls := []message.ToolCall{}
-		for _, v := range taskMessages {
+		for _, v := range agentCallMessages(toolCall, messagesService) {
 			toolCalls = append(toolCalls, v.ToolCalls()...)
 		}
 		for _, call := range toolCalls {
@@ -704,6 +726,25 @@ func renderToolMessage(
 	return toolMsg
 }
 
+// agentCallMessages returns the sub-session messages produced by an agent tool
+// call. Continued sessions also hold earlier calls, so only the messages after
+// the prompt of this call are kept.
+func agentCallMessages(toolCall message.ToolCall, messagesService message.Service) []message.Message {
+	var params agent.AgentParams
+	json.Unmarshal([]byte(toolCall.Input), &params)
+	sessionID := toolCall.ID
+	if params.SessionID != "" {
+		sessionID = params.SessionID
+	}
+	taskMessages, _ := messagesService.List(context.Background(), sessionID)
+	for i := len(taskMessages) - 1; i >= 0; i-- {
+		if taskMessages[i].Role == message.User && strings.HasPrefix(taskMessages[i].Content().String(), params.Prompt) {
+			return taskMessages[i+1:]
+		}
+	}
+	return taskMessages
+}
+
 // Helper function to format the time difference between two Unix timestamps
 func formatTimestampDiff(start, end int64) string {
 	diffSeconds := float64(end-start) / 1000.0 // Convert to seconds
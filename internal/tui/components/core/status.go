@@ -8,6 +8,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
 	"github.com/opencode-ai/opencode/internal/llm/models"
 	"github.com/opencode-ai/opencode/internal/lsp"
 	"github.com/opencode-ai/opencode/internal/lsp/protocol"
@@ -29,6 +30,9 @@ type statusCmp struct {
 	messageTTL time.Duration
 	lspClients map[string]*lsp.Client
 	session    session.Session
+	// cacheHitRate is the share of the input of the last response of the
+	// session read from the prompt cache, negative when unknown.
+	cacheHitRate float64
 }
 
 // clearMessageCmd is a command that clears status messages after a timeout
@@ -48,9 +52,20 @@ func (m statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		return m, nil
 	case chat.SessionSelectedMsg:
+		if m.session.ID != msg.ID {
+			m.cacheHitRate = -1
+		}
 		m.session = msg
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
+		m.cacheHitRate = -1
+	case pubsub.Event[agent.AgentEvent]:
+		if msg.Payload.Type == agent.AgentEventTypeUsage && msg.Payload.SessionID == m.session.ID {
+			m.cacheHitRate = -1
+			if rate, ok := msg.Payload.Usage.CacheHitRate(); ok {
+				m.cacheHitRate = rate
+			}
+		}
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
 			if m.session.ID == msg.Payload.ID {
@@ -84,7 +99,7 @@ func getHelpWidget() string {
 		Render(helpText)
 }
 
-func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
+func formatTokensAndCost(tokens, contextWindow int64, cost, cacheHitRate float64) string {
 	// Format tokens in human-readable format (e.g., 110K, 1.2M)
 	var formattedTokens string
 	switch {
@@ -113,12 +128,15 @@ func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
 		formattedTokens = fmt.Sprintf("%s(%d%%)", styles.WarningIcon, int(percentage))
 	}
 
+	if cacheHitRate >= 0 {
+		return fmt.Sprintf("Context: %s, Cost: %s, Cache: %d%%", formattedTokens, formattedCost, int(cacheHitRate*100))
+	}
 	return fmt.Sprintf("Context: %s, Cost: %s", formattedTokens, formattedCost)
 }
 
 func (m statusCmp) View() string {
 	t := theme.CurrentTheme()
-	modelID := config.Get().Agents[config.AgentCoder].Model
+	modelID := config.Get().Agents[config.PrimaryAgent()].Model
 	model := models.SupportedModels[modelID]
 
 	// Initialize the help widget
@@ -127,7 +145,7 @@ func (m statusCmp) View() string {
 	tokenInfoWidth := 0
 	if m.session.ID != "" {
 		totalTokens := m.session.PromptTokens + m.session.CompletionTokens
-		tokens := formatTokensAndCost(totalTokens, model.ContextWindow, m.session.Cost)
+		tokens := formatTokensAndCost(totalTokens, model.ContextWindow, m.session.Cost, m.cacheHitRate)
 		tokensStyle := styles.Padded().
 			Background(t.Text()).
 			Foreground(t.BackgroundSecondary())
@@ -271,23 +289,29 @@ func (m statusCmp) model() string {
 
 	cfg := config.Get()
 
-	coder, ok := cfg.Agents[config.AgentCoder]
+	primary := config.PrimaryAgent()
+	coder, ok := cfg.Agents[primary]
 	if !ok {
 		return "Unknown"
 	}
 	model := models.SupportedModels[coder.Model]
+	name := model.Name
+	if primary != config.AgentCoder {
+		name = fmt.Sprintf("%s · %s", primary, model.Name)
+	}
 
 	return styles.Padded().
 		Background(t.Secondary()).
 		Foreground(t.Background()).
-		Render(model.Name)
+		Render(name)
 }
 
 func NewStatusCmp(lspClients map[string]*lsp.Client) StatusCmp {
 	helpWidget = getHelpWidget()
 
 	return &statusCmp{
-		messageTTL: 10 * time.Second,
-		lspClients: lspClients,
+		messageTTL:   10 * time.Second,
+		cacheHitRate: -1,
+		lspClients:   lspClients,
 	}
 }
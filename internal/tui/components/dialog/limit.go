@@ -0,0 +1,139 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/tui/layout"
+	"github.com/opencode-ai/opencode/internal/tui/styles"
+	"github.com/opencode-ai/opencode/internal/tui/theme"
+	"github.com/opencode-ai/opencode/internal/tui/util"
+)
+
+// LimitResponseMsg is sent when the user answers the limit dialog
+type LimitResponseMsg struct {
+	SessionID string
+	Limit     agent.LimitExceeded
+	Continue  bool
+}
+
+// LimitDialog asks the user whether to raise a budget limit and continue
+type LimitDialog interface {
+	tea.Model
+	layout.Bindings
+	SetLimit(sessionID string, limit agent.LimitExceeded)
+}
+
+type limitDialogCmp struct {
+	sessionID  string
+	limit      agent.LimitExceeded
+	selectedNo bool
+}
+
+func (l *limitDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (l *limitDialogCmp) SetLimit(sessionID string, limit agent.LimitExceeded) {
+	l.sessionID = sessionID
+	l.limit = limit
+	l.selectedNo = false
+}
+
+func (l *limitDialogCmp) respond(cont bool) tea.Cmd {
+	return util.CmdHandler(LimitResponseMsg{
+		SessionID: l.sessionID,
+		Limit:     l.limit,
+		Continue:  cont,
+	})
+}
+
+func (l *limitDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, helpKeys.LeftRight) || key.Matches(msg, helpKeys.Tab):
+			l.selectedNo = !l.selectedNo
+			return l, nil
+		case key.Matches(msg, helpKeys.EnterSpace):
+			return l, l.respond(!l.selectedNo)
+		case key.Matches(msg, helpKeys.Yes):
+			return l, l.respond(true)
+		case key.Matches(msg, helpKeys.No), msg.String() == "esc":
+			return l, l.respond(false)
+		}
+	}
+	return l, nil
+}
+
+// RaisedLimit returns the value a limit is raised to when the user chooses to
+// continue: the current usage plus the original headroom.
+func RaisedLimit(limit agent.LimitExceeded) float64 {
+	return limit.Current + limit.Limit
+}
+
+func (l *limitDialogCmp) question() string {
+	reached := l.limit.Error()
+	reached = strings.ToUpper(reached[:1]) + reached[1:] + "."
+	if l.limit.Kind == agent.LimitToolIterations {
+		return fmt.Sprintf("%s\nContinue for another %d iterations?", reached, int(l.limit.Limit))
+	}
+	return fmt.Sprintf("%s\nRaise the limit to $%.2f and continue?", reached, RaisedLimit(l.limit))
+}
+
+func (l *limitDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	yesStyle := baseStyle
+	noStyle := baseStyle
+	spacerStyle := baseStyle.Background(t.Background())
+
+	if l.selectedNo {
+		noStyle = noStyle.Background(t.Primary()).Foreground(t.Background())
+		yesStyle = yesStyle.Background(t.Background()).Foreground(t.Primary())
+	} else {
+		yesStyle = yesStyle.Background(t.Primary()).Foreground(t.Background())
+		noStyle = noStyle.Background(t.Background()).Foreground(t.Primary())
+	}
+
+	yesButton := yesStyle.Padding(0, 1).Render("Continue")
+	noButton := noStyle.Padding(0, 1).Render("Stop")
+
+	buttons := lipgloss.JoinHorizontal(lipgloss.Left, yesButton, spacerStyle.Render("  "), noButton)
+
+	question := l.question()
+	width := lipgloss.Width(question)
+	remainingWidth := width - lipgloss.Width(buttons)
+	if remainingWidth > 0 {
+		buttons = spacerStyle.Render(strings.Repeat(" ", remainingWidth)) + buttons
+	}
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			baseStyle.Width(width).Render(question),
+			"",
+			buttons,
+		),
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.Warning()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (l *limitDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(helpKeys)
+}
+
+func NewLimitDialogCmp() LimitDialog {
+	return &limitDialogCmp{}
+}
@@ -0,0 +1,231 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/opencode-ai/opencode/internal/diff"
+	"github.com/opencode-ai/opencode/internal/history"
+	"github.com/opencode-ai/opencode/internal/tui/layout"
+	"github.com/opencode-ai/opencode/internal/tui/styles"
+	"github.com/opencode-ai/opencode/internal/tui/theme"
+	"github.com/opencode-ai/opencode/internal/tui/util"
+)
+
+// RewindResponseMsg is sent when the user confirms or cancels a rewind
+type RewindResponseMsg struct {
+	SessionID string
+	Restores  []history.Restore
+	Confirm   bool
+}
+
+// RewindDialog previews the file changes of a rewind before applying them
+type RewindDialog interface {
+	tea.Model
+	layout.Bindings
+	SetRewind(sessionID string, restores []history.Restore)
+}
+
+type rewindMapping struct {
+	LeftRight  key.Binding
+	Tab        key.Binding
+	EnterSpace key.Binding
+	Yes        key.Binding
+	No         key.Binding
+}
+
+var rewindKeys = rewindMapping{
+	LeftRight: key.NewBinding(
+		key.WithKeys("left", "right"),
+		key.WithHelp("←/→", "switch options"),
+	),
+	Tab: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "switch options"),
+	),
+	EnterSpace: key.NewBinding(
+		key.WithKeys("enter", " "),
+		key.WithHelp("enter/space", "confirm"),
+	),
+	Yes: key.NewBinding(
+		key.WithKeys("y", "Y"),
+		key.WithHelp("y", "rewind"),
+	),
+	No: key.NewBinding(
+		key.WithKeys("n", "N", "esc"),
+		key.WithHelp("n/esc", "cancel"),
+	),
+}
+
+type rewindDialogCmp struct {
+	width, height int
+	sessionID     string
+	restores      []history.Restore
+	selectedNo    bool
+	viewport      viewport.Model
+	rendered      string
+}
+
+func (r *rewindDialogCmp) Init() tea.Cmd {
+	return r.viewport.Init()
+}
+
+func (r *rewindDialogCmp) SetRewind(sessionID string, restores []history.Restore) {
+	r.sessionID = sessionID
+	r.restores = restores
+	r.selectedNo = false
+	r.rendered = ""
+	r.viewport.GotoTop()
+}
+
+func (r *rewindDialogCmp) respond(confirm bool) tea.Cmd {
+	return util.CmdHandler(RewindResponseMsg{
+		SessionID: r.sessionID,
+		Restores:  r.restores,
+		Confirm:   confirm,
+	})
+}
+
+func (r *rewindDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		r.width = int(float64(msg.Width) * 0.8)
+		r.height = int(float64(msg.Height) * 0.8)
+		r.rendered = ""
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, rewindKeys.LeftRight) || key.Matches(msg, rewindKeys.Tab):
+			r.selectedNo = !r.selectedNo
+			return r, nil
+		case key.Matches(msg, rewindKeys.EnterSpace):
+			return r, r.respond(!r.selectedNo)
+		case key.Matches(msg, rewindKeys.Yes):
+			return r, r.respond(true)
+		case key.Matches(msg, rewindKeys.No):
+			return r, r.respond(false)
+		default:
+			vp, cmd := r.viewport.Update(msg)
+			r.viewport = vp
+			return r, cmd
+		}
+	}
+	return r, nil
+}
+
+// renderChanges renders one section per file: a summary line followed by the
+// diff from the current content to the restored one.
+func (r *rewindDialogCmp) renderChanges() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	var sections []string
+	for _, restore := range r.restores {
+		patch, additions, removals := diff.GenerateDiff(restore.Current, restore.Content, restore.Path)
+		summary := fmt.Sprintf("%s (+%d -%d)", restore.Path, additions, removals)
+		if restore.Delete {
+			summary = fmt.Sprintf("%s (delete)", restore.Path)
+		}
+		sections = append(sections, baseStyle.
+			Width(r.viewport.Width).
+			Foreground(t.Primary()).
+			Bold(true).
+			Render(summary))
+
+		formatted, err := diff.FormatDiff(patch, diff.WithTotalWidth(r.viewport.Width))
+		if err != nil {
+			formatted = fmt.Sprintf("Error formatting diff: %v", err)
+		}
+		if formatted != "" {
+			sections = append(sections, formatted)
+		}
+		sections = append(sections, baseStyle.Width(r.viewport.Width).Render(""))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+func (r *rewindDialogCmp) renderButtons() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	yesStyle := baseStyle
+	noStyle := baseStyle
+	spacerStyle := baseStyle.Background(t.Background())
+
+	if r.selectedNo {
+		noStyle = noStyle.Background(t.Primary()).Foreground(t.Background())
+		yesStyle = yesStyle.Background(t.Background()).Foreground(t.Primary())
+	} else {
+		yesStyle = yesStyle.Background(t.Primary()).Foreground(t.Background())
+		noStyle = noStyle.Background(t.Background()).Foreground(t.Primary())
+	}
+
+	content := lipgloss.JoinHorizontal(
+		lipgloss.Left,
+		yesStyle.Padding(0, 1).Render("Rewind (y)"),
+		spacerStyle.Render("  "),
+		noStyle.Padding(0, 1).Render("Cancel (n)"),
+		spacerStyle.Render("  "),
+	)
+
+	remainingWidth := r.width - 4 - lipgloss.Width(content)
+	if remainingWidth > 0 {
+		content = spacerStyle.Render(strings.Repeat(" ", remainingWidth)) + content
+	}
+	return content
+}
+
+func (r *rewindDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	title := baseStyle.
+		Bold(true).
+		Width(r.width - 4).
+		Foreground(t.Primary()).
+		Render("Rewind Files")
+	header := baseStyle.
+		Width(r.width - 4).
+		Foreground(t.TextMuted()).
+		Render(fmt.Sprintf("%d file(s) will be restored to their state before the selected message:", len(r.restores)))
+	buttons := r.renderButtons()
+
+	r.viewport.Width = r.width - 4
+	r.viewport.Height = r.height - lipgloss.Height(title) - lipgloss.Height(header) - lipgloss.Height(buttons) - 4
+	if r.rendered == "" {
+		r.rendered = r.renderChanges()
+		r.viewport.SetContent(r.rendered)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Top,
+		title,
+		baseStyle.Render(strings.Repeat(" ", r.width-4)),
+		header,
+		baseStyle.Render(strings.Repeat(" ", r.width-4)),
+		baseStyle.Background(t.Background()).Render(r.viewport.View()),
+		buttons,
+	)
+
+	return baseStyle.
+		Padding(1, 0, 0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(r.width).
+		Height(r.height).
+		Render(content)
+}
+
+func (r *rewindDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(rewindKeys)
+}
+
+func NewRewindDialogCmp() RewindDialog {
+	return &rewindDialogCmp{
+		viewport: viewport.New(0, 0),
+	}
+}
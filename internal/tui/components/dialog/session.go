@@ -27,6 +27,35 @@ type SessionDialog interface {
 	SetSelectedSession(sessionID string)
 }
 
+// NestAgentSessions places the sessions created by the agent tool right after
+// their parent session. Agent sessions whose parent is not listed are left out.
+func NestAgentSessions(sessions, agentSessions []session.Session) []session.Session {
+	children := make(map[string][]session.Session)
+	for _, sess := range agentSessions {
+		children[sess.ParentSessionID] = append(children[sess.ParentSessionID], sess)
+	}
+	nested := make([]session.Session, 0, len(sessions)+len(agentSessions))
+	for _, sess := range sessions {
+		nested = append(nested, sess)
+		nested = append(nested, children[sess.ID]...)
+	}
+	return nested
+}
+
+// isAgentSession reports whether sess was created by the agent tool
+func isAgentSession(sess session.Session) bool {
+	return sess.ParentSessionID != "" && sess.ForkedFromMessageID == ""
+}
+
+// sessionLabel is the text shown for a session, agent sessions are indented
+// under their parent.
+func sessionLabel(sess session.Session) string {
+	if isAgentSession(sess) {
+		return "  ↳ " + sess.Title
+	}
+	return sess.Title
+}
+
 type sessionDialogCmp struct {
 	sessions          []session.Session
 	selectedIdx       int
@@ -121,8 +150,8 @@ func (s *sessionDialogCmp) View() string {
 	// Calculate max width needed for session titles
 	maxWidth := 40 // Minimum width
 	for _, sess := range s.sessions {
-		if len(sess.Title) > maxWidth-4 { // Account for padding
-			maxWidth = len(sess.Title) + 4
+		if label := sessionLabel(sess); lipgloss.Width(label) > maxWidth-4 { // Account for padding
+			maxWidth = lipgloss.Width(label) + 4
 		}
 	}
 
@@ -159,7 +188,11 @@ func (s *sessionDialogCmp) View() string {
 				Bold(true)
 		}
 
-		sessionItems = append(sessionItems, itemStyle.Padding(0, 1).Render(sess.Title))
+		if isAgentSession(sess) && i != s.selectedIdx {
+			itemStyle = itemStyle.Foreground(t.TextMuted())
+		}
+
+		sessionItems = append(sessionItems, itemStyle.Padding(0, 1).Render(sessionLabel(sess)))
 	}
 
 	title := baseStyle.
@@ -0,0 +1,136 @@
+package dialog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/opencode-ai/opencode/internal/hooks"
+	"github.com/opencode-ai/opencode/internal/tui/layout"
+	"github.com/opencode-ai/opencode/internal/tui/styles"
+	"github.com/opencode-ai/opencode/internal/tui/theme"
+	"github.com/opencode-ai/opencode/internal/tui/util"
+)
+
+const suggestionDialogWidth = 70
+
+// SuggestionResponseMsg is sent when the user approves or dismisses a hook
+// suggestion
+type SuggestionResponseMsg struct {
+	SessionID  string
+	Suggestion hooks.Suggestion
+	Approve    bool
+}
+
+// SuggestionDialog asks the user whether to send a prompt suggested by a
+// post-turn hook
+type SuggestionDialog interface {
+	tea.Model
+	layout.Bindings
+	SetSuggestion(sessionID string, suggestion hooks.Suggestion, remaining int)
+}
+
+type suggestionDialogCmp struct {
+	sessionID  string
+	suggestion hooks.Suggestion
+	remaining  int
+	selectedNo bool
+}
+
+func (s *suggestionDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (s *suggestionDialogCmp) SetSuggestion(sessionID string, suggestion hooks.Suggestion, remaining int) {
+	s.sessionID = sessionID
+	s.suggestion = suggestion
+	s.remaining = remaining
+	s.selectedNo = false
+}
+
+func (s *suggestionDialogCmp) respond(approve bool) tea.Cmd {
+	return util.CmdHandler(SuggestionResponseMsg{
+		SessionID:  s.sessionID,
+		Suggestion: s.suggestion,
+		Approve:    approve,
+	})
+}
+
+func (s *suggestionDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, helpKeys.LeftRight) || key.Matches(msg, helpKeys.Tab):
+			s.selectedNo = !s.selectedNo
+			return s, nil
+		case key.Matches(msg, helpKeys.EnterSpace):
+			return s, s.respond(!s.selectedNo)
+		case key.Matches(msg, helpKeys.Yes):
+			return s, s.respond(true)
+		case key.Matches(msg, helpKeys.No), msg.String() == "esc":
+			return s, s.respond(false)
+		}
+	}
+	return s, nil
+}
+
+func (s *suggestionDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	yesStyle := baseStyle
+	noStyle := baseStyle
+	spacerStyle := baseStyle.Background(t.Background())
+
+	if s.selectedNo {
+		noStyle = noStyle.Background(t.Primary()).Foreground(t.Background())
+		yesStyle = yesStyle.Background(t.Background()).Foreground(t.Primary())
+	} else {
+		yesStyle = yesStyle.Background(t.Primary()).Foreground(t.Background())
+		noStyle = noStyle.Background(t.Background()).Foreground(t.Primary())
+	}
+
+	yesButton := yesStyle.Padding(0, 1).Render("Send")
+	noButton := noStyle.Padding(0, 1).Render("Dismiss")
+
+	buttons := lipgloss.JoinHorizontal(lipgloss.Left, yesButton, spacerStyle.Render("  "), noButton)
+	remainingWidth := suggestionDialogWidth - lipgloss.Width(buttons)
+	if remainingWidth > 0 {
+		buttons = spacerStyle.Render(strings.Repeat(" ", remainingWidth)) + buttons
+	}
+
+	header := fmt.Sprintf("Hook %s suggests:", s.suggestion.Hook)
+	if s.remaining > 0 {
+		header += fmt.Sprintf(" (%d more)", s.remaining)
+	}
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			baseStyle.Width(suggestionDialogWidth).Foreground(t.TextMuted()).Render(header),
+			baseStyle.Width(suggestionDialogWidth).Render(""),
+			baseStyle.Width(suggestionDialogWidth).Render(s.suggestion.Text),
+			baseStyle.Width(suggestionDialogWidth).Render(""),
+			baseStyle.Width(suggestionDialogWidth).Render("Send it as the next prompt?"),
+			"",
+			buttons,
+		),
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (s *suggestionDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(helpKeys)
+}
+
+func NewSuggestionDialogCmp() SuggestionDialog {
+	return &suggestionDialogCmp{}
+}
@@ -2,6 +2,8 @@ package page
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/opencode-ai/opencode/internal/app"
 	"github.com/opencode-ai/opencode/internal/completions"
+	"github.com/opencode-ai/opencode/internal/llm/agent"
 	"github.com/opencode-ai/opencode/internal/message"
 	"github.com/opencode-ai/opencode/internal/session"
 	"github.com/opencode-ai/opencode/internal/tui/components/chat"
@@ -82,16 +85,24 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case dialog.CompletionDialogCloseMsg:
 		p.showCompletionDialog = false
 	case chat.SendMsg:
+		if msg.Edit != nil {
+			return p, p.resendMessage(msg.Text, msg.Attachments, *msg.Edit)
+		}
 		cmd := p.sendMessage(msg.Text, msg.Attachments)
 		if cmd != nil {
 			return p, cmd
 		}
-	case dialog.CommandRunCustomMsg:
-		// Check if the agent is busy before executing custom commands
-		if p.app.CoderAgent.IsBusy() {
-			return p, util.ReportWarn("Agent is busy, please wait before executing a command...")
+	case chat.SteerMsg:
+		err := p.app.CoderAgent.Steer(p.session.ID, msg.Text)
+		if errors.Is(err, agent.ErrSessionNotBusy) {
+			// The run finished in the meantime, send the note as a prompt
+			return p, p.sendMessage(msg.Text, nil)
 		}
-
+		if err != nil {
+			return p, util.ReportError(err)
+		}
+		return p, util.ReportInfo("Note will be passed to the agent before its next step")
+	case dialog.CommandRunCustomMsg:
 		// Process the command content with arguments if any
 		content := msg.Content
 		if msg.Args != nil {
@@ -183,10 +194,99 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 		cmds = append(cmds, util.CmdHandler(chat.SessionSelectedMsg(session)))
 	}
 
+	busy := p.app.CoderAgent.IsSessionBusy(p.session.ID)
 	_, err := p.app.CoderAgent.Run(context.Background(), p.session.ID, text, attachments...)
 	if err != nil {
 		return util.ReportError(err)
 	}
+	if busy {
+		cmds = append(cmds, util.ReportInfo("Prompt queued, it will be sent when the agent finishes"))
+	}
+	return tea.Batch(cmds...)
+}
+
+// resendMessage replaces an earlier user message with an edited one. The
+// original conversation is either cut back to before the message or kept
+// intact while the edited message is sent in a fork. Queued prompts are
+// simply updated before they are sent.
+func (p *chatPage) resendMessage(text string, attachments []message.Attachment, edit chat.MessageEdit) tea.Cmd {
+	ctx := context.Background()
+	original := edit.Message
+	if edit.Queued {
+		if err := p.app.CoderAgent.UpdateQueued(original.SessionID, original.ID, text, attachments...); err != nil {
+			return util.ReportError(err)
+		}
+		return util.ReportInfo("Queued prompt updated")
+	}
+	if p.app.CoderAgent.IsSessionBusy(original.SessionID) {
+		return util.ReportWarn("Agent is working, please wait...")
+	}
+
+	msgs, err := p.app.Messages.List(ctx, original.SessionID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	index := -1
+	for i, msg := range msgs {
+		if msg.ID == original.ID {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return util.ReportError(fmt.Errorf("message to edit no longer exists"))
+	}
+
+	sessionID := original.SessionID
+	var cmds []tea.Cmd
+	if edit.RevertFiles {
+		restores, err := p.app.History.PlanRewind(ctx, original.SessionID, original.Seq)
+		if err != nil {
+			return util.ReportError(err)
+		}
+		if err := p.app.History.Rewind(ctx, original.SessionID, restores); err != nil {
+			return util.ReportError(err)
+		}
+		if len(restores) > 0 {
+			cmds = append(cmds, util.ReportInfo(fmt.Sprintf("Reverted %d file(s)", len(restores))))
+		}
+	}
+
+	if edit.Fork {
+		if index == 0 {
+			return util.ReportWarn("Cannot fork before the first message")
+		}
+		forked, err := p.app.Sessions.Fork(ctx, original.SessionID, msgs[index-1].ID)
+		if err != nil {
+			return util.ReportError(err)
+		}
+		p.session = forked
+		sessionID = forked.ID
+		cmds = append(cmds, util.CmdHandler(chat.SessionSelectedMsg(forked)))
+	} else {
+		sess, err := p.app.Sessions.Get(ctx, original.SessionID)
+		if err != nil {
+			return util.ReportError(err)
+		}
+		if err := p.app.Messages.DeleteFrom(ctx, original.SessionID, original.ID); err != nil {
+			return util.ReportError(err)
+		}
+		// Drop the summary if it was among the discarded messages
+		for _, msg := range msgs[index:] {
+			if msg.ID == sess.SummaryMessageID {
+				sess.SummaryMessageID = ""
+				if _, err := p.app.Sessions.Save(ctx, sess); err != nil {
+					return util.ReportError(err)
+				}
+				break
+			}
+		}
+	}
+
+	_, err = p.app.CoderAgent.Run(ctx, sessionID, text, attachments...)
+	if err != nil {
+		return util.ReportError(err)
+	}
 	return tea.Batch(cmds...)
 }
 
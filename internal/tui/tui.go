@@ -3,6 +3,8 @@ package tui
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/exec"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -10,8 +12,12 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/opencode-ai/opencode/internal/app"
 	"github.com/opencode-ai/opencode/internal/config"
+	"github.com/opencode-ai/opencode/internal/history"
+	"github.com/opencode-ai/opencode/internal/hooks"
 	"github.com/opencode-ai/opencode/internal/llm/agent"
+	"github.com/opencode-ai/opencode/internal/llm/tools"
 	"github.com/opencode-ai/opencode/internal/logging"
+	"github.com/opencode-ai/opencode/internal/message"
 	"github.com/opencode-ai/opencode/internal/permission"
 	"github.com/opencode-ai/opencode/internal/pubsub"
 	"github.com/opencode-ai/opencode/internal/session"
@@ -37,6 +43,19 @@ type keyMap struct {
 
 type startCompactSessionMsg struct{}
 
+type showAgentsMsg struct{}
+
+// pendingSuggestion is a post-turn hook suggestion waiting for the user
+type pendingSuggestion struct {
+	sessionID  string
+	suggestion hooks.Suggestion
+}
+
+type showRewindDialogMsg struct {
+	sessionID string
+	restores  []history.Restore
+}
+
 const (
 	quitKey = "q"
 )
@@ -107,6 +126,9 @@ type appModel struct {
 
 	showPermissions bool
 	permissions     dialog.PermissionDialogCmp
+	// pendingPermissions are the requests waiting for the one in the dialog
+	// to be answered.
+	pendingPermissions []permission.PermissionRequest
 
 	showHelp bool
 	help     dialog.HelpCmp
@@ -136,6 +158,16 @@ type appModel struct {
 	showMultiArgumentsDialog bool
 	multiArgumentsDialog     dialog.MultiArgumentsDialogCmp
 
+	showLimitDialog bool
+	limitDialog     dialog.LimitDialog
+
+	showRewindDialog bool
+	rewindDialog     dialog.RewindDialog
+
+	showSuggestionDialog bool
+	suggestionDialog     dialog.SuggestionDialog
+	suggestions          []pendingSuggestion
+
 	isCompacting      bool
 	compactingMessage string
 }
@@ -212,6 +244,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.filepicker = filepicker.(dialog.FilepickerCmp)
 		cmds = append(cmds, filepickerCmd)
 
+		rewind, rewindCmd := a.rewindDialog.Update(msg)
+		a.rewindDialog = rewind.(dialog.RewindDialog)
+		cmds = append(cmds, rewindCmd)
+
 		a.initDialog.SetSize(msg.Width, msg.Height)
 
 		if a.showMultiArgumentsDialog {
@@ -273,6 +309,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Permission
 	case pubsub.Event[permission.PermissionRequest]:
+		if a.showPermissions {
+			a.pendingPermissions = append(a.pendingPermissions, msg.Payload)
+			return a, nil
+		}
 		a.showPermissions = true
 		return a, a.permissions.SetPermissions(msg.Payload)
 	case dialog.PermissionResponseMsg:
@@ -285,6 +325,11 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case dialog.PermissionDeny:
 			a.app.Permissions.Deny(msg.Permission)
 		}
+		if len(a.pendingPermissions) > 0 {
+			next := a.pendingPermissions[0]
+			a.pendingPermissions = a.pendingPermissions[1:]
+			return a, a.permissions.SetPermissions(next)
+		}
 		a.showPermissions = false
 		return a, cmd
 
@@ -303,6 +348,18 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showCommandDialog = false
 		return a, nil
 
+	case chat.MessageActionsMsg:
+		if a.showQuit || a.showPermissions || a.showSessionDialog {
+			return a, nil
+		}
+		if msg.Queued {
+			a.commandDialog.SetCommands(a.queuedActions(msg.Message))
+		} else {
+			a.commandDialog.SetCommands(a.messageActions(msg.Message))
+		}
+		a.showCommandDialog = true
+		return a, nil
+
 	case startCompactSessionMsg:
 		// Start compacting the current session
 		a.isCompacting = true
@@ -322,6 +379,32 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case pubsub.Event[agent.AgentEvent]:
 		payload := msg.Payload
+		if payload.Type == agent.AgentEventTypeUsage {
+			s, _ := a.status.Update(msg)
+			a.status = s.(core.StatusCmp)
+			return a, nil
+		}
+		if payload.Type == agent.AgentEventTypeQueue {
+			a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(msg)
+			return a, cmd
+		}
+		if payload.Type == agent.AgentEventTypeSuggest {
+			for _, suggestion := range payload.Suggestions {
+				a.suggestions = append(a.suggestions, pendingSuggestion{sessionID: payload.SessionID, suggestion: suggestion})
+			}
+			if !a.showSuggestionDialog {
+				a.nextSuggestion()
+			}
+			return a, nil
+		}
+		if payload.Type == agent.AgentEventTypeCompact {
+			return a, util.ReportInfo(payload.Progress)
+		}
+		if payload.Type == agent.AgentEventTypeLimit && payload.Limit != nil {
+			a.limitDialog.SetLimit(payload.Message.SessionID, *payload.Limit)
+			a.showLimitDialog = true
+			return a, nil
+		}
 		if payload.Error != nil {
 			a.isCompacting = false
 			return a, util.ReportError(payload.Error)
@@ -332,17 +415,63 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if payload.Done && payload.Type == agent.AgentEventTypeSummarize {
 			a.isCompacting = false
 			return a, util.ReportInfo("Session summarization complete")
-		} else if payload.Done && payload.Type == agent.AgentEventTypeResponse && a.selectedSession.ID != "" {
-			model := a.app.CoderAgent.Model()
-			contextWindow := model.ContextWindow
-			tokens := a.selectedSession.CompletionTokens + a.selectedSession.PromptTokens
-			if (tokens >= int64(float64(contextWindow)*0.95)) && config.Get().AutoCompact {
-				return a, util.CmdHandler(startCompactSessionMsg{})
-			}
 		}
 		// Continue listening for events
 		return a, nil
 
+	case dialog.LimitResponseMsg:
+		a.showLimitDialog = false
+		if !msg.Continue {
+			return a, nil
+		}
+		budget := config.Get().Budget
+		switch msg.Limit.Kind {
+		case agent.LimitSessionCost:
+			budget.MaxSessionCost = dialog.RaisedLimit(msg.Limit)
+		case agent.LimitDailyCost:
+			budget.MaxDailyCost = dialog.RaisedLimit(msg.Limit)
+		}
+		if err := config.UpdateBudget(budget); err != nil {
+			return a, util.ReportError(err)
+		}
+		if _, err := a.app.CoderAgent.Continue(context.Background(), msg.SessionID); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, nil
+
+	case dialog.SuggestionResponseMsg:
+		if len(a.suggestions) > 0 {
+			a.suggestions = a.suggestions[1:]
+		}
+		a.nextSuggestion()
+		if !msg.Approve {
+			return a, nil
+		}
+		if _, err := a.app.CoderAgent.Run(context.Background(), msg.SessionID, msg.Suggestion.Text); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, nil
+
+	case showAgentsMsg:
+		a.commandDialog.SetCommands(a.agentCommands())
+		a.showCommandDialog = true
+		return a, nil
+
+	case showRewindDialogMsg:
+		a.rewindDialog.SetRewind(msg.sessionID, msg.restores)
+		a.showRewindDialog = true
+		return a, nil
+
+	case dialog.RewindResponseMsg:
+		a.showRewindDialog = false
+		if !msg.Confirm {
+			return a, nil
+		}
+		if err := a.app.History.Rewind(context.Background(), msg.SessionID, msg.Restores); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, util.ReportInfo(fmt.Sprintf("Rewound %d file(s)", len(msg.Restores)))
+
 	case dialog.CloseThemeDialogMsg:
 		a.showThemeDialog = false
 		return a, nil
@@ -361,7 +490,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Model selected: msg.Model.Name
 
-		model, err := a.app.CoderAgent.Update(config.AgentCoder, msg.Model.ID)
+		model, err := a.app.CoderAgent.Update(a.app.CoderAgent.AgentName(), msg.Model.ID)
 		if err != nil {
 			// Error updating model
 			return a, util.ReportError(err)
@@ -489,7 +618,11 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if len(sessions) == 0 {
 					return a, util.ReportWarn("No sessions available")
 				}
-				a.sessionDialog.SetSessions(sessions)
+				agentSessions, err := a.app.Sessions.ListAgentSessions(context.Background())
+				if err != nil {
+					return a, util.ReportError(err)
+				}
+				a.sessionDialog.SetSessions(dialog.NestAgentSessions(sessions, agentSessions))
 				a.showSessionDialog = true
 				return a, nil
 			}
@@ -601,6 +734,33 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, tea.Batch(cmds...)
 		}
 	}
+	if a.showRewindDialog {
+		d, rewindCmd := a.rewindDialog.Update(msg)
+		a.rewindDialog = d.(dialog.RewindDialog)
+		cmds = append(cmds, rewindCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+	if a.showSuggestionDialog {
+		d, suggestionCmd := a.suggestionDialog.Update(msg)
+		a.suggestionDialog = d.(dialog.SuggestionDialog)
+		cmds = append(cmds, suggestionCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+	if a.showLimitDialog {
+		d, limitCmd := a.limitDialog.Update(msg)
+		a.limitDialog = d.(dialog.LimitDialog)
+		cmds = append(cmds, limitCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
 	if a.showPermissions {
 		d, permissionsCmd := a.permissions.Update(msg)
 		a.permissions = d.(dialog.PermissionDialogCmp)
@@ -673,6 +833,178 @@ func (a *appModel) RegisterCommand(cmd dialog.Command) {
 	a.commands = append(a.commands, cmd)
 }
 
+// messageActions returns the commands offered for a message selected in the
+// chat list.
+func (a *appModel) messageActions(msg message.Message) []dialog.Command {
+	var actions []dialog.Command
+	if msg.Role == message.User {
+		edit := func(fork, revert bool) func(cmd dialog.Command) tea.Cmd {
+			return func(cmd dialog.Command) tea.Cmd {
+				return util.CmdHandler(chat.EditMessageMsg{
+					Message:     msg,
+					Fork:        fork,
+					RevertFiles: revert,
+				})
+			}
+		}
+		actions = append(actions,
+			dialog.Command{
+				ID:          "edit",
+				Title:       "Edit and Resend",
+				Description: "Edit this message and resend it, discarding the messages after it",
+				Handler:     edit(false, false),
+			},
+			dialog.Command{
+				ID:          "edit-revert",
+				Title:       "Edit and Resend, Revert Files",
+				Description: "Also restore the files changed after this message",
+				Handler:     edit(false, true),
+			},
+			dialog.Command{
+				ID:          "edit-fork",
+				Title:       "Edit and Resend in Fork",
+				Description: "Keep this conversation and resend the edited message in a new session",
+				Handler:     edit(true, false),
+			},
+		)
+	}
+	for _, call := range msg.ToolCalls() {
+		for _, handle := range tools.SpilledOutputs(msg.SessionID, call.ID) {
+			path, err := tools.SpillPath(msg.SessionID, handle)
+			if err != nil {
+				continue
+			}
+			actions = append(actions, dialog.Command{
+				ID:          "output-" + handle,
+				Title:       "View Full Output: " + handle,
+				Description: fmt.Sprintf("Open the full output of the %s call in your pager", call.Name),
+				Handler: func(cmd dialog.Command) tea.Cmd {
+					return openPager(path)
+				},
+			})
+		}
+	}
+	return append(actions,
+		dialog.Command{
+			ID:          "rewind",
+			Title:       "Rewind Files",
+			Description: "Restore the files changed since this message, with a preview",
+			Handler: func(cmd dialog.Command) tea.Cmd {
+				if a.app.CoderAgent.IsSessionBusy(msg.SessionID) {
+					return util.ReportWarn("Agent is working, please wait...")
+				}
+				restores, err := a.app.History.PlanRewind(context.Background(), msg.SessionID, msg.Seq)
+				if err != nil {
+					return util.ReportError(err)
+				}
+				if len(restores) == 0 {
+					return util.ReportInfo("No file changes to rewind")
+				}
+				return util.CmdHandler(showRewindDialogMsg{sessionID: msg.SessionID, restores: restores})
+			},
+		},
+		dialog.Command{
+			ID:          "fork",
+			Title:       "Fork Session",
+			Description: "Continue in a new session branched from this message",
+			Handler: func(cmd dialog.Command) tea.Cmd {
+				forked, err := a.app.Sessions.Fork(context.Background(), msg.SessionID, msg.ID)
+				if err != nil {
+					return util.ReportError(err)
+				}
+				return tea.Batch(
+					util.CmdHandler(chat.SessionSelectedMsg(forked)),
+					util.ReportInfo("Forked session: "+forked.Title),
+				)
+			},
+		},
+	)
+}
+
+// openPager shows a file in $PAGER, or less when it is not set.
+func openPager(path string) tea.Cmd {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	c := exec.Command(pager, path) //nolint:gosec
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return nil
+	})
+}
+
+// agentCommands returns one command per agent that can answer prompts.
+func (a *appModel) agentCommands() []dialog.Command {
+	names := append([]config.AgentName{config.AgentCoder}, config.CustomAgents()...)
+	commands := make([]dialog.Command, 0, len(names))
+	for _, name := range names {
+		title := string(name)
+		if name == a.app.CoderAgent.AgentName() {
+			title += " (current)"
+		}
+		description := config.Get().Agents[name].Description
+		if name == config.AgentCoder {
+			description = "The default coding agent"
+		}
+		commands = append(commands, dialog.Command{
+			ID:          "agent-" + string(name),
+			Title:       title,
+			Description: description,
+			Handler: func(cmd dialog.Command) tea.Cmd {
+				if err := a.app.SwitchAgent(name); err != nil {
+					return util.ReportError(err)
+				}
+				return util.ReportInfo(fmt.Sprintf("Switched to the %s agent", name))
+			},
+		})
+	}
+	return commands
+}
+
+// queuedActions returns the commands offered for a prompt waiting in the
+// agent queue.
+func (a *appModel) queuedActions(msg message.Message) []dialog.Command {
+	return []dialog.Command{
+		{
+			ID:          "edit-queued",
+			Title:       "Edit Queued Prompt",
+			Description: "Change this prompt before it is sent",
+			Handler: func(cmd dialog.Command) tea.Cmd {
+				return util.CmdHandler(chat.EditMessageMsg{Message: msg, Queued: true})
+			},
+		},
+		{
+			ID:          "remove-queued",
+			Title:       "Remove from Queue",
+			Description: "Drop this prompt without sending it",
+			Handler: func(cmd dialog.Command) tea.Cmd {
+				if err := a.app.CoderAgent.RemoveQueued(msg.SessionID, msg.ID); err != nil {
+					return util.ReportError(err)
+				}
+				return util.ReportInfo("Removed prompt from queue")
+			},
+		},
+	}
+}
+
+// nextSuggestion shows the first pending hook suggestion, or closes the
+// suggestion dialog when there is none left.
+func (a *appModel) nextSuggestion() {
+	if len(a.suggestions) == 0 {
+		a.showSuggestionDialog = false
+		return
+	}
+	next := a.suggestions[0]
+	a.suggestionDialog.SetSuggestion(next.sessionID, next.suggestion, len(a.suggestions)-1)
+	a.showSuggestionDialog = true
+}
+
 func (a *appModel) findCommand(id string) (dialog.Command, bool) {
 	for _, cmd := range a.commands {
 		if cmd.ID == id {
@@ -728,6 +1060,51 @@ func (a appModel) View() string {
 		)
 	}
 
+	if a.showRewindDialog {
+		overlay := a.rewindDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
+	if a.showSuggestionDialog {
+		overlay := a.suggestionDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
+	if a.showLimitDialog {
+		overlay := a.limitDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
 	if a.showFilepicker {
 		overlay := a.filepicker.View()
 		row := lipgloss.Height(appView) / 2
@@ -906,19 +1283,22 @@ func (a appModel) View() string {
 func New(app *app.App) tea.Model {
 	startPage := page.ChatPage
 	model := &appModel{
-		currentPage:   startPage,
-		loadedPages:   make(map[page.PageID]bool),
-		status:        core.NewStatusCmp(app.LSPClients),
-		help:          dialog.NewHelpCmp(),
-		quit:          dialog.NewQuitCmp(),
-		sessionDialog: dialog.NewSessionDialogCmp(),
-		commandDialog: dialog.NewCommandDialogCmp(),
-		modelDialog:   dialog.NewModelDialogCmp(),
-		permissions:   dialog.NewPermissionDialogCmp(),
-		initDialog:    dialog.NewInitDialogCmp(),
-		themeDialog:   dialog.NewThemeDialogCmp(),
-		app:           app,
-		commands:      []dialog.Command{},
+		currentPage:      startPage,
+		loadedPages:      make(map[page.PageID]bool),
+		status:           core.NewStatusCmp(app.LSPClients),
+		help:             dialog.NewHelpCmp(),
+		quit:             dialog.NewQuitCmp(),
+		sessionDialog:    dialog.NewSessionDialogCmp(),
+		commandDialog:    dialog.NewCommandDialogCmp(),
+		modelDialog:      dialog.NewModelDialogCmp(),
+		permissions:      dialog.NewPermissionDialogCmp(),
+		initDialog:       dialog.NewInitDialogCmp(),
+		themeDialog:      dialog.NewThemeDialogCmp(),
+		limitDialog:      dialog.NewLimitDialogCmp(),
+		rewindDialog:     dialog.NewRewindDialogCmp(),
+		suggestionDialog: dialog.NewSuggestionDialogCmp(),
+		app:              app,
+		commands:         []dialog.Command{},
 		pages: map[page.PageID]tea.Model{
 			page.ChatPage: page.NewChatPage(app),
 			page.LogsPage: page.NewLogsPage(),
@@ -946,6 +1326,15 @@ If there are Cursor rules (in .cursor/rules/ or .cursorrules) or Copilot rules (
 		},
 	})
 
+	model.RegisterCommand(dialog.Command{
+		ID:          "agent",
+		Title:       "Switch Agent",
+		Description: "Choose the agent that answers your prompts",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return util.CmdHandler(showAgentsMsg{})
+		},
+	})
+
 	model.RegisterCommand(dialog.Command{
 		ID:          "compact",
 		Title:       "Compact Session",
@@ -0,0 +1,192 @@
+// Package worktree manages temporary git worktrees that let agents change the
+// project without touching the main working tree.
+package worktree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Worktree is a detached checkout of the project's current state.
+type Worktree struct {
+	// Dir is the directory inside the worktree that matches the project
+	// working directory.
+	Dir string
+	// Repo is the top level directory of the main repository.
+	Repo string
+	root string
+	base string
+
+	closeMu sync.Mutex
+	closers []func()
+}
+
+var (
+	activeMu sync.Mutex
+	active   = make(map[string]*Worktree)
+)
+
+// Create checks out the current state of the repository containing
+// workingDir, including uncommitted changes to tracked files, in a new
+// worktree in the temporary directory.
+func Create(ctx context.Context, workingDir string) (*Worktree, error) {
+	workingDir, err := filepath.EvalSymlinks(workingDir)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git(ctx, workingDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", workingDir, err)
+	}
+	repo = strings.TrimSpace(repo)
+	// stash create records the working tree in a commit without changing it,
+	// and prints nothing when there are no changes. The commit is never shared,
+	// so a fallback identity is fine when the user has none configured.
+	base, err := git(ctx, repo, "-c", "user.name=opencode", "-c", "user.email=opencode@localhost", "stash", "create")
+	if err != nil {
+		return nil, err
+	}
+	if base = strings.TrimSpace(base); base == "" {
+		if base, err = git(ctx, repo, "rev-parse", "HEAD"); err != nil {
+			return nil, fmt.Errorf("repository has no commits: %w", err)
+		}
+		base = strings.TrimSpace(base)
+	}
+	return checkout(ctx, repo, workingDir, base)
+}
+
+// Reopen checks out base, the commit a removed worktree was created from, in
+// a new worktree and applies the patch saved from its changes, unless
+// patchPath is empty, so work can go on where it stopped.
+func Reopen(ctx context.Context, workingDir, base, patchPath string) (*Worktree, error) {
+	workingDir, err := filepath.EvalSymlinks(workingDir)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git(ctx, workingDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", workingDir, err)
+	}
+	w, err := checkout(ctx, strings.TrimSpace(repo), workingDir, base)
+	if err != nil {
+		return nil, err
+	}
+	if patchPath == "" {
+		return w, nil
+	}
+	if _, err := git(ctx, w.root, "apply", "--binary", patchPath); err != nil {
+		w.Remove()
+		return nil, err
+	}
+	return w, nil
+}
+
+// checkout adds a detached worktree of base in the temporary directory.
+func checkout(ctx context.Context, repo, workingDir, base string) (*Worktree, error) {
+	rel, err := filepath.Rel(repo, workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := os.MkdirTemp("", "opencode-worktree-")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := git(ctx, repo, "worktree", "add", "--detach", root, base); err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+
+	w := &Worktree{
+		Dir:  filepath.Join(root, rel),
+		Repo: repo,
+		root: root,
+		base: base,
+	}
+	activeMu.Lock()
+	active[root] = w
+	activeMu.Unlock()
+	return w, nil
+}
+
+// Base returns the commit the worktree was checked out from.
+func (w *Worktree) Base() string {
+	return w.base
+}
+
+// Diff returns a patch of every change made in the worktree since it was
+// created, new files included, that applies to the main repository with
+// git apply.
+func (w *Worktree) Diff(ctx context.Context) (string, error) {
+	if _, err := git(ctx, w.root, "add", "--all"); err != nil {
+		return "", err
+	}
+	return git(ctx, w.root, "diff", "--cached", "--binary", w.base)
+}
+
+// OnRemove registers f to release something working in the worktree, such
+// as a shell, before the worktree is removed.
+func (w *Worktree) OnRemove(f func()) {
+	w.closeMu.Lock()
+	defer w.closeMu.Unlock()
+	w.closers = append(w.closers, f)
+}
+
+// Remove deletes the worktree and its files.
+func (w *Worktree) Remove() error {
+	activeMu.Lock()
+	delete(active, w.root)
+	activeMu.Unlock()
+
+	w.closeMu.Lock()
+	closers := w.closers
+	w.closers = nil
+	w.closeMu.Unlock()
+	for _, f := range closers {
+		f()
+	}
+
+	_, err := git(context.Background(), w.Repo, "worktree", "remove", "--force", w.root)
+	if rmErr := os.RemoveAll(w.root); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// RemoveAll deletes every worktree that has not been removed yet.
+func RemoveAll() error {
+	activeMu.Lock()
+	worktrees := make([]*Worktree, 0, len(active))
+	for _, w := range active {
+		worktrees = append(worktrees, w)
+	}
+	activeMu.Unlock()
+
+	var errs []error
+	for _, w := range worktrees {
+		errs = append(errs, w.Remove())
+	}
+	return errors.Join(errs...)
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git: %s", msg)
+		}
+		return "", fmt.Errorf("git: %w", err)
+	}
+	return stdout.String(), nil
+}
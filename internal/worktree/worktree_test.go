@@ -0,0 +1,167 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func run(t *testing.T, dir string, name string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s %v: %v\n%s", name, args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestWorktreeRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	run(t, repo, "git", "init", "-q")
+	writeFile(t, filepath.Join(repo, "sub", "main.txt"), "one\n")
+	run(t, repo, "git", "add", ".")
+	run(t, repo, "git", "commit", "-q", "-m", "initial")
+	// Uncommitted changes are part of the worktree
+	writeFile(t, filepath.Join(repo, "sub", "main.txt"), "two\n")
+
+	w, err := Create(ctx, filepath.Join(repo, "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Remove()
+	closed := 0
+	w.OnRemove(func() { closed++ })
+
+	if got := readFile(t, filepath.Join(w.Dir, "main.txt")); got != "two\n" {
+		t.Fatalf("worktree content = %q, want %q", got, "two\n")
+	}
+
+	patch, err := w.Diff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patch != "" {
+		t.Fatalf("expected no changes, got:\n%s", patch)
+	}
+
+	writeFile(t, filepath.Join(w.Dir, "main.txt"), "three\n")
+	writeFile(t, filepath.Join(w.Dir, "new.txt"), "new\n")
+	patch, err = w.Diff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(patch, "sub/main.txt") || !strings.Contains(patch, "sub/new.txt") {
+		t.Fatalf("patch is missing changes:\n%s", patch)
+	}
+	if got := readFile(t, filepath.Join(repo, "sub", "main.txt")); got != "two\n" {
+		t.Fatalf("main working tree changed to %q", got)
+	}
+
+	patchPath := filepath.Join(t.TempDir(), "changes.patch")
+	writeFile(t, patchPath, patch)
+	run(t, repo, "git", "apply", patchPath)
+	if got := readFile(t, filepath.Join(repo, "sub", "main.txt")); got != "three\n" {
+		t.Fatalf("applied content = %q, want %q", got, "three\n")
+	}
+	if got := readFile(t, filepath.Join(repo, "sub", "new.txt")); got != "new\n" {
+		t.Fatalf("applied new file = %q, want %q", got, "new\n")
+	}
+
+	if err := w.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if closed != 1 {
+		t.Fatalf("closers ran %d times, want 1", closed)
+	}
+	if _, err := os.Stat(w.Dir); !os.IsNotExist(err) {
+		t.Fatalf("worktree still exists: %v", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	run(t, repo, "git", "init", "-q")
+	writeFile(t, filepath.Join(repo, "main.txt"), "one\n")
+	run(t, repo, "git", "add", ".")
+	run(t, repo, "git", "commit", "-q", "-m", "initial")
+
+	w, err := Create(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(w.Dir, "new.txt"), "new\n")
+	patch, err := w.Diff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patchPath := filepath.Join(t.TempDir(), "changes.patch")
+	writeFile(t, patchPath, patch)
+	if err := w.Remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The main working tree moving on does not change the reopened worktree
+	writeFile(t, filepath.Join(repo, "main.txt"), "two\n")
+	w, err = Reopen(ctx, repo, w.Base(), patchPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Remove()
+	if got := readFile(t, filepath.Join(w.Dir, "main.txt")); got != "one\n" {
+		t.Fatalf("reopened content = %q, want %q", got, "one\n")
+	}
+	if got := readFile(t, filepath.Join(w.Dir, "new.txt")); got != "new\n" {
+		t.Fatalf("reopened new file = %q, want %q", got, "new\n")
+	}
+	reopened, err := w.Diff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened != patch {
+		t.Fatalf("reopened patch = %q, want %q", reopened, patch)
+	}
+}
+
+func TestCreateOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+	if _, err := Create(context.Background(), t.TempDir()); err == nil {
+		t.Fatal("expected an error outside a git repository")
+	}
+}